@@ -1,15 +1,113 @@
 package api
 
 import (
+	"bytes"
 	_ "embed"
+	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
 )
 
 //go:embed ambulance-wl.openapi.yaml
 var openapiSpec []byte
 
+const (
+	mimeJson = "application/json"
+	mimeYaml = "application/yaml"
+)
+
 func HandleOpenApi(ctx *gin.Context) {
-	ctx.Data(http.StatusOK, "application/yaml", openapiSpec)
+	OpenApiHandler("")(ctx)
+}
+
+// OpenApiHandler serves the specification with the servers urls prefixed by the base path,
+// under which the service routes are mounted. The specification is provided as JSON by default,
+// YAML is provided if requested by the `Accept` header or by the `format=yaml` query parameter.
+func OpenApiHandler(basePath string) gin.HandlerFunc {
+	yamlSpec, err := specWithBasePath(openapiSpec, basePath)
+	if err != nil {
+		log.Printf("Failed to apply base path to the openapi specification: %v", err)
+		yamlSpec = openapiSpec
+	}
+	jsonSpec, err := specToJson(yamlSpec)
+	if err != nil {
+		log.Printf("Failed to convert the openapi specification to JSON: %v", err)
+	}
+
+	return func(ctx *gin.Context) {
+		format := ctx.Query("format")
+		if format == "" {
+			format = ctx.NegotiateFormat(mimeJson, mimeYaml, "application/x-yaml", "text/yaml")
+		}
+
+		switch format {
+		case "json", mimeJson:
+			if jsonSpec != nil {
+				ctx.Data(http.StatusOK, mimeJson, jsonSpec)
+				return
+			}
+			// conversion failed, yaml is still better than nothing
+			fallthrough
+		case "yaml", mimeYaml, "application/x-yaml", "text/yaml":
+			ctx.Data(http.StatusOK, mimeYaml, yamlSpec)
+		default:
+			ctx.JSON(http.StatusNotAcceptable, gin.H{
+				"status":  "Not Acceptable",
+				"message": "The specification is available as application/json or application/yaml",
+			})
+		}
+	}
+}
+
+// specToJson converts the yaml specification into its JSON form
+func specToJson(spec []byte) ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+func specWithBasePath(spec []byte, basePath string) ([]byte, error) {
+	if basePath == "" {
+		return spec, nil
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return nil, err
+	}
+
+	servers := mappingValue(document.Content[0], "servers")
+	if servers != nil {
+		for _, server := range servers.Content {
+			if url := mappingValue(server, "url"); url != nil {
+				url.Value = basePath + url.Value
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// mappingValue returns the value node of the key in the yaml mapping node
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type OpenApiSuite struct {
+	suite.Suite
+}
+
+func TestOpenApiSuite(t *testing.T) {
+	suite.Run(t, new(OpenApiSuite))
+}
+
+func (suite *OpenApiSuite) get(url string, accept string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/openapi", OpenApiHandler("/prefix"))
+
+	request := httptest.NewRequest("GET", url, nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *OpenApiSuite) Test_OpenApi_JsonByDefault() {
+	// ACT
+	recorder := suite.get("/openapi", "")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("application/json", recorder.Header().Get("Content-Type"))
+	suite.True(json.Valid(recorder.Body.Bytes()))
+}
+
+func (suite *OpenApiSuite) Test_OpenApi_YamlMatchesJson() {
+	// ARRANGE
+	var jsonDocument interface{}
+	suite.Require().NoError(json.Unmarshal(suite.get("/openapi", "application/json").Body.Bytes(), &jsonDocument))
+
+	for _, recorder := range []*httptest.ResponseRecorder{
+		suite.get("/openapi", "application/yaml"),
+		suite.get("/openapi?format=yaml", ""),
+	} {
+		// ACT
+		var yamlDocument interface{}
+		err := yaml.Unmarshal(recorder.Body.Bytes(), &yamlDocument)
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal("application/yaml", recorder.Header().Get("Content-Type"))
+		// normalize the number types of the yaml decoder to those of the json one
+		normalized, err := json.Marshal(yamlDocument)
+		suite.Require().NoError(err)
+		var yamlAsJson interface{}
+		suite.Require().NoError(json.Unmarshal(normalized, &yamlAsJson))
+		suite.Equal(jsonDocument, yamlAsJson)
+	}
+}
+
+func (suite *OpenApiSuite) Test_OpenApi_UnsupportedFormat() {
+	suite.Equal(http.StatusNotAcceptable, suite.get("/openapi", "text/html").Code)
+}
@@ -3,16 +3,27 @@ package main
 import (
 	"context"
 	_ "embed"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	// the scratch image has no time zone database, the ambulance time zones are resolved from the embedded one
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/milung/ambulance-webapi/api"
 	"github.com/milung/ambulance-webapi/internal/ambulance_wl"
 	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/milung/ambulance-webapi/internal/logging"
+	"github.com/milung/ambulance-webapi/internal/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/technologize/otel-go-contrib/otelginmetrics"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -24,23 +35,23 @@ import (
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"golang.org/x/net/netutil"
 )
 
-// initialize OpenTelemetry instrumentations
-func initTelemetry() (func(context.Context) error, error) {
-	ctx := context.Background()
-	res, err := resource.New(ctx,
+// telemetry resource shared by the exported metrics, traces and logs
+func newResource(ctx context.Context) (*resource.Resource, error) {
+	return resource.New(ctx,
 		resource.WithAttributes(semconv.ServiceNameKey.String("Ambulance WebAPI Service")),
 		resource.WithAttributes(semconv.ServiceNamespaceKey.String("WAC Hospital")),
 		resource.WithSchemaURL(semconv.SchemaURL),
 		resource.WithContainer(),
 	)
+}
 
-	if err != nil {
-		return nil, err
-	}
-
+// initialize OpenTelemetry instrumentations
+func initTelemetry(res *resource.Resource) (func(context.Context) error, error) {
+	ctx := context.Background()
 	metricExporter, err := prometheus.New()
 	if err != nil {
 		return nil, err
@@ -80,23 +91,441 @@ func initTelemetry() (func(context.Context) error, error) {
 
 }
 
+// basePath normalizes the configured route prefix to the form `/prefix`, empty for the root
+func basePath(value string) string {
+	value = strings.Trim(value, "/")
+	if value == "" {
+		return ""
+	}
+	return "/" + value
+}
+
+// build information injected at the build time, e.g. by
+// -ldflags "-X main.version=1.2.0 -X main.gitCommit=<sha> -X main.buildTime=<RFC 3339 time>"
+var (
+	version   = "dev"
+	gitCommit = "unknown"
+	buildTime = "unknown"
+)
+
+// buildInfo identifies the running build for the incident triage
+func buildInfo() gin.H {
+	return gin.H{
+		"version":   version,
+		"gitCommit": gitCommit,
+		"buildTime": buildTime,
+		"goVersion": runtime.Version(),
+	}
+}
+
+// ginMode selects the gin mode, the explicit mode takes precedence over the environment
+// derived default - debug mode unless running in production
+func ginMode(environment string, explicitMode string) string {
+	switch mode := strings.ToLower(explicitMode); mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+		return mode
+	case "":
+	default:
+		log.Printf("Invalid gin mode value: %v", explicitMode)
+	}
+
+	if !strings.EqualFold(environment, "production") { // case insensitive comparison
+		return gin.DebugMode
+	}
+	return gin.ReleaseMode
+}
+
+// corsConfig parses the comma separated lists of the allowed origins and the exposed headers,
+// the default headers are exposed if none are provided
+func corsConfig(origins string, exposedHeaders string, maxAge string) middleware.CorsConfig {
+	split := func(value string) []string {
+		result := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+
+	config := middleware.CorsConfig{
+		AllowedOrigins: split(origins),
+		ExposedHeaders: split(exposedHeaders),
+		MaxAge:         middleware.DefaultCorsMaxAge,
+	}
+	if len(config.ExposedHeaders) == 0 {
+		config.ExposedHeaders = middleware.DefaultCorsExposedHeaders
+	}
+	if maxAge != "" {
+		if duration, err := time.ParseDuration(maxAge); err == nil && duration >= 0 {
+			config.MaxAge = duration
+		} else {
+			log.Printf("Invalid CORS max age value: %v", maxAge)
+		}
+	}
+	return config
+}
+
+// accessLogConfig parses the comma separated lists of the logged fields and of the skipped paths and
+// the level of the access log records, the defaults are used for the empty or invalid values
+func accessLogConfig(fields string, level string, skipPaths string) middleware.AccessLogConfig {
+	config := middleware.AccessLogConfig{
+		Level:     slog.LevelInfo,
+		SkipPaths: middleware.DefaultAccessLogSkipPaths,
+	}
+	selected, unknown := middleware.ParseAccessLogFields(fields)
+	if len(unknown) > 0 {
+		log.Printf("Invalid access log fields: %v", strings.Join(unknown, ", "))
+	}
+	config.Fields = selected
+	if level != "" {
+		if err := config.Level.UnmarshalText([]byte(level)); err != nil {
+			log.Printf("Invalid access log level value: %v", level)
+			config.Level = slog.LevelInfo
+		}
+	}
+	if skipPaths != "" {
+		config.SkipPaths = []string{}
+		for _, path := range strings.Split(skipPaths, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				config.SkipPaths = append(config.SkipPaths, path)
+			}
+		}
+	}
+	return config
+}
+
+// serverLimits bounds the resources the clients may hold on the server, independent of the rate limits
+type serverLimits struct {
+	// size of the request line and the headers
+	MaxHeaderBytes int
+	// simultaneously open connections, not limited if zero
+	MaxConnections int
+	// simultaneously served streams, the streams hold their connections so they count to MaxConnections
+	MaxStreams int
+}
+
+// idle keep-alive connections release their slot of the limited connections after the timeout
+const idleConnectionTimeout = time.Minute
+
+// serverLimitsConfig parses the limits of the server, the defaults are used for the empty or invalid values.
+// Unless configured otherwise, the streams may take at most half of the limited connections, and they
+// leave at least one connection for the regular requests if more than one connection is allowed.
+func serverLimitsConfig(maxHeaderBytes string, maxConnections string, maxStreams string) serverLimits {
+	positive := func(name string, value string) int {
+		if value == "" {
+			return 0
+		}
+		if number, err := strconv.Atoi(value); err == nil && number > 0 {
+			return number
+		}
+		log.Printf("Invalid %v value: %v", name, value)
+		return 0
+	}
+
+	limits := serverLimits{
+		MaxHeaderBytes: positive("max header bytes", maxHeaderBytes),
+		MaxConnections: positive("max connections", maxConnections),
+		MaxStreams:     positive("max streams", maxStreams),
+	}
+	if limits.MaxHeaderBytes == 0 {
+		limits.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	if limits.MaxConnections > 0 {
+		if limits.MaxStreams == 0 {
+			limits.MaxStreams = limits.MaxConnections / 2
+		}
+		limits.MaxStreams = max(min(limits.MaxStreams, limits.MaxConnections-1), 1)
+	}
+	return limits
+}
+
+// newServer creates the http server of the handler limited by the configuration
+func newServer(port string, handler http.Handler, limits serverLimits) *http.Server {
+	server := &http.Server{
+		Addr:           ":" + port,
+		Handler:        handler,
+		MaxHeaderBytes: limits.MaxHeaderBytes,
+	}
+	if limits.MaxConnections > 0 {
+		server.IdleTimeout = idleConnectionTimeout
+	}
+	return server
+}
+
+// listenAndServe serves the connections of the server, the connections over the limit wait
+// in the backlog until some of the open connections is closed
+func listenAndServe(server *http.Server, limits serverLimits) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	if limits.MaxConnections > 0 {
+		listener = netutil.LimitListener(listener, limits.MaxConnections)
+	}
+	return server.Serve(listener)
+}
+
+// time given to the requests in progress to complete when the service is stopped
+const shutdownTimeout = 15 * time.Second
+
+// serve runs the server until it fails or until the termination signal, on the signal the server stops
+// accepting new connections and waits for the requests in progress at most the shutdownTimeout
+func serve(server *http.Server, limits serverLimits) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	shutdown := make(chan error, 1)
+	go func() {
+		<-signals
+		log.Printf("Shutting down, waiting up to %v for the requests in progress", shutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	if err := listenAndServe(server, limits); err != http.ErrServerClosed {
+		return err
+	}
+	return <-shutdown
+}
+
+// newDbService creates the service of the selected storage backend - MongoDB by default,
+// or the in-memory storage for the local development without the database server
+func newDbService[DocType interface{}](backend string, opts ...db_service.MongoServiceOption) db_service.DbService[DocType] {
+	switch strings.ToLower(backend) {
+	case "memory":
+		return db_service.NewMemoryService[DocType](opts...)
+	case "", "mongo", "mongodb":
+	default:
+		log.Printf("Invalid database backend value: %v, using MongoDB", backend)
+	}
+	return db_service.NewMongoService[DocType](db_service.MongoServiceConfig{}, opts...)
+}
+
+// mountRoutes registers the api routes, the openapi specification and the build information under the base path
+func mountRoutes(engine *gin.Engine, basePath string) {
+	router := engine.Group(basePath)
+	ambulance_wl.AddRoutes(router)
+
+	// openapi spec endpoint
+	router.GET("/openapi", api.OpenApiHandler(basePath))
+
+	// build of the running service, available also without the admin token
+	router.GET("/version", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, buildInfo())
+	})
+}
+
+// mountMetrics registers the prometheus scrape endpoint, optionally guarded by the credentials given
+// as "bearer:<token>" or "basic:<user>:<password>"; the endpoint is open if no credentials are configured
+func mountMetrics(engine *gin.Engine, auth string) error {
+	handlers := []gin.HandlerFunc{}
+	if auth != "" {
+		scheme, credentials, _ := strings.Cut(auth, ":")
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			if credentials == "" {
+				return fmt.Errorf("bearer token is empty")
+			}
+			handlers = append(handlers, middleware.BearerAuth(credentials))
+		case "basic":
+			user, password, found := strings.Cut(credentials, ":")
+			if user == "" || !found {
+				return fmt.Errorf("basic credentials must be provided as basic:<user>:<password>")
+			}
+			handlers = append(handlers, middleware.BasicAuth(user, password))
+		default:
+			return fmt.Errorf("unsupported scheme %q, expected bearer or basic", scheme)
+		}
+	}
+
+	promhandler := promhttp.Handler()
+	handlers = append(handlers, func(ctx *gin.Context) {
+		promhandler.ServeHTTP(ctx.Writer, ctx.Request)
+	})
+	engine.Any("/metrics", handlers...)
+	return nil
+}
+
+// default upper limit of the cached ambulances, see AMBULANCE_API_CACHE_SIZE
+const defaultCacheSize = 1000
+
+// withCache wraps the service by the cache of the ambulances read within the AMBULANCE_API_CACHE_TTL,
+// the service is returned unchanged if the time to live is not configured
+func withCache(dbService db_service.DbService[ambulance_wl.Ambulance]) db_service.DbService[ambulance_wl.Ambulance] {
+	value := os.Getenv("AMBULANCE_API_CACHE_TTL")
+	if value == "" {
+		return dbService
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		if err != nil {
+			log.Printf("Invalid cache time to live value: %v", value)
+		}
+		return dbService
+	}
+	size := defaultCacheSize
+	if value := os.Getenv("AMBULANCE_API_CACHE_SIZE"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			size = parsed
+		} else {
+			log.Printf("Invalid cache size value: %v", value)
+		}
+	}
+	return db_service.NewCachedService(dbService, ttl, size)
+}
+
+// mountAdminRoutes registers the operational endpoints guarded by the admin token,
+// the endpoints are not available if no token is configured
+func mountAdminRoutes(engine *gin.Engine, token string, dbService interface{}) {
+	if token == "" {
+		return
+	}
+	admin := engine.Group("/admin", middleware.BearerAuth(token))
+
+	// re-run the reconciliation of the stored waiting lists
+	if ambulance_wl.FeatureEnabled(ambulance_wl.FeatureAdminReconcile) {
+		admin.POST("/ambulance/:ambulanceId/reconcile", ambulance_wl.ReconcileAmbulance)
+		admin.POST("/reconcile-all", ambulance_wl.ReconcileAllAmbulances)
+	}
+	if ambulance_wl.FeatureEnabled(ambulance_wl.FeatureAdminGenerator) {
+		admin.POST("/ambulance/:ambulanceId/generate", ambulance_wl.GenerateWaitingListEntries)
+	}
+	// bulk onboarding of the ambulances from the CSV
+	if ambulance_wl.FeatureEnabled(ambulance_wl.FeatureImportExport) {
+		admin.POST("/ambulances/import", ambulance_wl.ImportAmbulancesCsv)
+	}
+
+	// effective configuration for the diagnostics of the deployment
+	admin.GET("/config", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, effectiveConfig(dbService))
+	})
+
+	// reconnect to the database with the credentials re-read from the environment
+	if reconnector, ok := dbService.(db_service.Reconnector); ok {
+		admin.POST("/reconnect", func(ctx *gin.Context) {
+			if err := reconnector.Reconnect(ctx.Request.Context()); err != nil {
+				ctx.JSON(http.StatusBadGateway, gin.H{
+					"status":  "Bad Gateway",
+					"message": "Failed to reconnect to database",
+					"error":   err.Error(),
+				})
+				return
+			}
+			ctx.Status(http.StatusNoContent)
+		})
+	}
+}
+
+// effectiveConfig reports the settings the service resolved from the environment, secrets are redacted
+func effectiveConfig(dbService interface{}) gin.H {
+	redacted := func(secret string) string {
+		if secret == "" {
+			return ""
+		}
+		return "***"
+	}
+
+	result := gin.H{
+		"service": gin.H{
+			"port":           os.Getenv("AMBULANCE_API_PORT"),
+			"basePath":       basePath(os.Getenv("AMBULANCE_API_BASE_PATH")),
+			"ginMode":        gin.Mode(),
+			"enableGzip":     os.Getenv("AMBULANCE_API_ENABLE_GZIP"),
+			"accessLog":      os.Getenv("AMBULANCE_API_ACCESS_LOG"),
+			"requestTimeout": os.Getenv("AMBULANCE_API_REQUEST_TIMEOUT"),
+			"responseTime":   os.Getenv("AMBULANCE_API_ENABLE_RESPONSE_TIME"),
+			"maxHeaderBytes": os.Getenv("AMBULANCE_API_MAX_HEADER_BYTES"),
+			"maxConnections": os.Getenv("AMBULANCE_API_MAX_CONNECTIONS"),
+			"maxStreams":     os.Getenv("AMBULANCE_API_MAX_STREAMS"),
+			"seedFile":       os.Getenv("AMBULANCE_API_SEED_FILE"),
+			"dbBackend":      os.Getenv("AMBULANCE_API_DB_BACKEND"),
+			"cacheTtl":       os.Getenv("AMBULANCE_API_CACHE_TTL"),
+			"cacheSize":      os.Getenv("AMBULANCE_API_CACHE_SIZE"),
+			"adminToken":     redacted(os.Getenv("AMBULANCE_API_ADMIN_TOKEN")),
+			"metricsAuth":    redacted(os.Getenv("AMBULANCE_API_METRICS_AUTH")),
+		},
+		"waitingList": ambulance_wl.ServerSettings(),
+	}
+	if provider, ok := dbService.(db_service.ConfigProvider); ok {
+		result["database"] = provider.EffectiveConfig().Redacted()
+	}
+	return result
+}
+
+// checkDatabase verifies the database is reachable during the startup, so that the misconfigured
+// deployment is visible before the first request arrives
+func checkDatabase(dbService interface{}, failFast bool) {
+	pinger, ok := dbService.(db_service.Pinger)
+	if !ok {
+		return
+	}
+	if err := pinger.Ping(context.Background()); err != nil {
+		if failFast {
+			log.Fatalf("Database is not reachable: %v", err)
+		}
+		log.Printf("WARNING: Database is not reachable, continuing: %v", err)
+		return
+	}
+	log.Printf("Database connection verified")
+}
+
+// ensureIndexes creates the indexes of the services, failures are only reported - the service may
+// still operate without them, e.g. if the database user is not allowed to create indexes
+func ensureIndexes(services ...interface{}) {
+	for _, service := range services {
+		if manager, ok := service.(db_service.IndexManager); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := manager.EnsureIndexes(ctx); err != nil {
+				log.Printf("WARNING: Failed to create database indexes: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// seedDatabase inserts the missing ambulances of the seed source, failures are only reported
+func seedDatabase(dbService db_service.DbService[ambulance_wl.Ambulance], source string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := ambulance_wl.SeedAmbulances(ctx, dbService, source); err != nil {
+		log.Printf("WARNING: Failed to seed ambulances from %v: %v", source, err)
+	}
+}
+
 func main() {
-	log.Printf("Server started")
+	log.Printf("Server started, version %v, commit %v, built %v", version, gitCommit, buildTime)
 
 	port := os.Getenv("AMBULANCE_API_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	environment := os.Getenv("AMBULANCE_API_ENVIRONMENT")
-	if !strings.EqualFold(environment, "production") { // case insensitive comparison
-		gin.SetMode(gin.DebugMode)
-	}
+	limits := serverLimitsConfig(
+		os.Getenv("AMBULANCE_API_MAX_HEADER_BYTES"),
+		os.Getenv("AMBULANCE_API_MAX_CONNECTIONS"),
+		os.Getenv("AMBULANCE_API_MAX_STREAMS"),
+	)
+
+	gin.SetMode(ginMode(os.Getenv("AMBULANCE_API_ENVIRONMENT"), os.Getenv("AMBULANCE_API_GIN_MODE")))
 	engine := gin.New()
 	engine.Use(gin.Recovery())
 
+	res, err := newResource(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+
+	// structured logs correlated with the traces, if enabled
+	// the otlp logs exporter is configured by the `OTEL_EXPORTER_OTLP_*` variables as the trace exporter
+	shutdownLogs, err := logging.Setup(context.Background(), os.Getenv("OTEL_LOGS_EXPORTER"), os.Stdout, res)
+	if err != nil {
+		log.Fatalf("Failed to initialize logs exporter: %v", err)
+	}
+	defer func() { _ = shutdownLogs(context.Background()) }()
+
 	// setup telemetry
-	shutdown, err := initTelemetry()
+	shutdown, err := initTelemetry(res)
 	if err != nil {
 		log.Fatalf("Failed to initialize telemetry: %v", err)
 	}
@@ -108,31 +537,135 @@ func main() {
 			"Ambulance WebAPI Service",
 			// Custom attributes
 			otelginmetrics.WithAttributes(func(serverName, route string, request *http.Request) []attribute.KeyValue {
-				return append(otelginmetrics.DefaultAttributes(serverName, route, request))
+				return otelginmetrics.DefaultAttributes(serverName, route, request)
 			}),
 		),
 		otelgin.Middleware("wl-webapi-server"),
 	)
 
+	// structured record of each handled request, after the tracing so the trace id can correlate it
+	if enableAccessLog, _ := strconv.ParseBool(os.Getenv("AMBULANCE_API_ACCESS_LOG")); enableAccessLog {
+		engine.Use(middleware.AccessLog(accessLogConfig(
+			os.Getenv("AMBULANCE_API_ACCESS_LOG_FIELDS"),
+			os.Getenv("AMBULANCE_API_ACCESS_LOG_LEVEL"),
+			os.Getenv("AMBULANCE_API_ACCESS_LOG_SKIP_PATHS"),
+		)))
+	}
+
+	// error rates per route for alerting
+	handlerErrors, err := middleware.HandlerErrorsMetrics(otel.Meter("ambulance-webapi-http"))
+	if err != nil {
+		log.Fatalf("Failed to initialize handler error metrics: %v", err)
+	}
+	engine.Use(handlerErrors)
+
+	// cross-origin access of the web applications served from other domains
+	if origins := os.Getenv("AMBULANCE_API_CORS_ALLOWED_ORIGINS"); origins != "" {
+		engine.Use(middleware.Cors(corsConfig(
+			origins,
+			os.Getenv("AMBULANCE_API_CORS_EXPOSED_HEADERS"),
+			os.Getenv("AMBULANCE_API_CORS_MAX_AGE"),
+		)))
+	}
+
+	// compress large responses for the clients on slow links
+	if enableGzip, _ := strconv.ParseBool(os.Getenv("AMBULANCE_API_ENABLE_GZIP")); enableGzip {
+		engine.Use(middleware.Gzip(middleware.DefaultGzipMinSize))
+	}
+
+	// overall deadline of the request processing, including the database operations
+	if value := os.Getenv("AMBULANCE_API_REQUEST_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			engine.Use(middleware.Timeout(timeout))
+		} else {
+			log.Printf("Invalid request timeout value: %v", value)
+		}
+	}
+
+	// latency as seen by the service, for the clients debugging without access to the traces
+	if enableResponseTime, _ := strconv.ParseBool(os.Getenv("AMBULANCE_API_ENABLE_RESPONSE_TIME")); enableResponseTime {
+		engine.Use(middleware.ResponseTime())
+	}
+
+	// long lived streams may not take up the connections of the regular requests
+	engine.Use(middleware.LimitStreams(limits.MaxStreams))
+
 	// setup context update  middleware
-	dbService := db_service.NewMongoService[ambulance_wl.Ambulance](db_service.MongoServiceConfig{})
+	dbBackend := os.Getenv("AMBULANCE_API_DB_BACKEND")
+	dbService := newDbService[ambulance_wl.Ambulance](dbBackend)
 	defer dbService.Disconnect(context.Background())
+	if connectOnStart, _ := strconv.ParseBool(os.Getenv("AMBULANCE_API_DB_CONNECT_ON_START")); connectOnStart {
+		failFast, _ := strconv.ParseBool(os.Getenv("AMBULANCE_API_DB_FAIL_FAST"))
+		checkDatabase(dbService, failFast)
+	}
+	// registry of the waiting patients enforces single entry of the patient across the service instances
+	patientRegistry := newDbService[ambulance_wl.PatientRegistration](
+		dbBackend,
+		db_service.WithCollection("waiting_list_patients"),
+		db_service.WithUniqueIndex("ambulanceid", "patientid"),
+	)
+	defer patientRegistry.Disconnect(context.Background())
+	// append-only record of the waiting list changes
+	auditLog := newDbService[ambulance_wl.AuditEntry](
+		dbBackend,
+		db_service.WithCollection("waiting_list_audit"),
+	)
+	defer auditLog.Disconnect(context.Background())
+	go ensureIndexes(dbService, patientRegistry)
+
+	// initial data for the demo and CI environments
+	if seedSource := os.Getenv("AMBULANCE_API_SEED_FILE"); seedSource != "" {
+		go seedDatabase(dbService, seedSource)
+	}
+
+	// hot ambulances are read from the memory, the writes through the cached service invalidate the cached
+	// copies; the other uses of the service need its uncached interfaces
+	requestDbService := withCache(dbService)
 	engine.Use(func(ctx *gin.Context) {
-		ctx.Set("db_service", dbService)
+		ctx.Set("db_service", requestDbService)
+		ctx.Set("patient_registry", patientRegistry)
+		ctx.Set("audit_log", auditLog)
 		ctx.Next()
 	})
 
+	// propagate changes made by any replica of the service to the subscribers of this instance
+	if watcher, ok := dbService.(db_service.DocumentWatcher[ambulance_wl.Ambulance]); ok {
+		watchCtx, stopWatching := context.WithCancel(context.Background())
+		defer stopWatching()
+		go func() {
+			err := watcher.WatchDocuments(watchCtx, ambulance_wl.PublishAmbulanceChange)
+			if err != nil && err != context.Canceled {
+				log.Printf("Watching of ambulance changes stopped: %v", err)
+			}
+		}()
+	}
+
+	// mark the entries of the patients who left without notice
+	sweepCtx, stopSweeping := context.WithCancel(context.Background())
+	defer stopSweeping()
+	// the swept ambulances must not be served from the cache of the requests
+	go ambulance_wl.RunNoShowSweeper(sweepCtx, requestDbService, auditLog)
+
+	// select language of the response messages
+	engine.Use(ambulance_wl.LanguageMiddleware())
+
 	// request routings
-	ambulance_wl.AddRoutes(engine)
+	mountRoutes(engine, basePath(os.Getenv("AMBULANCE_API_BASE_PATH")))
 
-	// openapi spec endpoint
-	engine.GET("/openapi", api.HandleOpenApi)
+	// operational endpoints
+	mountAdminRoutes(engine, os.Getenv("AMBULANCE_API_ADMIN_TOKEN"), dbService)
 
-	// metrics endpoint
-	promhandler := promhttp.Handler()
-	engine.Any("/metrics", func(ctx *gin.Context) {
-		promhandler.ServeHTTP(ctx.Writer, ctx.Request)
-	})
+	// metrics endpoint, open unless the credentials are configured
+	if err := mountMetrics(engine, os.Getenv("AMBULANCE_API_METRICS_AUTH")); err != nil {
+		log.Fatalf("Invalid AMBULANCE_API_METRICS_AUTH value: %v", err)
+	}
 
-	engine.Run(":" + port)
+	// trailing slash and letter case of the paths do not matter to the clients
+	server := newServer(port, middleware.NormalizePaths(engine), limits)
+	log.Printf("Listening on :%v", port)
+	if err := serve(server, limits); err != nil {
+		log.Printf("Server stopped: %v", err)
+	}
+	// changes of the requests cut off by the shutdown timeout are still stored, before the database is disconnected
+	ambulance_wl.FlushPendingWrites()
 }
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/ambulance_wl"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/milung/ambulance-webapi/internal/middleware"
+	"github.com/stretchr/testify/suite"
+)
+
+type MainSuite struct {
+	suite.Suite
+}
+
+func TestMainSuite(t *testing.T) {
+	suite.Run(t, new(MainSuite))
+}
+
+func (suite *MainSuite) serve(engine *gin.Engine, method string, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+	return recorder
+}
+
+func (suite *MainSuite) Test_BasePath_Normalized() {
+	suite.Equal("", basePath(""))
+	suite.Equal("", basePath("/"))
+	suite.Equal("/api/v1", basePath("api/v1/"))
+	suite.Equal("/api/v1", basePath("/api/v1"))
+}
+
+func (suite *MainSuite) Test_GinMode_DerivedFromEnvironment() {
+	suite.Equal(gin.ReleaseMode, ginMode("Production", ""))
+	suite.Equal(gin.DebugMode, ginMode("staging", ""))
+	suite.Equal(gin.DebugMode, ginMode("", ""))
+}
+
+func (suite *MainSuite) Test_GinMode_ExplicitModeOverridesEnvironment() {
+	suite.Equal(gin.ReleaseMode, ginMode("staging", "release"))
+	suite.Equal(gin.DebugMode, ginMode("production", "debug"))
+	suite.Equal(gin.TestMode, ginMode("production", "TEST"))
+	// invalid value falls back to the environment default
+	suite.Equal(gin.ReleaseMode, ginMode("production", "verbose"))
+}
+
+func (suite *MainSuite) Test_CorsConfig_ExposesDefaultHeaders() {
+	config := corsConfig("https://a.example.com, https://b.example.com", "", "")
+	suite.Equal([]string{"https://a.example.com", "https://b.example.com"}, config.AllowedOrigins)
+	suite.Equal(middleware.DefaultCorsExposedHeaders, config.ExposedHeaders)
+	suite.Equal(middleware.DefaultCorsMaxAge, config.MaxAge)
+
+	config = corsConfig("*", "X-Custom", "1h")
+	suite.Equal([]string{"X-Custom"}, config.ExposedHeaders)
+	suite.Equal(time.Hour, config.MaxAge)
+}
+
+func (suite *MainSuite) Test_AccessLogConfig_ParsesSettings() {
+	config := accessLogConfig("", "", "")
+	suite.Equal(slog.LevelInfo, config.Level)
+	suite.Empty(config.Fields)
+	suite.Equal(middleware.DefaultAccessLogSkipPaths, config.SkipPaths)
+
+	config = accessLogConfig("method, status, unknown", "debug", "/metrics, /admin")
+	suite.Equal(slog.LevelDebug, config.Level)
+	suite.Equal([]string{"method", "status"}, config.Fields)
+	suite.Equal([]string{"/metrics", "/admin"}, config.SkipPaths)
+}
+
+func (suite *MainSuite) Test_NewDbService_SelectsBackend() {
+	// in-memory storage has no server to ping
+	_, isMongo := newDbService[ambulance_wl.Ambulance]("memory").(db_service.Pinger)
+	suite.False(isMongo)
+
+	for _, backend := range []string{"", "mongo", "unknown"} {
+		_, isMongo := newDbService[ambulance_wl.Ambulance](backend).(db_service.Pinger)
+		suite.True(isMongo, backend)
+	}
+}
+
+func (suite *MainSuite) Test_MountRoutes_ReachableUnderBasePathOnly() {
+	// ARRANGE
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+
+	// ACT
+	mountRoutes(engine, "/prefix")
+
+	// ASSERT
+	openapi := suite.serve(engine, "GET", "/prefix/openapi?format=yaml")
+	suite.Equal(http.StatusOK, openapi.Code)
+	suite.Contains(openapi.Body.String(), "url: /prefix/api")
+	suite.Equal(http.StatusNotFound, suite.serve(engine, "GET", "/openapi").Code)
+
+	// no db service in the context - the handler is reached but fails
+	suite.Equal(http.StatusInternalServerError, suite.serve(engine, "GET", "/prefix/api/waiting-list/test/entries").Code)
+	suite.Equal(http.StatusNotFound, suite.serve(engine, "GET", "/api/waiting-list/test/entries").Code)
+}
+
+// configuredService reports the fixed database configuration
+type configuredService struct {
+	config db_service.MongoServiceConfig
+}
+
+func (this *configuredService) EffectiveConfig() db_service.MongoServiceConfig {
+	return this.config
+}
+
+func (suite *MainSuite) Test_AdminConfig_SecretsRedacted() {
+	// ARRANGE
+	gin.SetMode(gin.TestMode)
+	suite.T().Setenv("AMBULANCE_API_ADMIN_TOKEN", "admin-secret")
+	suite.T().Setenv("AMBULANCE_API_PORT", "8088")
+	engine := gin.New()
+	mountAdminRoutes(engine, "admin-secret", &configuredService{db_service.MongoServiceConfig{
+		ServerHost: "mongo",
+		ServerPort: 27017,
+		UserName:   "root",
+		Password:   "db-secret",
+		Collection: "ambulance",
+	}})
+	request := httptest.NewRequest("GET", "/admin/config", nil)
+	request.Header.Set("Authorization", "Bearer admin-secret")
+	recorder := httptest.NewRecorder()
+
+	// ACT
+	engine.ServeHTTP(recorder, request)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.NotContains(recorder.Body.String(), "db-secret")
+	suite.NotContains(recorder.Body.String(), "admin-secret")
+	var config struct {
+		Service  map[string]interface{}        `json:"service"`
+		Database db_service.MongoServiceConfig `json:"database"`
+	}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &config))
+	suite.Equal("***", config.Database.Password)
+	suite.Equal("mongo", config.Database.ServerHost)
+	suite.Equal("root", config.Database.UserName)
+	suite.Equal("ambulance", config.Database.Collection)
+	suite.Equal("8088", config.Service["port"])
+	suite.Equal("***", config.Service["adminToken"])
+}
+
+func (suite *MainSuite) Test_AdminConfig_RequiresToken() {
+	// ARRANGE
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	mountAdminRoutes(engine, "admin-secret", &configuredService{})
+
+	// ACT
+	recorder := suite.serve(engine, "GET", "/admin/config")
+
+	// ASSERT
+	suite.Equal(http.StatusUnauthorized, recorder.Code)
+}
+
+func (suite *MainSuite) Test_Version_ProvidesBuildInfoUnderBasePath() {
+	// ARRANGE
+	previousVersion, previousCommit, previousBuildTime := version, gitCommit, buildTime
+	defer func() { version, gitCommit, buildTime = previousVersion, previousCommit, previousBuildTime }()
+	version, gitCommit, buildTime = "1.2.0", "0a1b2c3", "2038-12-24T10:00:00Z"
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	mountRoutes(engine, "/prefix")
+
+	// ACT
+	recorder := suite.serve(engine, "GET", "/prefix/version")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	info := map[string]string{}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &info))
+	suite.Equal(map[string]string{
+		"version":   "1.2.0",
+		"gitCommit": "0a1b2c3",
+		"buildTime": "2038-12-24T10:00:00Z",
+		"goVersion": runtime.Version(),
+	}, info)
+}
+
+func (suite *MainSuite) Test_BuildInfo_DefaultsWithoutLdflags() {
+	info := buildInfo()
+	suite.Equal("dev", info["version"])
+	suite.Equal("unknown", info["gitCommit"])
+	suite.Equal("unknown", info["buildTime"])
+}
+
+func (suite *MainSuite) metricsStatus(auth string, configure func(request *http.Request)) int {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	suite.Require().NoError(mountMetrics(engine, auth))
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	if configure != nil {
+		configure(request)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func (suite *MainSuite) Test_Metrics_OpenWithoutAuth() {
+	suite.Equal(http.StatusOK, suite.metricsStatus("", nil))
+}
+
+func (suite *MainSuite) Test_Metrics_BearerAuth() {
+	suite.Equal(http.StatusUnauthorized, suite.metricsStatus("bearer:scrape-secret", nil))
+	suite.Equal(http.StatusUnauthorized, suite.metricsStatus("bearer:scrape-secret", func(request *http.Request) {
+		request.Header.Set("Authorization", "Bearer other")
+	}))
+	suite.Equal(http.StatusOK, suite.metricsStatus("bearer:scrape-secret", func(request *http.Request) {
+		request.Header.Set("Authorization", "Bearer scrape-secret")
+	}))
+}
+
+func (suite *MainSuite) Test_Metrics_BasicAuth() {
+	suite.Equal(http.StatusUnauthorized, suite.metricsStatus("basic:prometheus:pa:ss", nil))
+	suite.Equal(http.StatusOK, suite.metricsStatus("basic:prometheus:pa:ss", func(request *http.Request) {
+		request.SetBasicAuth("prometheus", "pa:ss")
+	}))
+}
+
+func (suite *MainSuite) Test_Metrics_InvalidAuthRejected() {
+	for _, auth := range []string{"bearer:", "basic:prometheus", "digest:user:password"} {
+		suite.Error(mountMetrics(gin.New(), auth), auth)
+	}
+}
+
+func (suite *MainSuite) Test_NewServer_MaxHeaderBytesFromConfig() {
+	// ARRANGE
+	limits := serverLimitsConfig("16384", "", "")
+
+	// ACT
+	server := newServer("8088", http.NotFoundHandler(), limits)
+
+	// ASSERT
+	suite.Equal(":8088", server.Addr)
+	suite.Equal(16384, server.MaxHeaderBytes)
+	suite.Zero(server.IdleTimeout)
+}
+
+func (suite *MainSuite) Test_ServerLimitsConfig_Defaults() {
+	limits := serverLimitsConfig("", "", "")
+	suite.Equal(serverLimits{MaxHeaderBytes: http.DefaultMaxHeaderBytes}, limits)
+
+	limits = serverLimitsConfig("-1", "many", "10")
+	suite.Equal(serverLimits{MaxHeaderBytes: http.DefaultMaxHeaderBytes, MaxStreams: 10}, limits)
+}
+
+func (suite *MainSuite) Test_ServerLimitsConfig_StreamsLeaveConnectionsForRequests() {
+	suite.Equal(50, serverLimitsConfig("", "100", "").MaxStreams)
+	suite.Equal(99, serverLimitsConfig("", "100", "500").MaxStreams)
+	suite.Equal(1, serverLimitsConfig("", "1", "").MaxStreams)
+
+	server := newServer("8080", http.NotFoundHandler(), serverLimitsConfig("", "100", ""))
+	suite.Equal(idleConnectionTimeout, server.IdleTimeout)
+}
@@ -21,20 +21,71 @@ type AmbulanceWaitingListAPI interface {
 	// internal registration of api routes
 	addRoutes(routerGroup *gin.RouterGroup)
 
+	// CheckinWaitingListEntry - Self check-in of the patient into waiting list
+	CheckinWaitingListEntry(ctx *gin.Context)
+
+	// CreateWaitingListEntries - Saves multiple new entries into waiting list
+	CreateWaitingListEntries(ctx *gin.Context)
+
 	// CreateWaitingListEntry - Saves new entry into waiting list
 	CreateWaitingListEntry(ctx *gin.Context)
 
+	// DeleteWaitingListEntries - Deletes the entries with the given statuses
+	DeleteWaitingListEntries(ctx *gin.Context)
+
 	// DeleteWaitingListEntry - Deletes specific entry
 	DeleteWaitingListEntry(ctx *gin.Context)
 
+	// GetPublicWaitingList - Provides the waiting list to be shown to the patients
+	GetPublicWaitingList(ctx *gin.Context)
+
+	// GetUpcomingWaitingListEntries - Provides the waiting list entries expected to be called soon
+	GetUpcomingWaitingListEntries(ctx *gin.Context)
+
+	// GetWaitingListAudit - Provides the audit records of the waiting list changes
+	GetWaitingListAudit(ctx *gin.Context)
+
+	// GetWaitingListChanges - Provides the changes of the waiting list since the last synchronization
+	GetWaitingListChanges(ctx *gin.Context)
+
+	// GetWaitingListDrainTime - Provides the estimated time when the waiting list is fully served
+	GetWaitingListDrainTime(ctx *gin.Context)
+
 	// GetWaitingListEntries - Provides the ambulance waiting list
 	GetWaitingListEntries(ctx *gin.Context)
 
+	// GetWaitingListEntriesByIds - Provides the waiting list entries with the given ids
+	GetWaitingListEntriesByIds(ctx *gin.Context)
+
 	// GetWaitingListEntry - Provides details about waiting list entry
 	GetWaitingListEntry(ctx *gin.Context)
 
+	// GetWaitingListEntryPosition - Provides the position of the entry in the queue
+	GetWaitingListEntryPosition(ctx *gin.Context)
+
+	// GetWaitingListEstimate - Provides the estimated wait of the patient not yet in the waiting list
+	GetWaitingListEstimate(ctx *gin.Context)
+
+	// GetWaitingListPatients - Provides ids of the patients in the waiting list
+	GetWaitingListPatients(ctx *gin.Context)
+
+	// GetWaitingListSchedule - Provides the computed schedule of the active entries
+	GetWaitingListSchedule(ctx *gin.Context)
+
+	// GetWaitingListThroughput - Provides the number of entries completed per time interval
+	GetWaitingListThroughput(ctx *gin.Context)
+
+	// TransferWaitingListEntry - Moves the entry to the waiting list of other ambulance
+	TransferWaitingListEntry(ctx *gin.Context)
+
+	// UpdateWaitingListDurations - Updates estimated durations of entries by their condition
+	UpdateWaitingListDurations(ctx *gin.Context)
+
 	// UpdateWaitingListEntry - Updates specific entry
 	UpdateWaitingListEntry(ctx *gin.Context)
+
+	// UpdateWaitingListStatuses - Changes the status of multiple entries at once
+	UpdateWaitingListStatuses(ctx *gin.Context)
 }
 
 // partial implementation of AmbulanceWaitingListAPI - all functions must be implemented in add on files
@@ -46,37 +97,139 @@ func newAmbulanceWaitingListAPI() AmbulanceWaitingListAPI {
 }
 
 func (this *implAmbulanceWaitingListAPI) addRoutes(routerGroup *gin.RouterGroup) {
+	routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/checkin", this.CheckinWaitingListEntry)
+	routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/batch", this.CreateWaitingListEntries)
 	routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/entries", this.CreateWaitingListEntry)
+	routerGroup.Handle(http.MethodDelete, "/waiting-list/:ambulanceId/entries", this.DeleteWaitingListEntries)
 	routerGroup.Handle(http.MethodDelete, "/waiting-list/:ambulanceId/entries/:entryId", this.DeleteWaitingListEntry)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/public", this.GetPublicWaitingList)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/upcoming", this.GetUpcomingWaitingListEntries)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/audit", this.GetWaitingListAudit)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/changes", this.GetWaitingListChanges)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/draintime", this.GetWaitingListDrainTime)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/schedule", this.GetWaitingListSchedule)
 	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/entries", this.GetWaitingListEntries)
+	routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/batch-get", this.GetWaitingListEntriesByIds)
 	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/entries/:entryId", this.GetWaitingListEntry)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/entries/:entryId/position", this.GetWaitingListEntryPosition)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/estimate", this.GetWaitingListEstimate)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/patients", this.GetWaitingListPatients)
+	routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/throughput", this.GetWaitingListThroughput)
+	routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/entries/:entryId/transfer", this.TransferWaitingListEntry)
+	routerGroup.Handle(http.MethodPatch, "/waiting-list/:ambulanceId/durations", this.UpdateWaitingListDurations)
 	routerGroup.Handle(http.MethodPut, "/waiting-list/:ambulanceId/entries/:entryId", this.UpdateWaitingListEntry)
+	routerGroup.Handle(http.MethodPatch, "/waiting-list/:ambulanceId/status", this.UpdateWaitingListStatuses)
 
 }
 
 // Copy following section to separate file, uncomment, and implemented as needed
+// // CheckinWaitingListEntry - Self check-in of the patient into waiting list
+// func (this *implAmbulanceWaitingListAPI) CheckinWaitingListEntry(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // CreateWaitingListEntries - Saves multiple new entries into waiting list
+// func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntries(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 // // CreateWaitingListEntry - Saves new entry into waiting list
 // func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntry(ctx *gin.Context) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // DeleteWaitingListEntries - Deletes the entries with the given statuses
+// func (this *implAmbulanceWaitingListAPI) DeleteWaitingListEntries(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 // // DeleteWaitingListEntry - Deletes specific entry
 // func (this *implAmbulanceWaitingListAPI) DeleteWaitingListEntry(ctx *gin.Context) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // GetPublicWaitingList - Provides the waiting list to be shown to the patients
+// func (this *implAmbulanceWaitingListAPI) GetPublicWaitingList(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetUpcomingWaitingListEntries - Provides the waiting list entries expected to be called soon
+// func (this *implAmbulanceWaitingListAPI) GetUpcomingWaitingListEntries(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListAudit - Provides the audit records of the waiting list changes
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListAudit(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListChanges - Provides the changes of the waiting list since the last synchronization
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListChanges(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListDrainTime - Provides the estimated time when the waiting list is fully served
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListDrainTime(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 // // GetWaitingListEntries - Provides the ambulance waiting list
 // func (this *implAmbulanceWaitingListAPI) GetWaitingListEntries(ctx *gin.Context) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // GetWaitingListEntriesByIds - Provides the waiting list entries with the given ids
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListEntriesByIds(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 // // GetWaitingListEntry - Provides details about waiting list entry
 // func (this *implAmbulanceWaitingListAPI) GetWaitingListEntry(ctx *gin.Context) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // GetWaitingListEntryPosition - Provides the position of the entry in the queue
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListEntryPosition(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListEstimate - Provides the estimated wait of the patient not yet in the waiting list
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListEstimate(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListPatients - Provides ids of the patients in the waiting list
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListPatients(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListSchedule - Provides the computed schedule of the active entries
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListSchedule(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // GetWaitingListThroughput - Provides the number of entries completed per time interval
+// func (this *implAmbulanceWaitingListAPI) GetWaitingListThroughput(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // TransferWaitingListEntry - Moves the entry to the waiting list of other ambulance
+// func (this *implAmbulanceWaitingListAPI) TransferWaitingListEntry(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // UpdateWaitingListDurations - Updates estimated durations of entries by their condition
+// func (this *implAmbulanceWaitingListAPI) UpdateWaitingListDurations(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 // // UpdateWaitingListEntry - Updates specific entry
 // func (this *implAmbulanceWaitingListAPI) UpdateWaitingListEntry(ctx *gin.Context) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // UpdateWaitingListStatuses - Changes the status of multiple entries at once
+// func (this *implAmbulanceWaitingListAPI) UpdateWaitingListStatuses(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
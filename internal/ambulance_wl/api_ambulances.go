@@ -27,6 +27,18 @@ type AmbulancesAPI interface {
 	// DeleteAmbulance - Deletes specific ambulance
 	DeleteAmbulance(ctx *gin.Context)
 
+	// ExportAmbulance - Provides snapshot of the ambulance document
+	ExportAmbulance(ctx *gin.Context)
+
+	// ImportAmbulance - Restores the ambulance document from the snapshot
+	ImportAmbulance(ctx *gin.Context)
+
+	// PatchAmbulance - Applies JSON Patch to specific ambulance
+	PatchAmbulance(ctx *gin.Context)
+
+	// UpdateAmbulanceMetadata - Updates the ambulance properties without touching the waiting list
+	UpdateAmbulanceMetadata(ctx *gin.Context)
+
 }
 
 // partial implementation of AmbulancesAPI - all functions must be implemented in add on files
@@ -41,6 +53,10 @@ func newAmbulancesAPI() AmbulancesAPI {
 func (this *implAmbulancesAPI) addRoutes(routerGroup *gin.RouterGroup) {
 	routerGroup.Handle( http.MethodPost, "/ambulance", this.CreateAmbulance) 
 	routerGroup.Handle( http.MethodDelete, "/ambulance/:ambulanceId", this.DeleteAmbulance) 
+	routerGroup.Handle( http.MethodGet, "/ambulance/:ambulanceId/export", this.ExportAmbulance) 
+	routerGroup.Handle( http.MethodPost, "/ambulance/:ambulanceId/import", this.ImportAmbulance) 
+	routerGroup.Handle( http.MethodPatch, "/ambulance/:ambulanceId", this.PatchAmbulance) 
+	routerGroup.Handle( http.MethodPatch, "/ambulance/:ambulanceId/metadata", this.UpdateAmbulanceMetadata) 
 
 }
 
@@ -55,4 +71,24 @@ func (this *implAmbulancesAPI) addRoutes(routerGroup *gin.RouterGroup) {
 //  	ctx.AbortWithStatus(http.StatusNotImplemented)
 // }
 //
+// // ExportAmbulance - Provides snapshot of the ambulance document
+// func (this *implAmbulancesAPI) ExportAmbulance(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // ImportAmbulance - Restores the ambulance document from the snapshot
+// func (this *implAmbulancesAPI) ImportAmbulance(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // PatchAmbulance - Applies JSON Patch to specific ambulance
+// func (this *implAmbulancesAPI) PatchAmbulance(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
+// // UpdateAmbulanceMetadata - Updates the ambulance properties without touching the waiting list
+// func (this *implAmbulancesAPI) UpdateAmbulanceMetadata(ctx *gin.Context) {
+//  	ctx.AbortWithStatus(http.StatusNotImplemented)
+// }
+//
 
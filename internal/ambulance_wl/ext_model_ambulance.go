@@ -1,14 +1,82 @@
 package ambulance_wl
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slices"
 )
 
+const (
+	tieBreakId           = "id"
+	tieBreakPatientId    = "patientId"
+	tieBreakTicketNumber = "ticketNumber"
+)
+
+var errWaitingListConflict = errors.New("waiting list conflict")
+
+// validateWaitingList verifies the invariants of the waiting list - entries must have
+// an id and patient id, and neither the entry id nor the patient id can be listed twice
+func (this *Ambulance) validateWaitingList() error {
+	entryIds := map[string]bool{}
+	patientIds := map[string]bool{}
+	for _, entry := range this.WaitingList {
+		if entry.Id == "" || entry.PatientId == "" {
+			return fmt.Errorf("entry ID and patient ID are required")
+		}
+		if entryIds[entry.Id] {
+			return fmt.Errorf("%w: entry %v is listed more than once", errWaitingListConflict, entry.Id)
+		}
+		if patientIds[entry.PatientId] {
+			return fmt.Errorf("%w: patient %v is already waiting", errWaitingListConflict, entry.PatientId)
+		}
+		entryIds[entry.Id] = true
+		patientIds[entry.PatientId] = true
+	}
+	return nil
+}
+
+// clone provides the deep copy of the ambulance, changes of the copy do not affect the original
+func (this *Ambulance) clone() *Ambulance {
+	result := *this
+	result.WaitingList = slices.Clone(this.WaitingList)
+	for i := range result.WaitingList {
+		entry := &result.WaitingList[i]
+		for _, stamp := range []**time.Time{&entry.CompletedAt, &entry.CreatedAt, &entry.UpdatedAt} {
+			if *stamp != nil {
+				copied := **stamp
+				*stamp = &copied
+			}
+		}
+	}
+	result.PredefinedConditions = slices.Clone(this.PredefinedConditions)
+	result.OfficeHours = slices.Clone(this.OfficeHours)
+	result.RemovedEntries = slices.Clone(this.RemovedEntries)
+	return &result
+}
+
+// hasCapacityFor checks whether additional active entries fit into the waiting list, done
+// and no-show entries do not count towards the MaxWaitingListSize limit
+func (this *Ambulance) hasCapacityFor(additional int) bool {
+	if this.MaxWaitingListSize <= 0 {
+		return true
+	}
+	active := 0
+	for _, entry := range this.WaitingList {
+		if entry.isActive() {
+			active++
+		}
+	}
+	return active+additional <= int(this.MaxWaitingListSize)
+}
+
 func (this *Ambulance) reconcileWaitingList(ctx context.Context) {
 	_, span := tracer.Start(ctx, "reconcileWaitingList",
 		trace.WithAttributes(attribute.String("ambulanceId", this.Id)),
@@ -16,41 +84,448 @@ func (this *Ambulance) reconcileWaitingList(ctx context.Context) {
 	)
 	defer span.End()
 
+	if len(this.WaitingList) == 0 {
+		return
+	}
+
 	slices.SortFunc(this.WaitingList, func(left, right WaitingListEntry) int {
-		if left.WaitingSince.Before(right.WaitingSince) {
+		return compareArrival(&left, &right)
+	})
+
+	// done and no-show entries are kept in the list, but do not occupy the ambulance anymore;
+	// each room is a separate queue, sorted order is kept within the rooms
+	queues := map[string][]*WaitingListEntry{}
+	for i := range this.WaitingList {
+		if entry := &this.WaitingList[i]; entry.isActive() {
+			queues[entry.Room] = append(queues[entry.Room], entry)
+		}
+	}
+	strategy, ok := reconcileStrategies[this.ReconcileStrategy]
+	if !ok {
+		span.AddEvent("unknown reconcile strategy, using fifo",
+			trace.WithAttributes(attribute.String("strategy", this.ReconcileStrategy)))
+		strategy = scheduleFifo
+	}
+	// the office hours are evaluated in the time zone of the ambulance, see nextOpenInterval,
+	// the estimates are stored in UTC
+	now := clock.Now()
+	for _, queue := range queues {
+		strategy(this, queue, now)
+		for _, entry := range queue {
+			entry.EstimatedStart = entry.EstimatedStart.UTC()
+		}
+	}
+}
+
+// compareArrival orders the entries by their waitingSince, the entries arriving at the same time by compareTieBreak
+func compareArrival(left, right *WaitingListEntry) int {
+	if order := left.WaitingSince.Compare(right.WaitingSince); order != 0 {
+		return order
+	}
+	return compareTieBreak(left, right)
+}
+
+// servingOrder provides the order in which the reconcile strategy serves the active entries of the room,
+// indexed by the entry id. The entries of the ambulance are not changed.
+func (this *Ambulance) servingOrder(room string) map[string]int {
+	queue := []*WaitingListEntry{}
+	for i := range this.WaitingList {
+		// the strategy computes the estimates of the copies
+		if entry := this.WaitingList[i]; entry.isActive() && entry.Room == room {
+			queue = append(queue, &entry)
+		}
+	}
+	slices.SortFunc(queue, compareArrival)
+	strategy, ok := reconcileStrategies[this.ReconcileStrategy]
+	if !ok {
+		strategy = scheduleFifo
+	}
+	strategy(this, queue, clock.Now())
+
+	order := make(map[string]int, len(queue))
+	for i, entry := range queue {
+		order[entry.Id] = i
+	}
+	return order
+}
+
+// queuePosition provides the position of the waiting entry in the queue of its room, rooms are queued independently.
+// The entries are served in the order of their estimated starts and the entries starting at the same time
+// in the order of the reconcile strategy.
+func (this *Ambulance) queuePosition(entry *WaitingListEntry) int32 {
+	order := this.servingOrder(entry.Room)
+	position := int32(1)
+	for _, waiting := range this.WaitingList {
+		if waiting.Id == entry.Id || waiting.Room != entry.Room || waiting.effectiveStatus() != statusWaiting {
+			continue
+		}
+		if compared := waiting.EstimatedStart.Compare(entry.EstimatedStart); compared < 0 ||
+			(compared == 0 && order[waiting.Id] < order[entry.Id]) {
+			position++
+		}
+	}
+	return position
+}
+
+// compareTieBreak orders the entries arriving at the same time, e.g. bulk imported with a single timestamp,
+// by the configured TieBreakField; the entry id decides last, so the order never depends on the stored order
+func compareTieBreak(left, right *WaitingListEntry) int {
+	order := 0
+	switch config.TieBreakField {
+	case tieBreakPatientId:
+		order = strings.Compare(left.PatientId, right.PatientId)
+	case tieBreakTicketNumber:
+		order = cmp.Compare(left.TicketNumber, right.TicketNumber)
+	}
+	if order == 0 {
+		order = strings.Compare(left.Id, right.Id)
+	}
+	if config.TieBreakDescending {
+		return -order
+	}
+	return order
+}
+
+// estimatesStale returns true if the stored estimates do not hold at the given time anymore - some waiting
+// entry was expected to start already, or was never estimated. Reads reconcile the list only if stale.
+func (this *Ambulance) estimatesStale(now time.Time) bool {
+	for _, entry := range this.WaitingList {
+		if entry.effectiveStatus() == statusWaiting &&
+			(entry.EstimatedStart.Before(now) || entry.EstimatedStart.Before(entry.WaitingSince)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextTicketNumber provides the ticket number of the entry arriving at the given time, one above the highest
+// ticket of the entries arriving the same day; numbers start from 1 at the midnight in the time zone of the ambulance
+func (this *Ambulance) nextTicketNumber(arrival time.Time) int32 {
+	location := this.location()
+	year, month, day := arrival.In(location).Date()
+	highest := int32(0)
+	for _, entry := range this.WaitingList {
+		entryYear, entryMonth, entryDay := entry.WaitingSince.In(location).Date()
+		if entryYear == year && entryMonth == month && entryDay == day && entry.TicketNumber > highest {
+			highest = entry.TicketNumber
+		}
+	}
+	return highest + 1
+}
+
+// location provides the time zone of the ambulance, UTC if not set or not known
+func (this *Ambulance) location() *time.Location {
+	if this.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(this.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// validateTimeZone verifies the time zone is empty or a known IANA time zone name, e.g. Europe/Bratislava
+func (this *Ambulance) validateTimeZone() error {
+	if this.TimeZone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(this.TimeZone); err != nil {
+		return fmt.Errorf("unknown time zone %v: %w", this.TimeZone, err)
+	}
+	return nil
+}
+
+// reconcileStrategy orders the active entries of the single room queue of the ambulance, sorted by their
+// waitingSince, and computes their estimated starts not earlier than now, see Ambulance.scheduleQueue
+type reconcileStrategy func(ambulance *Ambulance, queue []*WaitingListEntry, now time.Time)
+
+// strategies selectable by the ambulance ReconcileStrategy, empty value is the default
+var reconcileStrategies = map[string]reconcileStrategy{
+	"":                   scheduleFifo,
+	"fifo":               scheduleFifo,
+	"shortest-job-first": scheduleShortestJobFirst,
+	"priority":           schedulePriority,
+}
+
+// scheduleFifo serves the patients in the order of their arrival
+func scheduleFifo(ambulance *Ambulance, queue []*WaitingListEntry, now time.Time) {
+	ambulance.scheduleQueue(queue, now)
+}
+
+// scheduleShortestJobFirst serves the waiting patients with the shortest estimated duration first,
+// patients already in examination keep their slots; equal durations are served in the order of arrival
+func scheduleShortestJobFirst(ambulance *Ambulance, queue []*WaitingListEntry, now time.Time) {
+	slices.SortStableFunc(queue, func(left, right *WaitingListEntry) int {
+		leftExamined := left.Status == statusInExamination
+		rightExamined := right.Status == statusInExamination
+		switch {
+		case leftExamined && !rightExamined:
 			return -1
-		} else if left.WaitingSince.After(right.WaitingSince) {
+		case !leftExamined && rightExamined:
 			return 1
-		} else {
+		case leftExamined && rightExamined:
 			return 0
 		}
+		return int(left.EstimatedDurationMinutes) - int(right.EstimatedDurationMinutes)
 	})
+	ambulance.scheduleQueue(queue, now)
+}
 
-	// we assume the first entry EstimatedStart is the correct one (computed before previous entry was deleted)
-	// but cannot be before current time
-	// for sake of simplicity we ignore concepts of opening hours here
-
-	if this.WaitingList[0].EstimatedStart.Before(this.WaitingList[0].WaitingSince) {
-		this.WaitingList[0].EstimatedStart = this.WaitingList[0].WaitingSince
+// schedulePriority serves the emergencies first and then the patients with the highest effective priority,
+// which grows with the waiting time, see effectivePriority; patients already in examination keep their slots
+// and equal effective priorities are served in the order of arrival
+func schedulePriority(ambulance *Ambulance, queue []*WaitingListEntry, now time.Time) {
+	tier := func(entry *WaitingListEntry) int {
+		switch {
+		case entry.Status == statusInExamination:
+			return 0
+		case entry.Priority == priorityEmergency:
+			return 1
+		default:
+			return 2
+		}
 	}
+	slices.SortStableFunc(queue, func(left, right *WaitingListEntry) int {
+		if leftTier, rightTier := tier(left), tier(right); leftTier != rightTier || leftTier < 2 {
+			return leftTier - rightTier
+		}
+		leftPriority, rightPriority := left.effectivePriority(now), right.effectivePriority(now)
+		switch {
+		case leftPriority > rightPriority:
+			return -1
+		case leftPriority < rightPriority:
+			return 1
+		default:
+			return 0
+		}
+	})
+	ambulance.scheduleQueue(queue, now)
+}
 
-	if this.WaitingList[0].EstimatedStart.Before(time.Now()) {
-		this.WaitingList[0].EstimatedStart = time.Now()
+// scheduleQueue computes the estimated start of the entries in the queue, each entry is served
+// by the slot that becomes free the earliest, at most ConcurrentSlots entries are served at once.
+// Only the office hours are counted - the waiting entries start at the earliest opening after their
+// slot is free and the work left at the closing continues at the next opening, see schedule.
+func (this *Ambulance) scheduleQueue(active []*WaitingListEntry, now time.Time) {
+	if len(active) == 0 {
+		return
 	}
+	slots := max(int(this.ConcurrentSlots), 1)
 
-	nextEntryStart :=
-		this.WaitingList[0].EstimatedStart.
-			Add(time.Duration(this.WaitingList[0].EstimatedDurationMinutes) * time.Minute)
-	for _, entry := range this.WaitingList[1:] {
-		if entry.EstimatedStart.Before(nextEntryStart) {
-			entry.EstimatedStart = nextEntryStart
-		}
+	// we assume the EstimatedStart of the entries occupying the slots is the correct one
+	// (computed before previous entry was deleted) but cannot be before current time
+	slotFreeAt := make([]time.Time, 0, slots)
+	for _, entry := range active {
 		if entry.EstimatedStart.Before(entry.WaitingSince) {
 			entry.EstimatedStart = entry.WaitingSince
 		}
 
-		nextEntryStart =
-			entry.EstimatedStart.
-				Add(time.Duration(entry.EstimatedDurationMinutes) * time.Minute)
+		slot := len(slotFreeAt)
+		if slot < slots {
+			if entry.EstimatedStart.Before(now) {
+				entry.EstimatedStart = now
+			}
+			slotFreeAt = append(slotFreeAt, time.Time{})
+		} else {
+			slot = 0
+			for i, freeAt := range slotFreeAt {
+				if freeAt.Before(slotFreeAt[slot]) {
+					slot = i
+				}
+			}
+			if entry.EstimatedStart.Before(slotFreeAt[slot]) {
+				entry.EstimatedStart = slotFreeAt[slot]
+			}
+		}
+
+		work := time.Duration(entry.EstimatedDurationMinutes) * time.Minute
+		if entry.effectiveStatus() == statusInExamination {
+			// the examination is already running, the patient is not sent away at the closing
+			slotFreeAt[slot] = entry.EstimatedStart.Add(work)
+			continue
+		}
+		if from, _, ok := this.nextOpenInterval(entry.EstimatedStart); ok {
+			entry.EstimatedStart = from
+		}
+		slotFreeAt[slot] = this.addOpenTime(entry.EstimatedStart, work)
+	}
+}
+
+// drainTime provides the estimated time when the last active entry is done, at least the given time. Unlike
+// the reconciled estimates it counts only the office hours - the work left at the closing continues at the
+// next opening. Rooms are served independently, each by ConcurrentSlots slots, in the reconciled order.
+func (this *Ambulance) drainTime(now time.Time) time.Time {
+	drain := now
+	for _, scheduled := range this.schedule(now) {
+		if scheduled.EstimatedEnd.After(drain) {
+			drain = scheduled.EstimatedEnd
+		}
+	}
+	return drain
+}
+
+// schedule assigns the active entries to the slots of their rooms and computes their start and end counting
+// only the office hours, see drainTime. Entries in examination occupy the slots first, the waiting ones start
+// at the earliest opening after their slot is free. Rooms are provided in the order of their names, entries
+// of the room in the order they are served.
+func (this *Ambulance) schedule(now time.Time) []ScheduledWaitingListEntry {
+	queues := map[string][]*WaitingListEntry{}
+	for i := range this.WaitingList {
+		if entry := &this.WaitingList[i]; entry.isActive() {
+			queues[entry.Room] = append(queues[entry.Room], entry)
+		}
+	}
+	rooms := make([]string, 0, len(queues))
+	for room := range queues {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+
+	result := []ScheduledWaitingListEntry{}
+	slots := max(int(this.ConcurrentSlots), 1)
+	for _, room := range rooms {
+		queue := queues[room]
+		// entries in examination occupy the slots first, the waiting ones follow in the order of their estimates
+		slices.SortStableFunc(queue, func(left, right *WaitingListEntry) int {
+			leftExamined := left.effectiveStatus() == statusInExamination
+			rightExamined := right.effectiveStatus() == statusInExamination
+			switch {
+			case leftExamined && !rightExamined:
+				return -1
+			case !leftExamined && rightExamined:
+				return 1
+			}
+			return left.EstimatedStart.Compare(right.EstimatedStart)
+		})
+
+		position := int32(0)
+		slotFreeAt := make([]time.Time, 0, slots)
+		for _, entry := range queue {
+			slot := len(slotFreeAt)
+			if slot < slots {
+				slotFreeAt = append(slotFreeAt, now)
+			} else {
+				slot = 0
+				for i, freeAt := range slotFreeAt {
+					if freeAt.Before(slotFreeAt[slot]) {
+						slot = i
+					}
+				}
+			}
+
+			work := time.Duration(entry.EstimatedDurationMinutes) * time.Minute
+			var start, end time.Time
+			if entry.effectiveStatus() == statusInExamination {
+				// the examination is already running, the patient is not sent away at the closing
+				start = entry.EstimatedStart
+				end = start.Add(work)
+			} else {
+				position++
+				start = slotFreeAt[slot]
+				if entry.WaitingSince.After(start) {
+					start = entry.WaitingSince
+				}
+				if from, _, ok := this.nextOpenInterval(start); ok {
+					start = from
+				}
+				end = this.addOpenTime(start, work)
+			}
+			if end.After(slotFreeAt[slot]) {
+				slotFreeAt[slot] = end
+			}
+
+			result = append(result, ScheduledWaitingListEntry{
+				Id:             entry.Id,
+				PatientId:      entry.PatientId,
+				Name:           entry.Name,
+				Room:           entry.Room,
+				Status:         entry.effectiveStatus(),
+				Position:       position,
+				Slot:           int32(slot + 1),
+				EstimatedStart: start.UTC(),
+				EstimatedEnd:   end.UTC(),
+			})
+		}
+	}
+	return result
+}
+
+// validate lists all problems of the provided metadata, each problem prefixed by the path of its field,
+// so that the form of the client can mark all invalid fields at once. Empty list means the metadata are valid.
+func (this *AmbulanceMetadata) validate() []string {
+	problems := []string{}
+	if this.TimeZone != nil {
+		if err := (&Ambulance{TimeZone: *this.TimeZone}).validateTimeZone(); err != nil {
+			problems = append(problems, fmt.Sprintf("timeZone: %v", err))
+		}
+	}
+	if this.OfficeHours != nil {
+		problems = append(problems, validateOfficeHours(*this.OfficeHours)...)
+	}
+	if this.MaxWaitingListSize != nil && *this.MaxWaitingListSize < 0 {
+		problems = append(problems, fmt.Sprintf("maxWaitingListSize: %d is negative", *this.MaxWaitingListSize))
+	}
+	if this.PredefinedConditions != nil {
+		// typical duration is the default estimated duration of the entries of the condition
+		for i, condition := range *this.PredefinedConditions {
+			if condition.TypicalDurationMinutes < 0 {
+				problems = append(problems, fmt.Sprintf(
+					"predefinedConditions[%d].typicalDurationMinutes: %d is negative", i, condition.TypicalDurationMinutes))
+			}
+		}
+	}
+	return problems
+}
+
+// storedFields maps the provided metadata to the fields of the stored ambulance document
+func (this *AmbulanceMetadata) storedFields() bson.M {
+	fields := bson.M{}
+	if this.Name != nil {
+		fields["name"] = *this.Name
+	}
+	if this.RoomNumber != nil {
+		fields["roomnumber"] = *this.RoomNumber
+	}
+	if this.PredefinedConditions != nil {
+		fields["predefinedconditions"] = *this.PredefinedConditions
+	}
+	if this.MaxWaitingListSize != nil {
+		fields["maxwaitinglistsize"] = *this.MaxWaitingListSize
+	}
+	if this.AllowFutureWaitingSince != nil {
+		fields["allowfuturewaitingsince"] = *this.AllowFutureWaitingSince
+	}
+	if this.TimeZone != nil {
+		fields["timezone"] = *this.TimeZone
+	}
+	if this.OfficeHours != nil {
+		fields["officehours"] = *this.OfficeHours
+	}
+	return fields
+}
+
+// applyTo sets the provided metadata on the ambulance, the waiting list is not changed
+func (this *AmbulanceMetadata) applyTo(ambulance *Ambulance) {
+	if this.Name != nil {
+		ambulance.Name = *this.Name
+	}
+	if this.RoomNumber != nil {
+		ambulance.RoomNumber = *this.RoomNumber
+	}
+	if this.PredefinedConditions != nil {
+		ambulance.PredefinedConditions = *this.PredefinedConditions
+	}
+	if this.MaxWaitingListSize != nil {
+		ambulance.MaxWaitingListSize = *this.MaxWaitingListSize
+	}
+	if this.AllowFutureWaitingSince != nil {
+		ambulance.AllowFutureWaitingSince = *this.AllowFutureWaitingSince
+	}
+	if this.TimeZone != nil {
+		ambulance.TimeZone = *this.TimeZone
+	}
+	if this.OfficeHours != nil {
+		ambulance.OfficeHours = *this.OfficeHours
 	}
 }
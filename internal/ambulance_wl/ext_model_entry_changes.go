@@ -0,0 +1,102 @@
+package ambulance_wl
+
+import (
+	"reflect"
+	"time"
+)
+
+// trackEntryChanges stamps the entries added or changed since the previous state of the ambulance and records
+// the removed ones, so that the clients can synchronize only the changes, see GetWaitingListChanges. The stamps
+// are provided by the service only, the removed entries are forgotten after the AMBULANCE_API_SYNC_RETENTION.
+func (this *Ambulance) trackEntryChanges(previous *Ambulance, now time.Time) {
+	// the database keeps the milliseconds only, the synchronization token must match the stored stamps
+	now = now.UTC().Truncate(time.Millisecond)
+	known := make(map[string]*WaitingListEntry, len(previous.WaitingList))
+	for i := range previous.WaitingList {
+		known[previous.WaitingList[i].Id] = &previous.WaitingList[i]
+	}
+
+	present := make(map[string]bool, len(this.WaitingList))
+	for i := range this.WaitingList {
+		entry := &this.WaitingList[i]
+		present[entry.Id] = true
+		before, ok := known[entry.Id]
+		switch {
+		case !ok:
+			entry.CreatedAt, entry.UpdatedAt = &now, &now
+		case entry.sameContent(before):
+			entry.CreatedAt, entry.UpdatedAt = before.CreatedAt, before.UpdatedAt
+		default:
+			entry.CreatedAt, entry.UpdatedAt = before.CreatedAt, &now
+		}
+	}
+
+	cutoff := now.Add(-config.SyncRetention)
+	removed := []RemovedWaitingListEntry{}
+	for _, entry := range previous.RemovedEntries {
+		// the entry added back is not removed anymore
+		if entry.RemovedAt.After(cutoff) && !present[entry.Id] {
+			removed = append(removed, entry)
+		}
+	}
+	for _, entry := range previous.WaitingList {
+		if !present[entry.Id] {
+			removed = append(removed, RemovedWaitingListEntry{Id: entry.Id, RemovedAt: now})
+		}
+	}
+	this.RemovedEntries = removed
+}
+
+// sameContent is true if the entries differ at most by their change stamps
+func (this *WaitingListEntry) sameContent(other *WaitingListEntry) bool {
+	// times are compared as instants, their locations differ between the stored and the computed ones
+	if !this.WaitingSince.Equal(other.WaitingSince) || !this.EstimatedStart.Equal(other.EstimatedStart) {
+		return false
+	}
+	if (this.CompletedAt == nil) != (other.CompletedAt == nil) ||
+		(this.CompletedAt != nil && !this.CompletedAt.Equal(*other.CompletedAt)) {
+		return false
+	}
+	left, right := *this, *other
+	for _, entry := range []*WaitingListEntry{&left, &right} {
+		entry.WaitingSince, entry.EstimatedStart = time.Time{}, time.Time{}
+		entry.CompletedAt, entry.CreatedAt, entry.UpdatedAt = nil, nil, nil
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// changesSince provides the changes of the waiting list at or after the given time, the changes made in the same
+// millisecond as the token are provided again as updated rather than missed, so the clients shall add the updated
+// entries they do not know yet. All entries are provided as added on reset.
+func (this *Ambulance) changesSince(since time.Time, reset bool) WaitingListChanges {
+	changes := WaitingListChanges{
+		Added:   []WaitingListEntry{},
+		Updated: []WaitingListEntry{},
+		Removed: []string{},
+		Reset:   reset,
+	}
+	latest := since
+	present := make(map[string]bool, len(this.WaitingList))
+	for _, entry := range this.WaitingList {
+		present[entry.Id] = true
+		if entry.UpdatedAt != nil && entry.UpdatedAt.After(latest) {
+			latest = *entry.UpdatedAt
+		}
+		switch {
+		case reset || (entry.CreatedAt != nil && entry.CreatedAt.After(since)):
+			changes.Added = append(changes.Added, entry)
+		case entry.UpdatedAt != nil && !entry.UpdatedAt.Before(since):
+			changes.Updated = append(changes.Updated, entry)
+		}
+	}
+	for _, entry := range this.RemovedEntries {
+		if entry.RemovedAt.After(latest) {
+			latest = entry.RemovedAt
+		}
+		if !reset && !present[entry.Id] && !entry.RemovedAt.Before(since) {
+			changes.Removed = append(changes.Removed, entry.Id)
+		}
+	}
+	changes.SyncToken = latest.UTC().Format(time.RFC3339Nano)
+	return changes
+}
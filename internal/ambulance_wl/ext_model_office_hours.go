@@ -0,0 +1,126 @@
+package ambulance_wl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+)
+
+// layout of the opening and closing times of the office hours
+const officeHoursLayout = "15:04"
+
+// names of the weekdays accepted in the office hours, in the order of the week
+var weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// validateOfficeHours lists all problems of the office hours - unknown weekdays, malformed times,
+// intervals not closing after their opening, and intervals overlapping other intervals of the same day.
+// Empty list means the office hours are well formed.
+func validateOfficeHours(hours []OfficeHours) []string {
+	type interval struct {
+		index            int
+		opening, closing time.Time
+	}
+
+	problems := []string{}
+	days := map[string][]interval{}
+	for i, hour := range hours {
+		weekday := strings.ToLower(hour.Weekday)
+		if !slices.Contains(weekdays, weekday) {
+			problems = append(problems, fmt.Sprintf("officeHours[%d]: unknown weekday %q", i, hour.Weekday))
+		}
+		opening, openErr := time.Parse(officeHoursLayout, hour.Open)
+		if openErr != nil {
+			problems = append(problems, fmt.Sprintf("officeHours[%d]: open %q is not in the format HH:MM", i, hour.Open))
+		}
+		closing, closeErr := time.Parse(officeHoursLayout, hour.Close)
+		if closeErr != nil {
+			problems = append(problems, fmt.Sprintf("officeHours[%d]: close %q is not in the format HH:MM", i, hour.Close))
+		}
+		if openErr != nil || closeErr != nil {
+			continue
+		}
+		if !closing.After(opening) {
+			problems = append(problems, fmt.Sprintf("officeHours[%d]: close %v is not after open %v", i, hour.Close, hour.Open))
+			continue
+		}
+		days[weekday] = append(days[weekday], interval{i, opening, closing})
+	}
+
+	for _, weekday := range weekdays {
+		intervals := days[weekday]
+		slices.SortFunc(intervals, func(left, right interval) int { return left.opening.Compare(right.opening) })
+		for i := 1; i < len(intervals); i++ {
+			previous, current := intervals[i-1], intervals[i]
+			if current.opening.Before(previous.closing) {
+				problems = append(problems, fmt.Sprintf(
+					"officeHours[%d]: overlaps officeHours[%d] on %v", current.index, previous.index, hours[current.index].Weekday))
+			}
+		}
+	}
+	return problems
+}
+
+// invalidOfficeHoursResponse lists all problems of the office hours in the error response
+func invalidOfficeHoursResponse(status interface{}, problems []string) gin.H {
+	return gin.H{
+		"status":   status,
+		"message":  "Invalid office hours of the ambulance",
+		"problems": problems,
+	}
+}
+
+// nextOpenInterval provides the earliest part of the office hours at or after the given time, the interval
+// starts at the given time if the ambulance is open at that moment. Not ok if the ambulance has no usable
+// office hours, in such case it is treated as always open.
+func (this *Ambulance) nextOpenInterval(after time.Time) (from, to time.Time, ok bool) {
+	location := this.location()
+	after = after.In(location)
+	// every weekday is visited once, the interval of today may be already closed so today is visited again at the end
+	for day := 0; day <= len(weekdays); day++ {
+		date := after.AddDate(0, 0, day)
+		year, month, dayOfMonth := date.Date()
+		weekday := strings.ToLower(date.Weekday().String())
+		for _, hour := range this.OfficeHours {
+			opening, openErr := time.Parse(officeHoursLayout, hour.Open)
+			closing, closeErr := time.Parse(officeHoursLayout, hour.Close)
+			if strings.ToLower(hour.Weekday) != weekday || openErr != nil || closeErr != nil {
+				continue
+			}
+			openAt := time.Date(year, month, dayOfMonth, opening.Hour(), opening.Minute(), 0, 0, location)
+			closeAt := time.Date(year, month, dayOfMonth, closing.Hour(), closing.Minute(), 0, 0, location)
+			if !closeAt.After(after) || !closeAt.After(openAt) {
+				continue
+			}
+			if openAt.Before(after) {
+				openAt = after
+			}
+			if !ok || openAt.Before(from) {
+				from, to, ok = openAt, closeAt, true
+			}
+		}
+		if ok {
+			return from, to, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// addOpenTime provides the time when the work of the given length started at the given time is done, counting
+// only the office hours - work not finished before the closing continues at the next opening
+func (this *Ambulance) addOpenTime(start time.Time, work time.Duration) time.Time {
+	for work > 0 {
+		from, to, ok := this.nextOpenInterval(start)
+		if !ok {
+			return start.Add(work)
+		}
+		if available := to.Sub(from); work <= available {
+			return from.Add(work)
+		}
+		work -= to.Sub(from)
+		start = to
+	}
+	return start
+}
@@ -0,0 +1,160 @@
+package ambulance_wl
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/exp/slices"
+)
+
+// maximal length of the entry note, in characters
+const maxNoteLength = 500
+
+const (
+	statusWaiting       = "waiting"
+	statusInExamination = "in-examination"
+	statusDone          = "done"
+	statusNoShow        = "no-show"
+)
+
+// isValidStatus checks the status is one of the known waiting list entry states
+func isValidStatus(status string) bool {
+	switch status {
+	case statusWaiting, statusInExamination, statusDone, statusNoShow:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	sourceWalkin   = "walkin"
+	sourcePhone    = "phone"
+	sourceReferral = "referral"
+	sourceOnline   = "online"
+)
+
+// isValidSource checks the source is one of the known ways the patient arrives to the waiting list
+func isValidSource(source string) bool {
+	switch source {
+	case sourceWalkin, sourcePhone, sourceReferral, sourceOnline:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	priorityRoutine   = "routine"
+	priorityUrgent    = "urgent"
+	priorityEmergency = "emergency"
+)
+
+// rank of the priorities used by the priority reconcile strategy, emergency is not ranked as it is always served first
+var priorityRanks = map[string]float64{
+	"":              0,
+	priorityRoutine: 0,
+	priorityUrgent:  1,
+}
+
+// isValidPriority checks the priority is one of the known triage priorities
+func isValidPriority(priority string) bool {
+	switch priority {
+	case priorityRoutine, priorityUrgent, priorityEmergency:
+		return true
+	default:
+		return false
+	}
+}
+
+// effectivePriority is the rank of the entry priority increased by config.PriorityAgingRate for each hour
+// the entry waits, so the long-waiting routine patients are not starved by the urgent ones arriving later
+func (this *WaitingListEntry) effectivePriority(now time.Time) float64 {
+	waited := max(now.Sub(this.WaitingSince), 0)
+	return priorityRanks[this.Priority] + config.PriorityAgingRate*waited.Hours()
+}
+
+// arrivalSource provides the source of the entry, the entries stored before the source was tracked arrived as walkin
+func (this *WaitingListEntry) arrivalSource() string {
+	if this.Source == "" {
+		return sourceWalkin
+	}
+	return this.Source
+}
+
+// allowed changes of the entry status, setting the current status again is always allowed
+var statusTransitions = map[string][]string{
+	statusWaiting:       {statusInExamination, statusDone, statusNoShow},
+	statusInExamination: {statusWaiting, statusDone},
+	statusNoShow:        {statusWaiting},
+	statusDone:          {},
+}
+
+// canTransitionTo checks the entry may change its status to the given one, e.g. the patient
+// who did not show up may come back later but the done examination cannot be restarted
+func (this *WaitingListEntry) canTransitionTo(status string) bool {
+	current := this.effectiveStatus()
+	return current == status || slices.Contains(statusTransitions[current], status)
+}
+
+// changeStatus sets the status of the entry and records the time the entry became done,
+// the completion time is cleared if the entry is not done
+func (this *WaitingListEntry) changeStatus(status string, now time.Time) {
+	switch {
+	case status != statusDone:
+		this.CompletedAt = nil
+	case this.CompletedAt == nil:
+		completedAt := now.UTC()
+		this.CompletedAt = &completedAt
+	}
+	this.Status = status
+}
+
+// effectiveStatus returns the state of the entry, entries stored before the status
+// was introduced are considered to be waiting
+func (this *WaitingListEntry) effectiveStatus() string {
+	if this.Status == "" {
+		return statusWaiting
+	}
+	return this.Status
+}
+
+// isActive returns true if the entry still occupies the ambulance - it is either waiting or in examination
+func (this *WaitingListEntry) isActive() bool {
+	status := this.effectiveStatus()
+	return status == statusWaiting || status == statusInExamination
+}
+
+// isAbandoned returns true if the waiting patient was not served long after the arrival - the arrival
+// is older than the grace multiple of the estimated duration
+func (this *WaitingListEntry) isAbandoned(now time.Time, graceMultiple float64) bool {
+	if this.effectiveStatus() != statusWaiting || graceMultiple <= 0 {
+		return false
+	}
+	grace := time.Duration(graceMultiple * float64(time.Duration(this.EstimatedDurationMinutes)*time.Minute))
+	return now.Sub(this.WaitingSince) > grace
+}
+
+// number of the trailing characters of the patient id shown publicly
+const publicPatientIdLength = 3
+
+// maskPatientId hides all but the last characters of the patient id, the ids too short to be
+// masked safely are hidden completely
+func maskPatientId(patientId string) string {
+	runes := []rune(patientId)
+	if len(runes) <= publicPatientIdLength {
+		return "***"
+	}
+	return "***" + string(runes[len(runes)-publicPatientIdLength:])
+}
+
+// sanitizeNote removes the control characters from the note, including line breaks and tabs
+func sanitizeNote(note string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, note)
+}
@@ -0,0 +1,93 @@
+package ambulance_wl
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+)
+
+// upper limit of the entries generated by the single request, protects the database from oversized documents
+const maxGeneratedEntries = 5000
+
+// GenerateWaitingListEntries - Appends synthetic entries to the waiting list, intended for the load tests.
+// Available only if enabled by the configuration.
+func GenerateWaitingListEntries(ctx *gin.Context) {
+	if !config.EntryGeneratorEnabled {
+		ctx.JSON(
+			http.StatusForbidden,
+			gin.H{
+				"status":  "Forbidden",
+				"message": "Entry generator is disabled, set AMBULANCE_API_ENABLE_ENTRY_GENERATOR to enable it",
+			})
+		return
+	}
+
+	count, err := strconv.Atoi(ctx.DefaultQuery("count", "100"))
+	if err != nil || count < 1 || count > maxGeneratedEntries {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": fmt.Sprintf("Query parameter count must be between 1 and %d", maxGeneratedEntries),
+			})
+		return
+	}
+
+	// the generated entries are stored directly, buffered changes stored later would overwrite them
+	writes.flush(ctx.Param("ambulanceId"))
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GenerateWaitingListEntries")
+		defer span.End()
+
+		previous := ambulance.clone()
+		start := time.Now()
+		now := clock.Now()
+		for i := 0; i < count; i++ {
+			entry := WaitingListEntry{
+				Id:                       newId(),
+				Name:                     fmt.Sprintf("Synthetic Patient %d", i+1),
+				WaitingSince:             now.Add(-time.Duration(rand.Intn(120)) * time.Minute),
+				EstimatedDurationMinutes: int32(5 + rand.Intn(56)),
+			}
+			// synthetic patients are not registered in the patient registry
+			entry.PatientId = "synthetic-" + entry.Id
+			if conditions := ambulance.PredefinedConditions; len(conditions) > 0 {
+				entry.Condition = conditions[rand.Intn(len(conditions))]
+			}
+			ambulance.WaitingList = append(ambulance.WaitingList, entry)
+		}
+		generated := time.Now()
+
+		ambulance.reconcileWaitingList(spanctx)
+		reconciled := time.Now()
+
+		// stored here rather than by updateAmbulanceFunc, so that the write is part of the timing
+		if !isDryRun(c) {
+			db := c.MustGet("db_service").(db_service.DbService[Ambulance])
+			ambulance.trackEntryChanges(previous, now)
+			if err := storeAmbulance(spanctx, db, ambulance.Id, ambulance); err == db_service.ErrPreconditionFailed {
+				return nil, changedAmbulanceResponse(c, http.StatusConflict, err), http.StatusConflict
+			} else if err != nil {
+				return nil, gin.H{
+					"status":  http.StatusBadGateway,
+					"message": "Failed to update ambulance in database",
+					"error":   err.Error(),
+				}, http.StatusBadGateway
+			}
+		}
+		stored := time.Now()
+
+		return nil, gin.H{
+			"generated":   count,
+			"total":       len(ambulance.WaitingList),
+			"generateMs":  generated.Sub(start).Milliseconds(),
+			"reconcileMs": reconciled.Sub(generated).Milliseconds(),
+			"storeMs":     stored.Sub(reconciled).Milliseconds(),
+		}, http.StatusOK
+	})
+}
@@ -0,0 +1,238 @@
+package ambulance_wl
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// upper limit of the ambulances imported by the single request
+const maxImportedAmbulances = 1000
+
+// columns of the imported CSV, the header row names them in any order; id and name are required,
+// the other columns provide the defaults of the ambulance settings and may be omitted
+var ambulanceCsvColumns = map[string]func(ambulance *Ambulance, value string) error{
+	"id": func(ambulance *Ambulance, value string) error {
+		if !isValidId(value) {
+			return fmt.Errorf("id %q is malformed", value)
+		}
+		ambulance.Id = value
+		return nil
+	},
+	"name": func(ambulance *Ambulance, value string) error {
+		if value == "" {
+			return errors.New("name is required")
+		}
+		ambulance.Name = value
+		return nil
+	},
+	"roomnumber": func(ambulance *Ambulance, value string) error {
+		ambulance.RoomNumber = value
+		return nil
+	},
+	"maxwaitinglistsize": func(ambulance *Ambulance, value string) error {
+		return parseCsvCount(value, "maxWaitingListSize", &ambulance.MaxWaitingListSize)
+	},
+	"concurrentslots": func(ambulance *Ambulance, value string) error {
+		return parseCsvCount(value, "concurrentSlots", &ambulance.ConcurrentSlots)
+	},
+	"reconcilestrategy": func(ambulance *Ambulance, value string) error {
+		if _, ok := reconcileStrategies[value]; !ok {
+			return fmt.Errorf("reconcileStrategy %q is not known", value)
+		}
+		ambulance.ReconcileStrategy = value
+		return nil
+	},
+	"timezone": func(ambulance *Ambulance, value string) error {
+		ambulance.TimeZone = value
+		return ambulance.validateTimeZone()
+	},
+}
+
+// names of the columns of the imported CSV as reported to the client
+var ambulanceCsvColumnNames = []string{
+	"id", "name", "roomNumber", "maxWaitingListSize", "concurrentSlots", "reconcileStrategy", "timeZone",
+}
+
+// parseCsvCount parses the non-negative number of the column, empty value means zero
+func parseCsvCount(value string, column string, target *int32) error {
+	if value == "" {
+		*target = 0
+		return nil
+	}
+	count, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || count < 0 {
+		return fmt.Errorf("%v %q is not a non-negative integer", column, value)
+	}
+	*target = int32(count)
+	return nil
+}
+
+// ambulanceImportResult is the result of the single row of the imported CSV
+type ambulanceImportResult struct {
+	// number of the row in the CSV, the header is the row 1
+	Row int `json:"row"`
+	// id of the ambulance of the row, empty if the row has no valid id
+	Id string `json:"id,omitempty"`
+	// HTTP status of the row, 201 if the ambulance was created
+	Status int `json:"status"`
+	// reason of the rejection of the row
+	Message string `json:"message,omitempty"`
+}
+
+// ImportAmbulancesCsv - Creates the ambulances listed in the CSV, one ambulance per row, intended for
+// the onboarding of multiple clinics at once. Invalid rows and existing ambulances are reported in
+// the per-row results and do not prevent creation of the other rows - 201 Created is provided if all
+// ambulances were created, 207 Multi-Status otherwise.
+func ImportAmbulancesCsv(ctx *gin.Context) {
+	spanctx, span := tracer.Start(ctx.Request.Context(), "ImportAmbulancesCsv")
+	defer span.End()
+
+	if mediaType, _, err := mime.ParseMediaType(ctx.ContentType()); err != nil || mediaType != "text/csv" {
+		ctx.JSON(
+			http.StatusUnsupportedMediaType,
+			gin.H{
+				"status":  "Unsupported Media Type",
+				"message": "Request body must be of text/csv type",
+			})
+		return
+	}
+
+	db, ok := ctx.MustGet("db_service").(db_service.DbService[Ambulance])
+	if !ok {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service context is not of type db_service.DbService",
+				"error":   "cannot cast db_service context to db_service.DbService",
+			})
+		return
+	}
+
+	reader := csv.NewReader(ctx.Request.Body)
+	reader.TrimLeadingSpace = true
+	// rows of different length are reported per row rather than failing the whole import
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == nil {
+		err = validateCsvHeader(header)
+	}
+	if err != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Invalid CSV header, columns id and name are required",
+				"columns": ambulanceCsvColumnNames,
+				"error":   err.Error(),
+			})
+		return
+	}
+
+	results := []ambulanceImportResult{}
+	created := 0
+	for number := 2; ; number++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(results) == maxImportedAmbulances {
+			ctx.JSON(
+				http.StatusRequestEntityTooLarge,
+				gin.H{
+					"status":  "Request Entity Too Large",
+					"message": fmt.Sprintf("At most %d ambulances can be imported at once, nothing after the row %d was imported", maxImportedAmbulances, number-1),
+					"results": results,
+				})
+			return
+		}
+
+		result := ambulanceImportResult{Row: number, Status: http.StatusCreated}
+		var ambulance *Ambulance
+		if err == nil {
+			ambulance, err = parseCsvAmbulance(header, row)
+		}
+		if ambulance != nil {
+			result.Id = ambulance.Id
+		}
+		if err != nil {
+			result.Status = http.StatusBadRequest
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		switch err := db.CreateDocument(spanctx, ambulance.Id, ambulance); {
+		case err == nil:
+			created++
+		case errors.Is(err, db_service.ErrConflict):
+			result.Status = http.StatusConflict
+			result.Message = localize(ctx, msgAmbulanceConflict)
+		default:
+			result.Status = http.StatusBadGateway
+			result.Message = "Failed to create ambulance in database: " + err.Error()
+		}
+		results = append(results, result)
+	}
+
+	span.SetAttributes(attribute.Int("rows", len(results)), attribute.Int("created", created))
+	span.AddEvent("ambulances imported", trace.WithAttributes(attribute.Int("failed", len(results)-created)))
+	if created < len(results) {
+		ctx.JSON(http.StatusMultiStatus, results)
+		return
+	}
+	ctx.JSON(http.StatusCreated, results)
+}
+
+// validateCsvHeader verifies the header names only the known columns, each at most once, id and name included
+func validateCsvHeader(header []string) error {
+	seen := map[string]bool{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		header[i] = column
+		if _, ok := ambulanceCsvColumns[column]; !ok {
+			return fmt.Errorf("column %q is not known", column)
+		}
+		if seen[column] {
+			return fmt.Errorf("column %q is listed more than once", column)
+		}
+		seen[column] = true
+	}
+	if !seen["id"] || !seen["name"] {
+		return errors.New("columns id and name are required")
+	}
+	return nil
+}
+
+// parseCsvAmbulance creates the ambulance from the row of the columns named by the header,
+// the ambulance is provided also on failure if its id was parsed
+func parseCsvAmbulance(header []string, row []string) (*Ambulance, error) {
+	if len(row) != len(header) {
+		return nil, fmt.Errorf("row has %d values, the header has %d columns", len(row), len(header))
+	}
+	ambulance := &Ambulance{WaitingList: []WaitingListEntry{}}
+	// id is parsed first, so that the failures of the other columns can be reported with the id
+	order := append([]string{"id"}, header...)
+	for _, column := range order {
+		index := slices.Index(header, column)
+		if err := ambulanceCsvColumns[column](ambulance, strings.TrimSpace(row[index])); err != nil {
+			if ambulance.Id == "" {
+				return nil, err
+			}
+			return ambulance, err
+		}
+	}
+	return ambulance, nil
+}
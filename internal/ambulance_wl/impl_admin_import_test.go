@@ -0,0 +1,60 @@
+package ambulance_wl
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AmbulanceWlSuite) Test_ImportAmbulancesCsv_InvalidRowReportedOthersCreated() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, "existing", mock.Anything).
+		Return(db_service.ErrConflict)
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	csv := "Id,Name,RoomNumber,MaxWaitingListSize\n" +
+		"clinic-a,Clinic A,101,10\n" +
+		"clinic-b,Clinic B,102,many\n" +
+		"clinic-c,Clinic C,,\n" +
+		"existing,Existing Clinic,103,5\n"
+	ctx, recorder := suite.newRequestContext("POST", "/admin/ambulances/import", csv)
+	ctx.Request.Header.Set("Content-Type", "text/csv; charset=utf-8")
+
+	// ACT
+	ImportAmbulancesCsv(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusMultiStatus, recorder.Code)
+	var results []ambulanceImportResult
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &results))
+	suite.Len(results, 4)
+	suite.Equal(ambulanceImportResult{Row: 2, Id: "clinic-a", Status: http.StatusCreated}, results[0])
+	suite.Equal(http.StatusBadRequest, results[1].Status)
+	suite.Equal("clinic-b", results[1].Id)
+	suite.Contains(results[1].Message, "maxWaitingListSize")
+	suite.Equal(http.StatusCreated, results[2].Status)
+	suite.Equal(http.StatusConflict, results[3].Status)
+
+	suite.dbServiceMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, "clinic-a",
+		mock.MatchedBy(func(ambulance *Ambulance) bool {
+			return ambulance.Name == "Clinic A" && ambulance.RoomNumber == "101" && ambulance.MaxWaitingListSize == 10
+		}))
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, "clinic-b", mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_ImportAmbulancesCsv_MissingNameColumn_BadRequest() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("POST", "/admin/ambulances/import", "id,roomNumber\nclinic-a,101\n")
+	ctx.Request.Header.Set("Content-Type", "text/csv")
+
+	// ACT
+	ImportAmbulancesCsv(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
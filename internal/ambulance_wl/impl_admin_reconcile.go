@@ -0,0 +1,108 @@
+package ambulance_wl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReconcileAmbulance - Recomputes and stores the waiting list of the ambulance, intended for operators
+// after the reconcile logic was changed or the stored data drifted
+func ReconcileAmbulance(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "ReconcileAmbulance")
+		defer span.End()
+
+		ambulance.reconcileWaitingList(spanctx)
+		result := ambulance.WaitingList
+		if result == nil {
+			result = []WaitingListEntry{}
+		}
+		return ambulance, result, http.StatusOK
+	})
+}
+
+// ReconcileAllAmbulances - Recomputes and stores the waiting lists of all stored ambulances,
+// failures of individual ambulances do not stop the processing and are reported in the response
+func ReconcileAllAmbulances(ctx *gin.Context) {
+	spanctx, span := tracer.Start(ctx.Request.Context(), "ReconcileAllAmbulances")
+	defer span.End()
+
+	value, exists := ctx.Get("db_service")
+	if !exists {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service not found",
+				"error":   "db_service not found",
+			})
+		return
+	}
+
+	db, ok := value.(db_service.DbService[Ambulance])
+	if !ok {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service context is not of type db_service.DbService",
+				"error":   "cannot cast db_service context to db_service.DbService",
+			})
+		return
+	}
+
+	// the stored ambulances are reconciled, buffered changes stored later would overwrite them
+	writes.flushAll()
+	reconciled := 0
+	failed := []string{}
+	afterId := ""
+	for {
+		ambulances, nextId, err := db.ListDocumentsAfter(spanctx, afterId, 0)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			ctx.JSON(
+				http.StatusBadGateway,
+				gin.H{
+					"status":     "Bad Gateway",
+					"message":    "Failed to load ambulances from database",
+					"error":      err.Error(),
+					"reconciled": reconciled,
+					"failed":     failed,
+				})
+			return
+		}
+
+		for _, ambulance := range ambulances {
+			err := changeStoredAmbulance(spanctx, db, ambulance, func(ambulance *Ambulance) bool {
+				previous := ambulance.clone()
+				ambulance.reconcileWaitingList(spanctx)
+				ambulance.trackEntryChanges(previous, clock.Now())
+				return true
+			})
+			if err != nil {
+				span.AddEvent("reconcile failed", trace.WithAttributes(
+					attribute.String("ambulance_id", ambulance.Id),
+					attribute.String("error", err.Error()),
+				))
+				failed = append(failed, ambulance.Id)
+				continue
+			}
+			reconciled++
+		}
+
+		if nextId == "" {
+			break
+		}
+		afterId = nextId
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"reconciled": reconciled,
+		"failed":     failed,
+	})
+}
@@ -0,0 +1,115 @@
+package ambulance_wl
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// staleAmbulance has the entries stored out of order with outdated estimates
+func staleAmbulance(id string, now time.Time) *Ambulance {
+	return &Ambulance{
+		Id: id,
+		WaitingList: []WaitingListEntry{
+			{Id: "later", PatientId: "p2", WaitingSince: now.Add(10 * time.Minute), EstimatedDurationMinutes: 15},
+			{Id: "earlier", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 15},
+		},
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileAmbulance_PersistsReconciledOrder() {
+	// ARRANGE
+	suite.givenAmbulance(staleAmbulance("test-ambulance", time.Now()))
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/admin/ambulance/test-ambulance/reconcile", "")
+
+	// ACT
+	ReconcileAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool {
+			return ambulance.WaitingList[0].Id == "earlier" && ambulance.WaitingList[1].Id == "later"
+		}), mock.Anything)
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	suite.Equal("earlier", entries[0].Id)
+	suite.Equal(entries[0].EstimatedStart.Add(15*time.Minute), entries[1].EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileAllAmbulances_WalksAllPages() {
+	// ARRANGE
+	now := time.Now()
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "", mock.Anything).
+		Return([]*Ambulance{staleAmbulance("first", now), staleAmbulance("second", now)}, "second", nil)
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "second", mock.Anything).
+		Return([]*Ambulance{staleAmbulance("third", now)}, "third", nil)
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "third", mock.Anything).
+		Return([]*Ambulance{}, "", nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, "second", mock.Anything, mock.Anything).
+		Return(errors.New("write failed"))
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/admin/reconcile-all", "")
+
+	// ACT
+	ReconcileAllAmbulances(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"reconciled": 2, "failed": ["second"]}`, recorder.Body.String())
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "third",
+		mock.MatchedBy(func(ambulance *Ambulance) bool {
+			return ambulance.WaitingList[0].Id == "earlier"
+		}), mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GenerateEntries_AppendsRequestedCount() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.EntryGeneratorEnabled = true
+	ambulance := &Ambulance{Id: "test-ambulance"}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/admin/ambulance/test-ambulance/generate?count=25", "")
+
+	// ACT
+	GenerateWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Len(ambulance.WaitingList, 25)
+	suite.NoError(ambulance.validateWaitingList())
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(float64(25), response["total"])
+}
+
+func (suite *AmbulanceWlSuite) Test_GenerateEntries_DisabledByDefault() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.EntryGeneratorEnabled = false
+	ctx, recorder := suite.newRequestContext("POST", "/admin/ambulance/test-ambulance/generate?count=25", "")
+
+	// ACT
+	GenerateWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusForbidden, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
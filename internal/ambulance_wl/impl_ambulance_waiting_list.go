@@ -1,64 +1,200 @@
 package ambulance_wl
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slices"
 )
 
-// CreateWaitingListEntry - Saves new entry into waiting list
-func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntry(ctx *gin.Context) {
+// CheckinWaitingListEntry - Self check-in of the patient into waiting list
+func (this *implAmbulanceWaitingListAPI) CheckinWaitingListEntry(ctx *gin.Context) {
+	var ambulanceId, patientId string
 	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
-		spanctx, span := tracer.Start(c.Request.Context(), "CreateWaitingListEntry")
+		spanctx, span := tracer.Start(c.Request.Context(), "CheckinWaitingListEntry")
 		defer span.End()
 
-		var entry WaitingListEntry
-
-		if err := c.ShouldBindJSON(&entry); err != nil {
+		var checkin WaitingListCheckin
+		if err := bindJSON(c, &checkin); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+		if checkin.PatientId = strings.TrimSpace(checkin.PatientId); checkin.PatientId == "" {
 			return nil, gin.H{
 				"status":  http.StatusBadRequest,
-				"message": "Invalid request body",
-				"error":   err.Error(),
+				"message": "Patient ID is required",
 			}, http.StatusBadRequest
 		}
 
-		if entry.PatientId == "" {
+		ambulanceId, patientId = ambulance.Id, checkin.PatientId
+		now := clock.Now()
+		if retryAfter, ok := checkins.allow(ambulance.Id, checkin.PatientId, now, config.CheckinInterval); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
 			return nil, gin.H{
-				"status":  http.StatusBadRequest,
-				"message": "Patient ID is required",
-			}, http.StatusBadRequest
+				"status":  http.StatusTooManyRequests,
+				"code":    msgCheckinTooSoon,
+				"message": localize(c, msgCheckinTooSoon),
+			}, http.StatusTooManyRequests
 		}
 
-		if entry.Id == "" || entry.Id == "@new" {
-			entry.Id = uuid.NewString()
+		if slices.ContainsFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+			return waiting.PatientId == checkin.PatientId
+		}) {
+			return nil, gin.H{
+				"status":  http.StatusConflict,
+				"code":    msgAlreadyCheckedIn,
+				"message": localize(c, msgAlreadyCheckedIn),
+			}, http.StatusConflict
 		}
 
-		if entry.WaitingSince.Before(time.Now()) {
-			entry.WaitingSince = time.Now()
+		// only the patient and the condition come from the kiosk, the rest is decided by the service
+		entry := WaitingListEntry{
+			PatientId:    checkin.PatientId,
+			WaitingSince: now,
+			Status:       statusWaiting,
+			Source:       sourceOnline,
+		}
+		if checkin.ConditionCode != "" {
+			conditionIndx := slices.IndexFunc(ambulance.PredefinedConditions, func(condition Condition) bool {
+				return condition.Code == checkin.ConditionCode
+			})
+			if conditionIndx < 0 {
+				return nil, gin.H{
+					"status":  http.StatusBadRequest,
+					"message": "Unknown condition code",
+				}, http.StatusBadRequest
+			}
+			entry.Condition = ambulance.PredefinedConditions[conditionIndx]
+			entry.EstimatedDurationMinutes = min(entry.Condition.TypicalDurationMinutes, config.MaxDurationMinutes)
 		}
 
-		if entry.EstimatedDurationMinutes <= 0 {
-			entry.EstimatedDurationMinutes = 15
+		if response, status := admitEntry(c, ambulance, &entry); response != nil {
+			return nil, response, status
 		}
 
-		conflictIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
-			return entry.Id == waiting.Id || entry.PatientId == waiting.PatientId
-		})
+		if response, status := registerEntryPatient(c, spanctx, ambulance, &entry); response != nil {
+			return nil, response, status
+		}
 
-		if conflictIndx >= 0 {
-			return nil, gin.H{
-				"status":  http.StatusConflict,
-				"message": "Entry already exists",
-			}, http.StatusConflict
+		ambulance.WaitingList = append(ambulance.WaitingList, entry)
+		ambulance.reconcileWaitingList(spanctx)
+		recordAudit(c, auditActionCreate, ambulance.Id, entry.Id)
+		if isDryRun(c) {
+			return ambulance, reconciledEntry(ambulance, entry.Id), http.StatusOK
+		}
+		entriesPath := strings.TrimSuffix(strings.TrimSuffix(c.Request.URL.Path, "/"), "/checkin") + "/entries/"
+		c.Header("Location", entriesPath+url.PathEscape(entry.Id))
+		return ambulance, reconciledEntry(ambulance, entry.Id), http.StatusCreated
+	})
+
+	// only the stored check-in limits the next one, not the rejected or previewed one
+	if ctx.Writer.Status() == http.StatusCreated {
+		checkins.record(ambulanceId, patientId, clock.Now(), config.CheckinInterval)
+	}
+}
+
+// CreateWaitingListEntries - Saves multiple new entries into waiting list
+func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntries(ctx *gin.Context) {
+	partial, _ := strconv.ParseBool(ctx.Query("partial"))
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "CreateWaitingListEntries")
+		defer span.End()
+
+		var entries []WaitingListEntry
+		if err := bindJSON(c, &entries); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+
+		// entries are admitted one by one, so that the later entries are verified against the earlier ones
+		results := make([]WaitingListBatchResult, len(entries))
+		admitted := []*WaitingListEntry{}
+		for i := range entries {
+			entry := &entries[i]
+			results[i].Index = int32(i)
+
+			response, status := admitEntry(c, ambulance, entry)
+			if response == nil {
+				response, status = registerEntryPatient(c, spanctx, ambulance, entry)
+			}
+			if response != nil {
+				if !partial {
+					// all or nothing - the patients claimed by the already admitted entries are released
+					// as the ambulance is not stored
+					response["index"] = i
+					return nil, response, status
+				}
+				results[i].Status = int32(status)
+				results[i].Message = fmt.Sprint(response["message"])
+				continue
+			}
+
+			ambulance.WaitingList = append(ambulance.WaitingList, *entry)
+			admitted = append(admitted, entry)
+			recordAudit(c, auditActionCreate, ambulance.Id, entry.Id)
+			results[i].Status = http.StatusCreated
+		}
+
+		if len(admitted) == 0 && partial {
+			// nothing to store
+			return nil, results, http.StatusMultiStatus
+		}
+
+		// single reconcile and single write for all admitted entries
+		ambulance.reconcileWaitingList(spanctx)
+		created := make([]WaitingListEntry, 0, len(admitted))
+		for i := range results {
+			if results[i].Status != http.StatusCreated {
+				continue
+			}
+			entry := reconciledEntry(ambulance, entries[i].Id)
+			results[i].Entry = &entry
+			created = append(created, entry)
+		}
+
+		if partial {
+			return ambulance, results, http.StatusMultiStatus
+		}
+		return ambulance, created, http.StatusOK
+	})
+}
+
+// CreateWaitingListEntry - Saves new entry into waiting list
+func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntry(ctx *gin.Context) {
+	// integrations may add the first entry before the ambulance is registered
+	allowAmbulanceAutoCreate(ctx)
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "CreateWaitingListEntry")
+		defer span.End()
+
+		var entry WaitingListEntry
+
+		if err := bindJSON(c, &entry); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+
+		if response, status := admitEntry(c, ambulance, &entry); response != nil {
+			return nil, response, status
+		}
+
+		if response, status := registerEntryPatient(c, spanctx, ambulance, &entry); response != nil {
+			return nil, response, status
 		}
 
 		ambulance.WaitingList = append(ambulance.WaitingList, entry)
 		ambulance.reconcileWaitingList(spanctx)
+		recordAudit(c, auditActionCreate, ambulance.Id, entry.Id)
 		// entry was copied by value return reconciled value from the list
 		entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
 			return entry.Id == waiting.Id
@@ -69,7 +205,327 @@ func (this *implAmbulanceWaitingListAPI) CreateWaitingListEntry(ctx *gin.Context
 				"message": "Failed to save entry",
 			}, http.StatusInternalServerError
 		}
-		return ambulance, ambulance.WaitingList[entryIndx], http.StatusOK
+		if isDryRun(c) {
+			// nothing is created
+			return ambulance, ambulance.WaitingList[entryIndx], http.StatusOK
+		}
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+url.PathEscape(entry.Id))
+		return ambulance, ambulance.WaitingList[entryIndx], http.StatusCreated
+	})
+}
+
+// admitEntry completes the defaults of the new entry and verifies it can be added to the waiting list,
+// provides the error response and status if the entry is rejected. The entry is not added to the list.
+func admitEntry(c *gin.Context, ambulance *Ambulance, entry *WaitingListEntry) (gin.H, int) {
+	if entry.PatientId == "" {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "Patient ID is required",
+		}, http.StatusBadRequest
+	}
+
+	generatedId := entry.Id == "" || entry.Id == "@new"
+	if generatedId {
+		entry.Id = newEntryId(ambulance.Id, entry)
+	} else if !isValidId(entry.Id) {
+		return invalidIdResponse(c, http.StatusBadRequest, "id", entry.Id), http.StatusBadRequest
+	}
+
+	now := clock.Now()
+	if response, status := validateWaitingSince(ambulance, entry.WaitingSince, now); response != nil {
+		return response, status
+	}
+	// scheduled arrivals are kept only if the ambulance accepts appointments
+	if entry.WaitingSince.Before(now) || !ambulance.AllowFutureWaitingSince {
+		entry.WaitingSince = now
+	}
+
+	if entry.EstimatedDurationMinutes == 0 {
+		entry.EstimatedDurationMinutes = 15
+	} else if response, status := validateDuration(entry.EstimatedDurationMinutes); response != nil {
+		return response, status
+	}
+
+	if entry.Note = sanitizeNote(entry.Note); utf8.RuneCountInString(entry.Note) > maxNoteLength {
+		return noteTooLongResponse()
+	}
+
+	if entry.Status == "" {
+		entry.Status = statusWaiting
+	} else if !isValidStatus(entry.Status) {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "Invalid entry status",
+		}, http.StatusBadRequest
+	}
+	// completion time is recorded by the service, not provided by the client
+	entry.CompletedAt = nil
+	entry.changeStatus(entry.Status, now)
+
+	if entry.Source == "" {
+		entry.Source = sourceWalkin
+	} else if !isValidSource(entry.Source) {
+		return invalidSourceResponse()
+	}
+
+	if entry.Priority == "" {
+		entry.Priority = priorityRoutine
+	} else if !isValidPriority(entry.Priority) {
+		return invalidPriorityResponse()
+	}
+
+	// collision of the generated id is not caused by the client, another id is generated instead;
+	// the random id is used also in the deterministic mode, the duplicate submission is the patient conflict below
+	for attempt := 0; generatedId && attempt < config.IdCollisionRetries; attempt++ {
+		if !slices.ContainsFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool { return entry.Id == waiting.Id }) {
+			break
+		}
+		entry.Id = newId()
+	}
+
+	conflictIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+		return entry.Id == waiting.Id || entry.PatientId == waiting.PatientId
+	})
+
+	if conflictIndx >= 0 {
+		return gin.H{
+			"status":  http.StatusConflict,
+			"code":    msgEntryConflict,
+			"message": localize(c, msgEntryConflict),
+		}, http.StatusConflict
+	}
+
+	if entry.isActive() && !ambulance.hasCapacityFor(1) {
+		return gin.H{
+			"status": http.StatusConflict,
+			"message": fmt.Sprintf(
+				"Waiting list is full, at most %d active entries are allowed", ambulance.MaxWaitingListSize),
+		}, http.StatusConflict
+	}
+
+	entry.TicketNumber = ambulance.nextTicketNumber(entry.WaitingSince)
+	return nil, http.StatusOK
+}
+
+// arrivals older than this are not accepted, the waiting list is not an archive of past visits
+const maxWaitingSinceAge = 24 * time.Hour
+
+// appointments cannot be booked further in advance
+const maxWaitingSinceAdvance = 365 * 24 * time.Hour
+
+// validateWaitingSince provides the error response and status if the waitingSince cannot be the arrival
+// of the patient; the same rule applies to the new and to the updated entries. Arrivals in the future are
+// not validated if the ambulance does not accept them, as they are replaced by the current time.
+func validateWaitingSince(ambulance *Ambulance, waitingSince time.Time, now time.Time) (gin.H, int) {
+	if waitingSince.IsZero() {
+		return nil, http.StatusOK
+	}
+	if waitingSince.Before(now.Add(-maxWaitingSinceAge)) {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": fmt.Sprintf("Waiting since must not be more than %d hours in the past", int(maxWaitingSinceAge.Hours())),
+		}, http.StatusBadRequest
+	}
+	if ambulance.AllowFutureWaitingSince && waitingSince.After(now.Add(maxWaitingSinceAdvance)) {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": fmt.Sprintf("Waiting since must not be more than %d days in the future", int(maxWaitingSinceAdvance.Hours()/24)),
+		}, http.StatusBadRequest
+	}
+	return nil, http.StatusOK
+}
+
+// validateDuration provides the error response and status if the estimated duration is not positive
+// or exceeds the configured maximum
+func validateDuration(minutes int32) (gin.H, int) {
+	if minutes <= 0 {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": "Estimated duration must be positive",
+		}, http.StatusBadRequest
+	}
+	if minutes > config.MaxDurationMinutes {
+		return gin.H{
+			"status":  http.StatusBadRequest,
+			"message": fmt.Sprintf("Estimated duration must not exceed %d minutes", config.MaxDurationMinutes),
+		}, http.StatusBadRequest
+	}
+	return nil, http.StatusOK
+}
+
+func noteTooLongResponse() (gin.H, int) {
+	return gin.H{
+		"status":  http.StatusBadRequest,
+		"message": fmt.Sprintf("Note must not exceed %d characters", maxNoteLength),
+	}, http.StatusBadRequest
+}
+
+func invalidSourceResponse() (gin.H, int) {
+	return gin.H{
+		"status":  http.StatusBadRequest,
+		"message": "Invalid entry source, use one of walkin, phone, referral, online",
+	}, http.StatusBadRequest
+}
+
+func invalidPriorityResponse() (gin.H, int) {
+	return gin.H{
+		"status":  http.StatusBadRequest,
+		"message": "Invalid entry priority, use one of routine, urgent, emergency",
+	}, http.StatusBadRequest
+}
+
+// registerEntryPatient claims the patient of the admitted entry in the patient registry, if the registry
+// is configured and the request is not a dry run
+func registerEntryPatient(c *gin.Context, ctx context.Context, ambulance *Ambulance, entry *WaitingListEntry) (gin.H, int) {
+	registry := patientRegistry(c)
+	if registry == nil || isDryRun(c) {
+		return nil, http.StatusOK
+	}
+	return registrationResponse(c, claimPatient(c, ctx, registry, ambulance, entry))
+}
+
+// registrationResponse maps the failure of the patient registration to the response, nil if it succeeded
+func registrationResponse(c *gin.Context, err error) (gin.H, int) {
+	switch err {
+	case nil:
+		return nil, http.StatusOK
+	case db_service.ErrConflict:
+		return gin.H{
+			"status":  http.StatusConflict,
+			"code":    msgEntryConflict,
+			"message": localize(c, msgEntryConflict),
+		}, http.StatusConflict
+	default:
+		return gin.H{
+			"status":  http.StatusBadGateway,
+			"message": "Failed to register patient",
+			"error":   err.Error(),
+		}, http.StatusBadGateway
+	}
+}
+
+// registerChangedPatients registers the patients listed in the updated ambulance but not in the previous one,
+// unless the updater already claimed them, e.g. the entries added by the patch of the ambulance. The registration
+// of the patient whose entry changed its id is moved to the new entry.
+func registerChangedPatients(c *gin.Context, ctx context.Context, previous *Ambulance, updated *Ambulance) (gin.H, int) {
+	registry := patientRegistry(c)
+	if registry == nil {
+		return nil, http.StatusOK
+	}
+	listed := patientEntries(previous)
+	claimed := map[string]bool{}
+	for _, entry := range claimedPatients(c) {
+		claimed[entry.PatientId] = true
+	}
+	for i := range updated.WaitingList {
+		entry := &updated.WaitingList[i]
+		entryId, wasListed := listed[entry.PatientId]
+		if entry.PatientId == "" || claimed[entry.PatientId] || (wasListed && entryId == entry.Id) {
+			continue
+		}
+		var err error
+		if wasListed {
+			err = reassignPatient(ctx, registry, updated, entry)
+		} else {
+			err = claimPatient(c, ctx, registry, updated, entry)
+		}
+		if response, status := registrationResponse(c, err); response != nil {
+			return response, status
+		}
+	}
+	return nil, http.StatusOK
+}
+
+// releaseClaimedPatients removes the registrations claimed by the request if the ambulance is not stored
+// after all, failures leave orphaned registrations which are taken over later
+func releaseClaimedPatients(c *gin.Context, ctx context.Context, ambulanceId string) {
+	claimed := claimedPatients(c)
+	c.Set(claimedPatientsKey, []WaitingListEntry{})
+	releaseEntryPatients(c, ctx, ambulanceId, claimed)
+}
+
+// releaseRemovedPatients removes the registrations of the patients no longer listed in the stored ambulance
+func releaseRemovedPatients(c *gin.Context, ctx context.Context, previous *Ambulance, updated *Ambulance) {
+	listed := patientEntries(updated)
+	removed := []WaitingListEntry{}
+	for _, entry := range previous.WaitingList {
+		if _, found := listed[entry.PatientId]; !found && entry.PatientId != "" {
+			removed = append(removed, entry)
+		}
+	}
+	releaseEntryPatients(c, ctx, updated.Id, removed)
+}
+
+// releaseEntryPatients removes the registrations of the patients of the entries,
+// failures leave orphaned registrations which are taken over later
+func releaseEntryPatients(c *gin.Context, ctx context.Context, ambulanceId string, entries []WaitingListEntry) {
+	registry := patientRegistry(c)
+	if registry == nil || isDryRun(c) {
+		return
+	}
+	for i := range entries {
+		if err := unregisterPatient(ctx, registry, ambulanceId, &entries[i]); err != nil {
+			trace.SpanFromContext(ctx).AddEvent(
+				"failed to unregister patient", trace.WithAttributes(attribute.String("error", err.Error())))
+		}
+	}
+}
+
+// DeleteWaitingListEntries - Deletes the entries with the given statuses
+func (this *implAmbulanceWaitingListAPI) DeleteWaitingListEntries(ctx *gin.Context) {
+	statuses := map[string]bool{}
+	if value := ctx.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); !isValidStatus(status) {
+				ctx.JSON(
+					http.StatusBadRequest,
+					gin.H{
+						"status":  "Bad Request",
+						"message": "Invalid entry status " + status,
+					})
+				return
+			}
+			statuses[status] = true
+		}
+	} else if confirmAll, _ := strconv.ParseBool(ctx.Query("confirmAll")); !confirmAll {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameter status is required, use confirmAll=true to delete all entries",
+			})
+		return
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "DeleteWaitingListEntries")
+		defer span.End()
+
+		kept := []WaitingListEntry{}
+		removed := []*WaitingListEntry{}
+		for i := range ambulance.WaitingList {
+			entry := &ambulance.WaitingList[i]
+			// no statuses given means all entries were confirmed
+			if len(statuses) == 0 || statuses[entry.effectiveStatus()] {
+				removed = append(removed, entry)
+			} else {
+				kept = append(kept, *entry)
+			}
+		}
+
+		result := gin.H{"removed": len(removed)}
+		if len(removed) == 0 {
+			// nothing changed - no need to update the ambulance in db
+			return nil, result, http.StatusOK
+		}
+
+		// the patients are released once the ambulance without them is stored
+		for _, entry := range removed {
+			recordAudit(c, auditActionDelete, ambulance.Id, entry.Id)
+		}
+		ambulance.WaitingList = kept
+		ambulance.reconcileWaitingList(spanctx)
+		return ambulance, result, http.StatusOK
 	})
 }
 
@@ -95,38 +551,383 @@ func (this *implAmbulanceWaitingListAPI) DeleteWaitingListEntry(ctx *gin.Context
 		if entryIndx < 0 {
 			return nil, gin.H{
 				"status":  http.StatusNotFound,
-				"message": "Entry not found",
+				"code":    msgEntryNotFound,
+				"message": localize(c, msgEntryNotFound),
 			}, http.StatusNotFound
 		}
 
+		recordAudit(c, auditActionDelete, ambulance.Id, entryId)
 		ambulance.WaitingList = append(ambulance.WaitingList[:entryIndx], ambulance.WaitingList[entryIndx+1:]...)
 		ambulance.reconcileWaitingList(spanctx)
 		return ambulance, nil, http.StatusNoContent
 	})
 }
 
+// GetPublicWaitingList - Provides the waiting list to be shown to the patients
+func (this *implAmbulanceWaitingListAPI) GetPublicWaitingList(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetPublicWaitingList")
+		defer span.End()
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
+		waiting := []WaitingListEntry{}
+		for _, entry := range ambulance.WaitingList {
+			if entry.effectiveStatus() == statusWaiting {
+				waiting = append(waiting, entry)
+			}
+		}
+		slices.SortStableFunc(waiting, func(left, right WaitingListEntry) int {
+			return left.EstimatedStart.Compare(right.EstimatedStart)
+		})
+
+		// only the projection is returned, names, notes, and conditions are never shown publicly
+		positions := map[string]int32{}
+		result := make([]PublicWaitingListEntry, 0, len(waiting))
+		for _, entry := range waiting {
+			positions[entry.Room]++
+			result = append(result, PublicWaitingListEntry{
+				Ticket:         maskPatientId(entry.PatientId),
+				TicketNumber:   entry.TicketNumber,
+				Room:           entry.Room,
+				Position:       positions[entry.Room],
+				EstimatedStart: entry.EstimatedStart,
+			})
+		}
+		return nil, result, http.StatusOK
+	})
+}
+
+// GetUpcomingWaitingListEntries - Provides the waiting list entries expected to be called soon
+func (this *implAmbulanceWaitingListAPI) GetUpcomingWaitingListEntries(ctx *gin.Context) {
+	withinMinutes, err := strconv.Atoi(ctx.Query("withinMinutes"))
+	if err != nil || withinMinutes <= 0 {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameter withinMinutes must be a positive number",
+			})
+		return
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetUpcomingWaitingListEntries")
+		defer span.End()
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
+		windowEnd := clock.Now().Add(time.Duration(withinMinutes) * time.Minute)
+		room, filterRoom := c.GetQuery("room")
+		result := []WaitingListEntry{}
+		for _, entry := range ambulance.WaitingList {
+			if filterRoom && entry.Room != room {
+				continue
+			}
+			// entries in examination were already called in
+			if entry.effectiveStatus() == statusWaiting && !entry.EstimatedStart.After(windowEnd) {
+				result = append(result, entry)
+			}
+		}
+		return nil, result, http.StatusOK
+	})
+}
+
+// GetWaitingListAudit - Provides the audit records of the waiting list changes
+func (this *implAmbulanceWaitingListAPI) GetWaitingListAudit(ctx *gin.Context) {
+	window := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lt"} {
+		value := ctx.Query(param)
+		if value == "" {
+			continue
+		}
+		bound, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ctx.JSON(
+				http.StatusBadRequest,
+				gin.H{
+					"status":  "Bad Request",
+					"message": "Query parameter " + param + " must be RFC 3339 date-time",
+					"error":   err.Error(),
+				})
+			return
+		}
+		window[operator] = bound
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListAudit")
+		defer span.End()
+
+		audit := auditLog(c)
+		if audit == nil {
+			return nil, gin.H{
+				"status":  http.StatusInternalServerError,
+				"message": "audit_log not found",
+				"error":   "audit_log not found",
+			}, http.StatusInternalServerError
+		}
+
+		filter := bson.M{"ambulanceid": ambulance.Id}
+		if len(window) > 0 {
+			filter["timestamp"] = window
+		}
+		records, err := audit.FindDocuments(spanctx, filter, db_service.WithSort("timestamp", true))
+		if err != nil {
+			return nil, gin.H{
+				"status":  http.StatusBadGateway,
+				"message": "Failed to load audit records from database",
+				"error":   err.Error(),
+			}, http.StatusBadGateway
+		}
+
+		result := make([]AuditEntry, 0, len(records))
+		for _, record := range records {
+			result = append(result, *record)
+		}
+		return nil, result, http.StatusOK
+	})
+}
+
+// GetWaitingListChanges - Provides the changes of the waiting list since the last synchronization
+func (this *implAmbulanceWaitingListAPI) GetWaitingListChanges(ctx *gin.Context) {
+	var since time.Time
+	if token := ctx.Query("since"); token != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, token)
+		if err != nil {
+			ctx.JSON(
+				http.StatusBadRequest,
+				gin.H{
+					"status":  "Bad Request",
+					"message": "Query parameter since must be the syncToken of the previous response",
+					"error":   err.Error(),
+				})
+			return
+		}
+		since = parsed
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		_, span := tracer.Start(c.Request.Context(), "GetWaitingListChanges")
+		defer span.End()
+
+		// the stored entries are provided without refreshing their estimates, the refresh is not a tracked change;
+		// the removed entries are forgotten after the retention, the older clients must reload the waiting list
+		reset := since.IsZero() || since.Before(clock.Now().Add(-config.SyncRetention))
+		changes := ambulance.changesSince(since, reset)
+		span.SetAttributes(
+			attribute.Bool("reset", reset),
+			attribute.Int("added", len(changes.Added)),
+			attribute.Int("updated", len(changes.Updated)),
+			attribute.Int("removed", len(changes.Removed)),
+		)
+		return nil, changes, http.StatusOK
+	})
+}
+
+// GetWaitingListDrainTime - Provides the estimated time when the waiting list is fully served
+func (this *implAmbulanceWaitingListAPI) GetWaitingListDrainTime(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListDrainTime")
+		defer span.End()
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		now := clock.Now()
+		if ambulance.estimatesStale(now) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
+		active := int32(0)
+		for _, entry := range ambulance.WaitingList {
+			if entry.isActive() {
+				active++
+			}
+		}
+		return nil, WaitingListDrainTime{
+			DrainTime:     ambulance.drainTime(now).UTC(),
+			ActiveEntries: active,
+		}, http.StatusOK
+	})
+}
+
 // GetWaitingListEntries - Provides the ambulance waiting list
 func (this *implAmbulanceWaitingListAPI) GetWaitingListEntries(ctx *gin.Context) {
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameter offset must be a non-negative number",
+			})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameter limit must be a positive number",
+			})
+		return
+	}
+	envelope, _ := strconv.ParseBool(ctx.Query("envelope"))
+	compare, ok := entriesOrdering(ctx.Query("sortBy"), ctx.DefaultQuery("order", "asc"))
+	if !ok {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameter sortBy must be one of waitingSince, estimatedStart, patientId and order one of asc, desc",
+			})
+		return
+	}
+
 	// update ambulance document
 	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
-		_, span := tracer.Start(c.Request.Context(), "GetWaitingListEntries")
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListEntries")
 		defer span.End()
 
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
 		result := ambulance.WaitingList
+		if room, ok := c.GetQuery("room"); ok {
+			result = []WaitingListEntry{}
+			for _, entry := range ambulance.WaitingList {
+				if entry.Room == room {
+					result = append(result, entry)
+				}
+			}
+		}
 		if result == nil {
 			result = []WaitingListEntry{}
 		}
+		if compare != nil {
+			// sorts only the provided view, the stored list keeps the reconciled order
+			result = slices.Clone(result)
+			slices.SortStableFunc(result, compare)
+		}
+
+		total := len(result)
+		result = result[min(offset, total):]
+		if limit > 0 {
+			result = result[:min(limit, len(result))]
+		}
+
+		var response interface{} = result
+		if envelope {
+			response = WaitingListEntriesPage{
+				Items:  result,
+				Total:  int32(total),
+				Limit:  int32(limit),
+				Offset: int32(offset),
+			}
+		}
+
+		// polling clients get the content only if it changed since their last request
+		if etag, err := weakETag(response); err == nil {
+			c.Header("ETag", etag)
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				return nil, nil, http.StatusNotModified
+			}
+		}
+		return nil, response, http.StatusOK
+	})
+}
+
+// GetWaitingListEntriesByIds - Provides the waiting list entries with the given ids
+func (this *implAmbulanceWaitingListAPI) GetWaitingListEntriesByIds(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListEntriesByIds")
+		defer span.End()
+
+		var request WaitingListEntryIds
+		if err := bindJSON(c, &request); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+		if len(request.EntryIds) == 0 {
+			return nil, gin.H{
+				"status":  http.StatusBadRequest,
+				"message": "At least one entry ID is required",
+			}, http.StatusBadRequest
+		}
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
+		// missing ids are reported instead of failing the whole request, repeated ids are provided once
+		result := WaitingListEntriesByIds{Entries: []WaitingListEntry{}, NotFound: []string{}}
+		requested := map[string]bool{}
+		for _, entryId := range request.EntryIds {
+			if requested[entryId] {
+				continue
+			}
+			requested[entryId] = true
+			entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+				return entryId == waiting.Id
+			})
+			if entryIndx < 0 {
+				result.NotFound = append(result.NotFound, entryId)
+			} else {
+				result.Entries = append(result.Entries, ambulance.WaitingList[entryIndx])
+			}
+		}
+		// return nil ambulance - no need to update it in db
 		return nil, result, http.StatusOK
 	})
 }
 
+// entriesOrdering provides the comparison of the entries by the sortBy field, nil if the reconciled
+// order shall be kept; ok is false for unknown field or order
+func entriesOrdering(sortBy string, order string) (compare func(left, right WaitingListEntry) int, ok bool) {
+	switch sortBy {
+	case "":
+		return nil, order == "asc" || order == "desc"
+	case "waitingSince":
+		compare = func(left, right WaitingListEntry) int { return left.WaitingSince.Compare(right.WaitingSince) }
+	case "estimatedStart":
+		compare = func(left, right WaitingListEntry) int { return left.EstimatedStart.Compare(right.EstimatedStart) }
+	case "patientId":
+		compare = func(left, right WaitingListEntry) int { return strings.Compare(left.PatientId, right.PatientId) }
+	default:
+		return nil, false
+	}
+
+	switch order {
+	case "asc":
+		return compare, true
+	case "desc":
+		ascending := compare
+		return func(left, right WaitingListEntry) int { return ascending(right, left) }, true
+	default:
+		return nil, false
+	}
+}
+
 // GetWaitingListEntry - Provides details about waiting list entry
 func (this *implAmbulanceWaitingListAPI) GetWaitingListEntry(ctx *gin.Context) {
 	// update ambulance document
 	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
-		_, span := tracer.Start(c.Request.Context(), "GetWaitingListEntry")
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListEntry")
 		defer span.End()
 
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
 		entryId := ctx.Param("entryId")
 
 		if entryId == "" {
@@ -143,7 +944,8 @@ func (this *implAmbulanceWaitingListAPI) GetWaitingListEntry(ctx *gin.Context) {
 		if entryIndx < 0 {
 			return nil, gin.H{
 				"status":  http.StatusNotFound,
-				"message": "Entry not found",
+				"code":    msgEntryNotFound,
+				"message": localize(c, msgEntryNotFound),
 			}, http.StatusNotFound
 		}
 		// return nil ambulance - no need to update it in db
@@ -151,6 +953,387 @@ func (this *implAmbulanceWaitingListAPI) GetWaitingListEntry(ctx *gin.Context) {
 	})
 }
 
+// GetWaitingListEntryPosition - Provides the position of the entry in the queue
+func (this *implAmbulanceWaitingListAPI) GetWaitingListEntryPosition(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListEntryPosition")
+		defer span.End()
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		if ambulance.estimatesStale(clock.Now()) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+
+		entryId := ctx.Param("entryId")
+		entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+			return entryId == waiting.Id
+		})
+		if entryIndx < 0 {
+			return nil, gin.H{
+				"status":  http.StatusNotFound,
+				"code":    msgEntryNotFound,
+				"message": localize(c, msgEntryNotFound),
+			}, http.StatusNotFound
+		}
+
+		entry := ambulance.WaitingList[entryIndx]
+		if entry.effectiveStatus() != statusWaiting {
+			return nil, gin.H{
+				"status":      http.StatusConflict,
+				"message":     "Entry is not waiting anymore",
+				"entryStatus": entry.effectiveStatus(),
+			}, http.StatusConflict
+		}
+
+		return nil, WaitingListEntryPosition{
+			Position:       ambulance.queuePosition(&entry),
+			EstimatedStart: entry.EstimatedStart,
+		}, http.StatusOK
+	})
+}
+
+// GetWaitingListEstimate - Provides the estimated wait of the patient not yet in the waiting list
+func (this *implAmbulanceWaitingListAPI) GetWaitingListEstimate(ctx *gin.Context) {
+	duration := int32(15)
+	if value := ctx.Query("durationMinutes"); value != "" {
+		minutes, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			ctx.JSON(
+				http.StatusBadRequest,
+				gin.H{
+					"status":  "Bad Request",
+					"message": "Query parameter durationMinutes must be an integer",
+					"error":   err.Error(),
+				})
+			return
+		}
+		duration = int32(minutes)
+	}
+	if response, status := validateDuration(duration); response != nil {
+		ctx.JSON(status, response)
+		return
+	}
+	priority := ctx.Query("priority")
+	if priority != "" && !isValidPriority(priority) {
+		response, status := invalidPriorityResponse()
+		ctx.JSON(status, response)
+		return
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListEstimate")
+		defer span.End()
+
+		// the hypothetical entry is reconciled together with the others as if it just arrived,
+		// the ambulance is not stored so the entry never gets into the waiting list
+		hypothetical := WaitingListEntry{
+			Id:                       "@estimate",
+			WaitingSince:             clock.Now(),
+			EstimatedDurationMinutes: duration,
+			Status:                   statusWaiting,
+			Room:                     ctx.Query("room"),
+			Priority:                 priority,
+		}
+		ambulance.WaitingList = append(ambulance.WaitingList, hypothetical)
+		ambulance.reconcileWaitingList(spanctx)
+		entry := reconciledEntry(ambulance, hypothetical.Id)
+
+		// strategies may serve later arrivals first, so the position follows the estimated starts;
+		// the start counts the office hours, the patient arriving to the closed ambulance waits for the opening
+		estimatedStart := entry.EstimatedStart
+		for _, scheduled := range ambulance.schedule(clock.Now()) {
+			if scheduled.Id == entry.Id {
+				estimatedStart = scheduled.EstimatedStart
+			}
+		}
+		return nil, WaitingListEntryPosition{
+			Position:       ambulance.queuePosition(&entry),
+			EstimatedStart: estimatedStart,
+		}, http.StatusOK
+	})
+}
+
+// GetWaitingListPatients - Provides ids of the patients in the waiting list
+func (this *implAmbulanceWaitingListAPI) GetWaitingListPatients(ctx *gin.Context) {
+	includeInactive, _ := strconv.ParseBool(ctx.Query("includeInactive"))
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		_, span := tracer.Start(c.Request.Context(), "GetWaitingListPatients")
+		defer span.End()
+
+		seen := map[string]bool{}
+		result := []string{}
+		for _, entry := range ambulance.WaitingList {
+			if seen[entry.PatientId] || !(includeInactive || entry.isActive()) {
+				continue
+			}
+			seen[entry.PatientId] = true
+			result = append(result, entry.PatientId)
+		}
+		// return nil ambulance - no need to update it in db
+		return nil, result, http.StatusOK
+	})
+}
+
+// GetWaitingListSchedule - Provides the computed schedule of the active entries
+func (this *implAmbulanceWaitingListAPI) GetWaitingListSchedule(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "GetWaitingListSchedule")
+		defer span.End()
+
+		// refresh estimates relative to the current time, the ambulance is not stored
+		now := clock.Now()
+		if ambulance.estimatesStale(now) {
+			ambulance.reconcileWaitingList(spanctx)
+		}
+		return nil, ambulance.schedule(now), http.StatusOK
+	})
+}
+
+// throughput is provided in at most this number of intervals, guards against tiny buckets over long windows
+const maxThroughputBuckets = 1000
+
+// GetWaitingListThroughput - Provides the number of entries completed per time interval
+func (this *implAmbulanceWaitingListAPI) GetWaitingListThroughput(ctx *gin.Context) {
+	window, windowErr := time.ParseDuration(ctx.DefaultQuery("window", "24h"))
+	bucket, bucketErr := time.ParseDuration(ctx.DefaultQuery("bucket", "1h"))
+	if windowErr != nil || bucketErr != nil || bucket <= 0 || window < bucket {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Query parameters window and bucket must be positive durations, e.g. 8h and 30m, the window not shorter than the bucket",
+			})
+		return
+	}
+	// the last interval ends now, the window is extended to the whole number of the intervals
+	count := int((window + bucket - 1) / bucket)
+	if count > maxThroughputBuckets {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": fmt.Sprintf("At most %d buckets can be provided, use longer bucket or shorter window", maxThroughputBuckets),
+			})
+		return
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		_, span := tracer.Start(c.Request.Context(), "GetWaitingListThroughput")
+		defer span.End()
+
+		// empty intervals are provided as well, so that the charts render continuously
+		end := clock.Now().UTC()
+		start := end.Add(-time.Duration(count) * bucket)
+		buckets := make([]ThroughputBucket, count)
+		for i := range buckets {
+			buckets[i].Start = start.Add(time.Duration(i) * bucket)
+			buckets[i].End = buckets[i].Start.Add(bucket)
+			buckets[i].BySource = map[string]int32{}
+		}
+		for _, entry := range ambulance.WaitingList {
+			if entry.effectiveStatus() != statusDone || entry.CompletedAt == nil {
+				continue
+			}
+			if completedAt := *entry.CompletedAt; !completedAt.Before(start) && completedAt.Before(end) {
+				completed := &buckets[int(completedAt.Sub(start)/bucket)]
+				completed.Completed++
+				completed.BySource[entry.arrivalSource()]++
+			}
+		}
+		// return nil ambulance - no need to update it in db
+		return nil, buckets, http.StatusOK
+	})
+}
+
+// TransferWaitingListEntry - Moves the entry to the waiting list of other ambulance
+func (this *implAmbulanceWaitingListAPI) TransferWaitingListEntry(ctx *gin.Context) {
+	var transfer WaitingListEntryTransfer
+	if err := bindJSON(ctx, &transfer); err != nil {
+		ctx.JSON(http.StatusBadRequest, invalidBodyResponse(ctx, http.StatusBadRequest, err))
+		return
+	}
+	// the target is stored directly, its buffered changes are stored first so that the target is read with them.
+	// Flushed before the buffer of the source is acquired by updateAmbulanceFunc, otherwise the opposite transfers
+	// could wait for each other. The target changed later is not overwritten, see storeAmbulance.
+	if isValidId(transfer.ToAmbulanceId) {
+		writes.flush(transfer.ToAmbulanceId)
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "TransferWaitingListEntry")
+		defer span.End()
+
+		if transfer.ToAmbulanceId == "" || transfer.ToAmbulanceId == ambulance.Id {
+			return nil, gin.H{
+				"status":  http.StatusBadRequest,
+				"message": "Target ambulance ID is required and must differ from the source ambulance",
+			}, http.StatusBadRequest
+		}
+
+		if !isValidId(transfer.ToAmbulanceId) {
+			return nil, invalidIdResponse(c, http.StatusBadRequest, "toAmbulanceId", transfer.ToAmbulanceId), http.StatusBadRequest
+		}
+
+		entryId := ctx.Param("entryId")
+		entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+			return entryId == waiting.Id
+		})
+		if entryIndx < 0 {
+			return nil, gin.H{
+				"status":  http.StatusNotFound,
+				"code":    msgEntryNotFound,
+				"message": localize(c, msgEntryNotFound),
+			}, http.StatusNotFound
+		}
+		entry := ambulance.WaitingList[entryIndx]
+
+		// presence and type of the db service was verified by updateAmbulanceFunc
+		value, _ := c.Get("db_service")
+		db := value.(db_service.DbService[Ambulance])
+
+		target, err := db.FindDocument(spanctx, transfer.ToAmbulanceId)
+		switch err {
+		case nil:
+		case db_service.ErrNotFound:
+			return nil, gin.H{
+				"status":  http.StatusNotFound,
+				"message": "Target ambulance not found",
+				"error":   err.Error(),
+			}, http.StatusNotFound
+		default:
+			return nil, gin.H{
+				"status":  http.StatusBadGateway,
+				"message": "Failed to load target ambulance from database",
+				"error":   err.Error(),
+			}, http.StatusBadGateway
+		}
+
+		if slices.ContainsFunc(target.WaitingList, func(waiting WaitingListEntry) bool {
+			return entry.Id == waiting.Id || entry.PatientId == waiting.PatientId
+		}) {
+			return nil, gin.H{
+				"status":  http.StatusConflict,
+				"message": "Patient or entry is already listed in the target waiting list",
+			}, http.StatusConflict
+		}
+
+		if entry.isActive() && !target.hasCapacityFor(1) {
+			return nil, gin.H{
+				"status": http.StatusConflict,
+				"message": fmt.Sprintf(
+					"Target waiting list is full, at most %d active entries are allowed", target.MaxWaitingListSize),
+			}, http.StatusConflict
+		}
+
+		previousTarget := target.clone()
+		target.WaitingList = append(target.WaitingList, entry)
+		target.reconcileWaitingList(spanctx)
+		ambulance.WaitingList = append(ambulance.WaitingList[:entryIndx], ambulance.WaitingList[entryIndx+1:]...)
+		ambulance.reconcileWaitingList(spanctx)
+
+		if isDryRun(c) {
+			// source is not stored by updateAmbulanceFunc either
+			return ambulance, reconciledEntry(target, entry.Id), http.StatusOK
+		}
+
+		registry := patientRegistry(c)
+		if registry != nil {
+			switch err := registerPatient(spanctx, registry, target, &entry); err {
+			case nil:
+			case db_service.ErrConflict:
+				return nil, gin.H{
+					"status":  http.StatusConflict,
+					"message": "Patient or entry is already listed in the target waiting list",
+				}, http.StatusConflict
+			default:
+				return nil, gin.H{
+					"status":  http.StatusBadGateway,
+					"message": "Failed to register patient",
+					"error":   err.Error(),
+				}, http.StatusBadGateway
+			}
+		}
+
+		// the target is stored first, if storing of the source fails the entry is rather listed twice than lost;
+		// the source registration is released once the source is stored
+		target.trackEntryChanges(previousTarget, clock.Now())
+		if err := storeAmbulance(spanctx, db, target.Id, target); err != nil {
+			releaseEntryPatients(c, spanctx, target.Id, []WaitingListEntry{entry})
+			if err == db_service.ErrPreconditionFailed {
+				return nil, changedAmbulanceResponse(c, http.StatusConflict, err), http.StatusConflict
+			}
+			return nil, gin.H{
+				"status":  http.StatusBadGateway,
+				"message": "Failed to update target ambulance in database",
+				"error":   err.Error(),
+			}, http.StatusBadGateway
+		}
+
+		// the change is recorded in the audit of both ambulances
+		recordAudit(c, auditActionTransfer, ambulance.Id, entry.Id)
+		recordAudit(c, auditActionTransfer, target.Id, entry.Id)
+		return ambulance, reconciledEntry(target, entry.Id), http.StatusOK
+	})
+}
+
+// reconciledEntry provides the value of the entry from the reconciled waiting list of the ambulance
+func reconciledEntry(ambulance *Ambulance, entryId string) WaitingListEntry {
+	entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+		return entryId == waiting.Id
+	})
+	return ambulance.WaitingList[entryIndx]
+}
+
+// UpdateWaitingListDurations - Updates estimated durations of entries by their condition
+func (this *implAmbulanceWaitingListAPI) UpdateWaitingListDurations(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "UpdateWaitingListDurations")
+		defer span.End()
+
+		var durations map[string]int32
+		if err := bindJSON(c, &durations); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+
+		for code, minutes := range durations {
+			if response, status := validateDuration(minutes); response != nil {
+				response["error"] = "invalid duration for condition " + code
+				return nil, response, status
+			}
+		}
+
+		updated := false
+		for i := range ambulance.WaitingList {
+			entry := &ambulance.WaitingList[i]
+			if minutes, ok := durations[entry.Condition.Code]; ok && entry.isActive() && entry.Condition.Code != "" {
+				entry.EstimatedDurationMinutes = minutes
+				updated = true
+				recordAudit(c, auditActionUpdate, ambulance.Id, entry.Id)
+			}
+		}
+
+		result := ambulance.WaitingList
+		if result == nil {
+			result = []WaitingListEntry{}
+		}
+		if !updated {
+			// nothing matched - no need to update the ambulance in db
+			return nil, result, http.StatusOK
+		}
+
+		ambulance.reconcileWaitingList(spanctx)
+		return ambulance, ambulance.WaitingList, http.StatusOK
+	})
+}
+
+// waitingListEntryUpdate is the body of the entry update, the fields not provided are kept unchanged
+type waitingListEntryUpdate struct {
+	WaitingListEntry
+	// provided note replaces the note of the entry, even if it is empty
+	Note *string `json:"note,omitempty"`
+}
+
 // UpdateWaitingListEntry - Updates specific entry
 func (this *implAmbulanceWaitingListAPI) UpdateWaitingListEntry(ctx *gin.Context) {
 	// update ambulance document
@@ -168,14 +1351,10 @@ func (this *implAmbulanceWaitingListAPI) UpdateWaitingListEntry(ctx *gin.Context
 		)
 		c.Request = c.Request.WithContext(spanctx)
 		defer span.End()
-		var entry WaitingListEntry
+		var entry waitingListEntryUpdate
 
-		if err := c.ShouldBindJSON(&entry); err != nil {
-			return nil, gin.H{
-				"status":  http.StatusBadRequest,
-				"message": "Invalid request body",
-				"error":   err.Error(),
-			}, http.StatusBadRequest
+		if err := bindJSON(c, &entry); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
 		}
 
 		entryId := ctx.Param("entryId")
@@ -194,10 +1373,24 @@ func (this *implAmbulanceWaitingListAPI) UpdateWaitingListEntry(ctx *gin.Context
 		if entryIndx < 0 {
 			return nil, gin.H{
 				"status":  http.StatusNotFound,
-				"message": "Entry not found",
+				"code":    msgEntryNotFound,
+				"message": localize(c, msgEntryNotFound),
 			}, http.StatusNotFound
 		}
 
+		// the changed patient or id must not collide with other entry, the patient registry
+		// is updated by updateAmbulanceFunc once the change is known to be valid
+		if slices.ContainsFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+			return waiting.Id != entryId &&
+				((entry.Id != "" && entry.Id == waiting.Id) || (entry.PatientId != "" && entry.PatientId == waiting.PatientId))
+		}) {
+			return nil, gin.H{
+				"status":  http.StatusConflict,
+				"code":    msgEntryConflict,
+				"message": localize(c, msgEntryConflict),
+			}, http.StatusConflict
+		}
+
 		if entry.PatientId != "" {
 			ambulance.WaitingList[entryIndx].PatientId = entry.PatientId
 		}
@@ -206,15 +1399,130 @@ func (this *implAmbulanceWaitingListAPI) UpdateWaitingListEntry(ctx *gin.Context
 			ambulance.WaitingList[entryIndx].Id = entry.Id
 		}
 
-		if entry.WaitingSince.After(time.Time{}) {
-			ambulance.WaitingList[entryIndx].WaitingSince = entry.WaitingSince
+		// unchanged arrival is accepted even if it became too old, e.g. the entry read and written back
+		if waitingSince := entry.WaitingSince; !waitingSince.IsZero() &&
+			!waitingSince.Equal(ambulance.WaitingList[entryIndx].WaitingSince) {
+			now := clock.Now()
+			if response, status := validateWaitingSince(ambulance, waitingSince, now); response != nil {
+				return nil, response, status
+			}
+			if waitingSince.After(now) && !ambulance.AllowFutureWaitingSince {
+				waitingSince = now
+			}
+			ambulance.WaitingList[entryIndx].WaitingSince = waitingSince
 		}
 
-		if entry.EstimatedDurationMinutes > 0 {
+		if entry.EstimatedDurationMinutes != 0 {
+			if response, status := validateDuration(entry.EstimatedDurationMinutes); response != nil {
+				return nil, response, status
+			}
 			ambulance.WaitingList[entryIndx].EstimatedDurationMinutes = entry.EstimatedDurationMinutes
 		}
 
+		if entry.Note != nil {
+			// the explicit empty note clears it
+			note := sanitizeNote(*entry.Note)
+			if utf8.RuneCountInString(note) > maxNoteLength {
+				response, status := noteTooLongResponse()
+				return nil, response, status
+			}
+			ambulance.WaitingList[entryIndx].Note = note
+		}
+
+		if entry.Source != "" {
+			if !isValidSource(entry.Source) {
+				response, status := invalidSourceResponse()
+				return nil, response, status
+			}
+			ambulance.WaitingList[entryIndx].Source = entry.Source
+		}
+
+		if entry.Priority != "" {
+			if !isValidPriority(entry.Priority) {
+				response, status := invalidPriorityResponse()
+				return nil, response, status
+			}
+			ambulance.WaitingList[entryIndx].Priority = entry.Priority
+		}
+
+		if entry.Status != "" {
+			if !isValidStatus(entry.Status) {
+				return nil, gin.H{
+					"status":  http.StatusBadRequest,
+					"message": "Invalid entry status",
+				}, http.StatusBadRequest
+			}
+			// the same transitions as for the status change of multiple entries, e.g. the done entry stays done
+			if current := &ambulance.WaitingList[entryIndx]; !current.canTransitionTo(entry.Status) {
+				return nil, gin.H{
+					"status":      http.StatusConflict,
+					"message":     fmt.Sprintf("Cannot change status from %v to %v", current.effectiveStatus(), entry.Status),
+					"entryStatus": current.effectiveStatus(),
+				}, http.StatusConflict
+			}
+			ambulance.WaitingList[entryIndx].changeStatus(entry.Status, clock.Now())
+		}
+
+		recordAudit(c, auditActionUpdate, ambulance.Id, ambulance.WaitingList[entryIndx].Id)
 		ambulance.reconcileWaitingList(spanctx)
 		return ambulance, ambulance.WaitingList[entryIndx], http.StatusOK
 	})
 }
+
+// UpdateWaitingListStatuses - Changes the status of multiple entries at once
+func (this *implAmbulanceWaitingListAPI) UpdateWaitingListStatuses(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "UpdateWaitingListStatuses")
+		defer span.End()
+
+		var change WaitingListStatusChange
+		if err := bindJSON(c, &change); err != nil {
+			return nil, invalidBodyResponse(c, http.StatusBadRequest, err), http.StatusBadRequest
+		}
+
+		if len(change.EntryIds) == 0 || !isValidStatus(change.Status) {
+			return nil, gin.H{
+				"status":  http.StatusBadRequest,
+				"message": "At least one entry ID and valid status are required",
+			}, http.StatusBadRequest
+		}
+
+		// all transitions are verified before any is applied
+		invalid := []gin.H{}
+		entries := make([]*WaitingListEntry, 0, len(change.EntryIds))
+		for _, entryId := range change.EntryIds {
+			entryIndx := slices.IndexFunc(ambulance.WaitingList, func(waiting WaitingListEntry) bool {
+				return entryId == waiting.Id
+			})
+			switch {
+			case entryIndx < 0:
+				invalid = append(invalid, gin.H{"entryId": entryId, "error": "entry not found"})
+			case !ambulance.WaitingList[entryIndx].canTransitionTo(change.Status):
+				invalid = append(invalid, gin.H{
+					"entryId": entryId,
+					"error": fmt.Sprintf("cannot change status from %v to %v",
+						ambulance.WaitingList[entryIndx].effectiveStatus(), change.Status),
+				})
+			default:
+				entries = append(entries, &ambulance.WaitingList[entryIndx])
+			}
+		}
+
+		if len(invalid) > 0 {
+			return nil, gin.H{
+				"status":         http.StatusUnprocessableEntity,
+				"message":        "Some entries cannot change their status, no entry was changed",
+				"invalidEntries": invalid,
+			}, http.StatusUnprocessableEntity
+		}
+
+		now := clock.Now()
+		for _, entry := range entries {
+			entry.changeStatus(change.Status, now)
+			recordAudit(c, auditActionUpdate, ambulance.Id, entry.Id)
+		}
+
+		ambulance.reconcileWaitingList(spanctx)
+		return ambulance, ambulance.WaitingList, http.StatusOK
+	})
+}
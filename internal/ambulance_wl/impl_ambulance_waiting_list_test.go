@@ -2,15 +2,26 @@ package ambulance_wl
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/milung/ambulance-webapi/internal/db_service"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slices"
 )
 
 type AmbulanceWlSuite struct {
@@ -36,11 +47,36 @@ func (this *DbServiceMock[DocType]) FindDocument(ctx context.Context, id string)
 	return args.Get(0).(*DocType), args.Error(1)
 }
 
+func (this *DbServiceMock[DocType]) FindDocuments(ctx context.Context, filter bson.M, opts ...db_service.QueryOption) ([]*DocType, error) {
+	args := this.Called(ctx, filter, opts)
+	return args.Get(0).([]*DocType), args.Error(1)
+}
+
+func (this *DbServiceMock[DocType]) ListDocumentsAfter(ctx context.Context, afterId string, limit int64) ([]*DocType, string, error) {
+	args := this.Called(ctx, afterId, limit)
+	return args.Get(0).([]*DocType), args.String(1), args.Error(2)
+}
+
 func (this *DbServiceMock[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
 	args := this.Called(ctx, id, document)
 	return args.Error(0)
 }
 
+func (this *DbServiceMock[DocType]) UpdateDocumentIf(ctx context.Context, id string, document *DocType, condition bson.M) error {
+	args := this.Called(ctx, id, document, condition)
+	return args.Error(0)
+}
+
+func (this *DbServiceMock[DocType]) UpdateFields(ctx context.Context, id string, fields bson.M, increments bson.M) error {
+	args := this.Called(ctx, id, fields, increments)
+	return args.Error(0)
+}
+
+func (this *DbServiceMock[DocType]) UpsertDocument(ctx context.Context, id string, document *DocType) error {
+	args := this.Called(ctx, id, document)
+	return args.Error(0)
+}
+
 func (this *DbServiceMock[DocType]) DeleteDocument(ctx context.Context, id string) error {
 	args := this.Called(ctx, id)
 	return args.Error(0)
@@ -74,10 +110,32 @@ func (suite *AmbulanceWlSuite) SetupTest() {
 			nil,
 		)
 }
+
+// givenAmbulance replaces the ambulance provided by the mocked db service
+func (suite *AmbulanceWlSuite) givenAmbulance(ambulance *Ambulance) {
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(ambulance, nil)
+}
+
+// newRequestContext creates test gin context for the request on the test-ambulance
+func (suite *AmbulanceWlSuite) newRequestContext(method string, url string, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Params = []gin.Param{
+		{Key: "ambulanceId", Value: "test-ambulance"},
+	}
+	ctx.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+	return ctx, recorder
+}
+
 func (suite *AmbulanceWlSuite) Test_UpdateWl_DbServiceUpdateCalled() {
 	// ARRANGE
 	suite.dbServiceMock.
-		On("UpdateDocument", mock.Anything, mock.Anything, mock.Anything).
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
 
 	json := `{
@@ -102,6 +160,2943 @@ func (suite *AmbulanceWlSuite) Test_UpdateWl_DbServiceUpdateCalled() {
 	sut.UpdateWaitingListEntry(ctx)
 
 	// ASSERT
-	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocument", mock.Anything, "test-ambulance", mock.Anything)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything)
+
+}
+
+func (suite *AmbulanceWlSuite) Test_GetUpcoming_ReturnsWaitingEntriesWithinWindow() {
+	// ARRANGE
+	now := time.Now()
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "second", PatientId: "p2", WaitingSince: now.Add(-9 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "done", PatientId: "p3", WaitingSince: now.Add(-8 * time.Minute), EstimatedDurationMinutes: 20, Status: statusDone},
+			{Id: "third", PatientId: "p4", WaitingSince: now.Add(-7 * time.Minute), EstimatedDurationMinutes: 20},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/upcoming?withinMinutes=30", "")
+
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetUpcomingWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.Id)
+	}
+	suite.Equal([]string{"first", "second"}, ids)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetUpcoming_MissingOrZeroWindowIsBadRequest() {
+	for _, url := range []string{
+		"/waiting-list/test-ambulance/upcoming",
+		"/waiting-list/test-ambulance/upcoming?withinMinutes=0",
+	} {
+		// ARRANGE
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.GetUpcomingWaitingListEntries(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+// createEntry submits the entry into the empty test-ambulance and returns the created entry
+func (suite *AmbulanceWlSuite) createEntry(body string) WaitingListEntry {
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", body)
+
+	sut := implAmbulanceWaitingListAPI{}
+	sut.CreateWaitingListEntry(ctx)
+
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	return entry
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_DeterministicIdsAreReproducible() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.DeterministicIds = true
+	body := `{"patientId": "test-patient", "waitingSince": "2038-12-24T10:05:12Z"}`
+	retryBody := `{"patientId": "test-patient", "waitingSince": "2038-12-24T10:05:48Z"}`
+	otherBody := `{"patientId": "other-patient", "waitingSince": "2038-12-24T10:05:12Z"}`
+
+	// ACT
+	first := suite.createEntry(body)
+	retry := suite.createEntry(retryBody)
+	other := suite.createEntry(otherBody)
+
+	// ASSERT
+	suite.NotEmpty(first.Id)
+	suite.Equal(first.Id, retry.Id)
+	suite.NotEqual(first.Id, other.Id)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_RandomIdsByDefault() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.DeterministicIds = false
+	body := `{"patientId": "test-patient", "waitingSince": "2038-12-24T10:05:12Z"}`
+
+	// ACT
+	first := suite.createEntry(body)
+	second := suite.createEntry(body)
+
+	// ASSERT
+	suite.NotEqual(first.Id, second.Id)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MissingAmbulance_NotFoundByDefault() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return((*Ambulance)(nil), db_service.ErrNotFound)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusNotFound, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MissingAmbulance_AutoCreated() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.AutoCreateAmbulance = true
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return((*Ambulance)(nil), db_service.ErrNotFound)
+	suite.dbServiceMock.On("CreateDocument", mock.Anything, "test-ambulance", mock.Anything).Return(nil)
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.dbServiceMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool { return ambulance.Id == "test-ambulance" }))
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool {
+			return len(ambulance.WaitingList) == 1 && ambulance.WaitingList[0].PatientId == "test-patient"
+		}), mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MissingAmbulance_ConcurrentlyCreated() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.AutoCreateAmbulance = true
+	// ARRANGE - other request inserts the ambulance between the lookup and the insert of this request
+	created := &Ambulance{Id: "test-ambulance", Name: "Concurrent", WaitingList: []WaitingListEntry{}}
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return((*Ambulance)(nil), db_service.ErrNotFound).Once()
+	suite.dbServiceMock.On("CreateDocument", mock.Anything, "test-ambulance", mock.Anything).Return(db_service.ErrConflict)
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return(created, nil)
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", created, mock.Anything).Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Len(created.WaitingList, 1)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance", created, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_CreatedWithLocation() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+		`{"id": "entry-1", "patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Equal("/waiting-list/test-ambulance/entries/entry-1", recorder.Header().Get("Location"))
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal("entry-1", entry.Id)
+	suite.Equal("test-patient", entry.PatientId)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_NotStored_NoLocation() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(fmt.Errorf("%w: connection refused", db_service.ErrUnavailable))
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+		`{"patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusServiceUnavailable, recorder.Code)
+	suite.Empty(recorder.Header().Get("Location"))
+}
+
+func (suite *AmbulanceWlSuite) checkin(body string) *httptest.ResponseRecorder {
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/checkin", body)
+	sut := implAmbulanceWaitingListAPI{}
+	sut.CheckinWaitingListEntry(ctx)
+	return recorder
+}
+
+func (suite *AmbulanceWlSuite) givenCheckinLimiter() {
+	previous := checkins
+	checkins = newCheckinLimiter()
+	suite.T().Cleanup(func() { checkins = previous })
+}
+
+func (suite *AmbulanceWlSuite) Test_Checkin_CreatesLockedDownEntry() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenCheckinLimiter()
+	ambulance := &Ambulance{
+		Id:                   "test-ambulance",
+		PredefinedConditions: []Condition{{Value: "Teploty", Code: "subfebrilia", TypicalDurationMinutes: 20}},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	// ACT - fields other than the patient and the condition are not accepted from the kiosk
+	recorder := suite.checkin(`{"patientId": "test-patient", "conditionCode": "subfebrilia",
+		"status": "done", "source": "walkin", "waitingSince": "2038-12-24T08:00:00Z"}`)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal("/waiting-list/test-ambulance/entries/"+entry.Id, recorder.Header().Get("Location"))
+	suite.Equal("test-patient", entry.PatientId)
+	suite.Equal(statusWaiting, entry.Status)
+	suite.Equal(sourceOnline, entry.Source)
+	suite.True(now.Equal(entry.WaitingSince))
+	suite.Equal("subfebrilia", entry.Condition.Code)
+	suite.Equal(int32(20), entry.EstimatedDurationMinutes)
+	suite.Len(ambulance.WaitingList, 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_Checkin_DoubleCheckinRejected() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenCheckinLimiter()
+	ambulance := &Ambulance{Id: "test-ambulance"}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	body := `{"patientId": "test-patient"}`
+	suite.Equal(http.StatusCreated, suite.checkin(body).Code)
+
+	// ACT
+	throttled := suite.checkin(body)
+	suite.givenClock(now.Add(2 * time.Minute))
+	queued := suite.checkin(body)
+
+	// ASSERT
+	suite.Equal(http.StatusTooManyRequests, throttled.Code)
+	suite.Equal("60", throttled.Header().Get("Retry-After"))
+	suite.Equal(http.StatusConflict, queued.Code)
+	suite.Contains(queued.Body.String(), msgAlreadyCheckedIn)
+	suite.Len(ambulance.WaitingList, 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_Checkin_RejectedOrPreviewedNotThrottled() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenCheckinLimiter()
+	// each request reads the stored ambulance, the previewed entry is not stored
+	givenStoredAmbulance := func() {
+		suite.givenAmbulance(&Ambulance{
+			Id:                   "test-ambulance",
+			PredefinedConditions: []Condition{{Value: "Teploty", Code: "subfebrilia", TypicalDurationMinutes: 20}},
+		})
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+	}
+
+	// ACT
+	givenStoredAmbulance()
+	unknownCondition := suite.checkin(`{"patientId": "test-patient", "conditionCode": "unknown"}`)
+	givenStoredAmbulance()
+	previewCtx, preview := suite.newRequestContext("POST", "/waiting-list/test-ambulance/checkin?dryRun=true",
+		`{"patientId": "test-patient", "conditionCode": "subfebrilia"}`)
+	sut := implAmbulanceWaitingListAPI{}
+	sut.CheckinWaitingListEntry(previewCtx)
+	givenStoredAmbulance()
+	checkedIn := suite.checkin(`{"patientId": "test-patient", "conditionCode": "subfebrilia"}`)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, unknownCondition.Code)
+	suite.Equal(http.StatusOK, preview.Code)
+	suite.Equal(http.StatusCreated, checkedIn.Code)
+}
+
+func (suite *AmbulanceWlSuite) Test_CheckinLimiter_ForgetsExpiredCheckins() {
+	// ARRANGE
+	sut := newCheckinLimiter()
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	sut.record("test-ambulance", "first-patient", now, time.Minute)
+
+	// ACT
+	sut.record("test-ambulance", "second-patient", now.Add(30*time.Second), time.Minute)
+	keptWithinInterval := len(sut.checkins)
+	sut.record("test-ambulance", "third-patient", now.Add(2*time.Minute), time.Minute)
+
+	// ASSERT
+	suite.Equal(2, keptWithinInterval)
+	suite.Len(sut.checkins, 1)
+	_, allowed := sut.allow("test-ambulance", "third-patient", now.Add(2*time.Minute), time.Minute)
+	suite.False(allowed)
+}
+
+func (suite *AmbulanceWlSuite) Test_MalformedIds_BadRequestWithoutDatabase() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	sut := implAmbulanceWaitingListAPI{}
+	cases := []struct {
+		ambulanceId string
+		entryId     string
+		field       string
+	}{
+		{"test ambulance", "test-entry", "ambulanceId"},
+		{"test-ambulance", "../entries", "entryId"},
+		{"test-ambulance", "entry;drop", "entryId"},
+		{"test-ambulance", strings.Repeat("e", maxIdLength+1), "entryId"},
+	}
+
+	for _, c := range cases {
+		ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/test-entry", "")
+		ctx.Params = gin.Params{{Key: "ambulanceId", Value: c.ambulanceId}, {Key: "entryId", Value: c.entryId}}
+
+		// ACT
+		sut.GetWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, c)
+		response := map[string]interface{}{}
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+		suite.Equal(msgInvalidId, response["code"], c)
+		suite.Contains(response["error"], c.field, c)
+	}
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "FindDocument", mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MalformedId_BadRequest() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+		`{"id": "entry 1", "patientId": "test-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), msgInvalidId)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_GeneratedIdCollision_AnotherIdGenerated() {
+	// ARRANGE
+	collidingBytes := strings.Repeat("c", 16)
+	colliding, err := uuid.NewRandomFromReader(strings.NewReader(collidingBytes))
+	suite.Require().NoError(err)
+	// the first two generated ids collide with the existing entry
+	uuid.SetRand(io.MultiReader(strings.NewReader(collidingBytes+collidingBytes), rand.Reader))
+	defer uuid.SetRand(nil)
+	suite.givenAmbulance(&Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: colliding.String(), PatientId: "existing-patient", WaitingSince: time.Now()}},
+	})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "new-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	var entry WaitingListEntry
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.NotEqual(colliding.String(), entry.Id)
+	suite.True(isValidId(entry.Id))
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_ClientIdCollision_Conflict() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "entry-1", PatientId: "existing-patient", WaitingSince: time.Now()}},
+	})
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+		`{"id": "entry-1", "patientId": "new-patient"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), msgEntryConflict)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_IsValidId_AcceptsGeneratedIds() {
+	suite.True(isValidId(newId()))
+	suite.True(isValidId(newUuidV7(time.Now())))
+	suite.True(isValidId(newUlid(time.Now())))
+	suite.True(isValidId("gp-warenova"))
+	suite.False(isValidId(""))
+	suite.False(isValidId("-leading-dash"))
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_IdStrategyFormats() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.DeterministicIds = false
+	formats := map[string]*regexp.Regexp{
+		idStrategyUuidV4: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		idStrategyUuidV7: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		idStrategyUlid:   regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`),
+	}
+
+	for strategy, format := range formats {
+		// ARRANGE
+		config.IdStrategy = strategy
+
+		// ACT
+		entry := suite.createEntry(`{"patientId": "test-patient"}`)
+
+		// ASSERT
+		suite.Regexp(format, entry.Id, strategy)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_NewId_TimeOrderedStrategiesSortByCreation() {
+	earlier := time.Date(2038, 12, 24, 10, 5, 0, 0, time.UTC)
+	later := earlier.Add(time.Millisecond)
+
+	suite.Less(newUuidV7(earlier), newUuidV7(later))
+	suite.Less(newUlid(earlier), newUlid(later))
+	suite.True(strings.HasPrefix(newUuidV7(earlier), "01fad343-fce0-7"))
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntry_NotFoundMessageIsLocalized() {
+	cases := map[string]string{
+		"sk-SK,sk;q=0.9,en;q=0.8": "Záznam nebol nájdený",
+		"en-US":                   "Entry not found",
+		"de":                      "Entry not found",
+		"":                        "Entry not found",
+	}
+	for acceptLanguage, expectedMessage := range cases {
+		// ARRANGE
+		ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/missing", "")
+		ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "missing"})
+		ctx.Request.Header.Set("Accept-Language", acceptLanguage)
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		LanguageMiddleware()(ctx)
+		sut.GetWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusNotFound, recorder.Code, acceptLanguage)
+		var response map[string]interface{}
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+		suite.Equal(msgEntryNotFound, response["code"], acceptLanguage)
+		suite.Equal(expectedMessage, response["message"], acceptLanguage)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateDurations_UpdatesMatchingEntries() {
+	// ARRANGE
+	now := time.Now()
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 10, Condition: Condition{Code: "blood-test"}},
+			{Id: "second", PatientId: "p2", WaitingSince: now.Add(time.Minute), EstimatedDurationMinutes: 10, Condition: Condition{Code: "followup"}},
+			{Id: "third", PatientId: "p3", WaitingSince: now.Add(2 * time.Minute), EstimatedDurationMinutes: 10, Condition: Condition{Code: "blood-test"}},
+			{Id: "done", PatientId: "p4", WaitingSince: now.Add(-time.Hour), EstimatedDurationMinutes: 10, Condition: Condition{Code: "blood-test"}, Status: statusDone},
+		},
+	})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("PATCH", "/waiting-list/test-ambulance/durations", `{"blood-test": 25}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListDurations(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	durations := map[string]int32{}
+	for _, entry := range entries {
+		durations[entry.Id] = entry.EstimatedDurationMinutes
+	}
+	suite.Equal(map[string]int32{"first": 25, "second": 10, "third": 25, "done": 10}, durations)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateDurations_NoMatchIsNoOp() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("PATCH", "/waiting-list/test-ambulance/durations", `{"blood-test": 25}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListDurations(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	suite.Len(entries, 1)
+	suite.Equal(int32(101), entries[0].EstimatedDurationMinutes)
+}
+
+func (suite *AmbulanceWlSuite) Test_DryRun_CreateReturnsEntryWithoutStoring() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries?dryRun=true", `{
+		"patientId": "new-patient",
+		"estimatedDurationMinutes": 20
+	}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("true", recorder.Header().Get(dryRunHeader))
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal("new-patient", entry.PatientId)
+	suite.NotEmpty(entry.Id)
+	// estimated after the existing entry of 101 minutes
+	suite.True(entry.EstimatedStart.After(time.Now().Add(100 * time.Minute)))
+}
+
+func (suite *AmbulanceWlSuite) Test_DryRun_UpdateAndDeleteDoNotStore() {
+	// ARRANGE
+	stored := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "test-entry", PatientId: "test-patient", WaitingSince: time.Now(), EstimatedDurationMinutes: 101},
+		},
+	}
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	suite.givenAmbulance(stored)
+	updateCtx, updateRecorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry?dryRun=true", `{"estimatedDurationMinutes": 42}`)
+	updateCtx.AddParam("entryId", "test-entry")
+	sut.UpdateWaitingListEntry(updateCtx)
+
+	suite.givenAmbulance(stored)
+	deleteCtx, deleteRecorder := suite.newRequestContext(
+		"DELETE", "/waiting-list/test-ambulance/entries/test-entry?dryRun=true", "")
+	deleteCtx.AddParam("entryId", "test-entry")
+	sut.DeleteWaitingListEntry(deleteCtx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, updateRecorder.Code)
+	suite.Equal("true", updateRecorder.Header().Get(dryRunHeader))
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(updateRecorder.Body.Bytes(), &entry))
+	suite.Equal(int32(42), entry.EstimatedDurationMinutes)
+
+	suite.Equal(http.StatusNoContent, deleteRecorder.Code)
+	suite.Equal("true", deleteRecorder.Header().Get(dryRunHeader))
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MaxWaitingListSizeCountsActiveEntriesOnly() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id:                 "test-ambulance",
+		MaxWaitingListSize: 2,
+		WaitingList: []WaitingListEntry{
+			{Id: "waiting", PatientId: "p1", WaitingSince: time.Now(), EstimatedDurationMinutes: 10},
+			{Id: "done", PatientId: "p2", WaitingSince: time.Now(), EstimatedDurationMinutes: 10, Status: statusDone},
+		},
+	})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
 
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p3"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_OverMaxWaitingListSizeConflicts() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id:                 "test-ambulance",
+		MaxWaitingListSize: 1,
+		WaitingList: []WaitingListEntry{
+			{Id: "waiting", PatientId: "p1", WaitingSince: time.Now(), EstimatedDurationMinutes: 10},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p2"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), "at most 1 active entries")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetPatients_DistinctActivePatientsOnly() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1"},
+			{Id: "second", PatientId: "p2", Status: statusInExamination},
+			{Id: "third", PatientId: "p1", Status: statusDone},
+			{Id: "fourth", PatientId: "p3", Status: statusNoShow},
+			{Id: "fifth", PatientId: "p2"},
+		},
+	})
+	sut := implAmbulanceWaitingListAPI{}
+
+	for url, expected := range map[string][]string{
+		"/waiting-list/test-ambulance/patients":                      {"p1", "p2"},
+		"/waiting-list/test-ambulance/patients?includeInactive=true": {"p1", "p2", "p3"},
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+
+		// ACT
+		sut.GetWaitingListPatients(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code)
+		var patients []string
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &patients))
+		suite.Equal(expected, patients, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetPatients_EmptyListIsEmptyArray() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/patients", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListPatients(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq("[]", recorder.Body.String())
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_RoomsAreScheduledIndependently() {
+	// ARRANGE
+	now := time.Now()
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "a1", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 30, Room: "a"},
+			{Id: "b1", PatientId: "p2", WaitingSince: now.Add(time.Minute), EstimatedDurationMinutes: 60, Room: "b"},
+			{Id: "a2", PatientId: "p3", WaitingSince: now.Add(2 * time.Minute), EstimatedDurationMinutes: 10, Room: "a"},
+			{Id: "b2", PatientId: "p4", WaitingSince: now.Add(3 * time.Minute), EstimatedDurationMinutes: 10, Room: "b"},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	starts := map[string]time.Time{}
+	for _, entry := range ambulance.WaitingList {
+		starts[entry.Id] = entry.EstimatedStart
+	}
+	// first entries of both rooms start immediately
+	suite.WithinDuration(now, starts["a1"], time.Second)
+	suite.WithinDuration(now.Add(time.Minute), starts["b1"], time.Second)
+	// followers wait only for the entries of their own room
+	suite.Equal(starts["a1"].Add(30*time.Minute), starts["a2"])
+	suite.Equal(starts["b1"].Add(60*time.Minute), starts["b2"])
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_FilteredByRoom() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "default", PatientId: "p1"},
+			{Id: "a1", PatientId: "p2", Room: "a"},
+			{Id: "b1", PatientId: "p3", Room: "b"},
+		},
+	})
+	sut := implAmbulanceWaitingListAPI{}
+
+	for url, expected := range map[string][]string{
+		"/waiting-list/test-ambulance/entries":        {"a1", "b1", "default"},
+		"/waiting-list/test-ambulance/entries?room=a": {"a1"},
+		"/waiting-list/test-ambulance/entries?room=":  {"default"},
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+
+		// ACT
+		sut.GetWaitingListEntries(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code)
+		var entries []WaitingListEntry
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+		ids := []string{}
+		for _, entry := range entries {
+			ids = append(ids, entry.Id)
+		}
+		suite.Equal(expected, ids, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_PatientRegisteredByOtherInstanceConflicts() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("CreateDocument", mock.Anything, "test-ambulance/p2", mock.Anything).
+		Return(db_service.ErrConflict)
+	registry.
+		On("FindDocument", mock.Anything, "test-ambulance/p2").
+		Return(&PatientRegistration{EntryId: "concurrent-entry", RegisteredAt: time.Now()}, nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p2"}`)
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_OrphanedRegistrationTakenOver() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrConflict)
+	registry.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(&PatientRegistration{EntryId: "lost-entry", RegisteredAt: time.Now().Add(-time.Hour)}, nil)
+	registry.
+		On("UpdateDocument", mock.Anything, "test-ambulance/p2", mock.Anything).
+		Return(nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p2"}`)
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	registry.AssertCalled(suite.T(), "UpdateDocument", mock.Anything, "test-ambulance/p2", mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_StoreFailed_ReleasesPatient() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("CreateDocument", mock.Anything, "test-ambulance/p2", mock.Anything).
+		Return(nil)
+	registry.
+		On("DeleteDocument", mock.Anything, "test-ambulance/p2").
+		Return(nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrPreconditionFailed)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p2"}`)
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	registry.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/p2")
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_PatientChanged_RegistrationMoved() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("CreateDocument", mock.Anything, "test-ambulance/other-patient", mock.Anything).
+		Return(nil)
+	registry.
+		On("DeleteDocument", mock.Anything, "test-ambulance/test-patient").
+		Return(nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"patientId": "other-patient"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	registry.AssertCalled(suite.T(), "CreateDocument", mock.Anything, "test-ambulance/other-patient", mock.Anything)
+	registry.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/test-patient")
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_PatientChanged_StoreFailed_NewPatientReleased() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("CreateDocument", mock.Anything, "test-ambulance/other-patient", mock.Anything).
+		Return(nil)
+	registry.
+		On("DeleteDocument", mock.Anything, "test-ambulance/other-patient").
+		Return(nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("connection refused"))
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"patientId": "other-patient"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadGateway, recorder.Code)
+	registry.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/other-patient")
+	// the patient of the stored entry stays registered
+	registry.AssertNotCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/test-patient")
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_IdChanged_RegistrationReassigned() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("UpsertDocument", mock.Anything, "test-ambulance/test-patient", mock.Anything).
+		Return(nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"id": "renamed-entry"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	registry.AssertCalled(suite.T(), "UpsertDocument", mock.Anything, "test-ambulance/test-patient",
+		mock.MatchedBy(func(registration *PatientRegistration) bool { return registration.EntryId == "renamed-entry" }))
+	registry.AssertNotCalled(suite.T(), "DeleteDocument", mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_DuplicateIdOrPatient_Conflict() {
+	for _, body := range []string{`{"id": "second-entry"}`, `{"patientId": "second-patient"}`} {
+		// ARRANGE
+		suite.givenAmbulance(&Ambulance{
+			Id: "test-ambulance",
+			WaitingList: []WaitingListEntry{
+				{Id: "test-entry", PatientId: "test-patient"},
+				{Id: "second-entry", PatientId: "second-patient"},
+			},
+		})
+		ctx, recorder := suite.newRequestContext("PUT", "/waiting-list/test-ambulance/entries/test-entry", body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.UpdateWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusConflict, recorder.Code, body)
+		suite.Contains(recorder.Body.String(), msgEntryConflict)
+		suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func (suite *AmbulanceWlSuite) givenEntries(count int) {
+	ambulance := &Ambulance{Id: "test-ambulance"}
+	for i := 0; i < count; i++ {
+		ambulance.WaitingList = append(ambulance.WaitingList, WaitingListEntry{
+			Id:        fmt.Sprintf("entry-%d", i),
+			PatientId: fmt.Sprintf("patient-%d", i),
+		})
+	}
+	suite.givenAmbulance(ambulance)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntriesByIds_RequestOrderAndNotFound() {
+	// ARRANGE
+	suite.givenEntries(4)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/batch-get",
+		`{"entryIds": ["entry-3", "missing-1", "entry-0", "entry-3", "missing-2"]}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntriesByIds(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var result WaitingListEntriesByIds
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &result))
+	suite.Require().Len(result.Entries, 2)
+	suite.Equal("entry-3", result.Entries[0].Id)
+	suite.Equal("entry-0", result.Entries[1].Id)
+	suite.Equal([]string{"missing-1", "missing-2"}, result.NotFound)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntriesByIds_NoIds_BadRequest() {
+	// ARRANGE
+	suite.givenEntries(1)
+	sut := implAmbulanceWaitingListAPI{}
+
+	for _, body := range []string{`{"entryIds": []}`, `{}`, `["entry-0"]`} {
+		ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/batch-get", body)
+
+		// ACT
+		sut.GetWaitingListEntriesByIds(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, body)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_Enveloped() {
+	// ARRANGE
+	suite.givenEntries(5)
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries?envelope=true&offset=1&limit=2", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var page WaitingListEntriesPage
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &page))
+	suite.Equal(int32(5), page.Total)
+	suite.Equal(int32(2), page.Limit)
+	suite.Equal(int32(1), page.Offset)
+	suite.Len(page.Items, 2)
+	suite.Equal("entry-1", page.Items[0].Id)
+	suite.Equal("entry-2", page.Items[1].Id)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_BareArrayByDefault() {
+	// ARRANGE
+	suite.givenEntries(3)
+	sut := implAmbulanceWaitingListAPI{}
+
+	for url, expectedLength := range map[string]int{
+		"/waiting-list/test-ambulance/entries":                   3,
+		"/waiting-list/test-ambulance/entries?offset=2":          1,
+		"/waiting-list/test-ambulance/entries?offset=5&limit=10": 0,
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+
+		// ACT
+		sut.GetWaitingListEntries(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code, url)
+		var entries []WaitingListEntry
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries), url)
+		suite.Len(entries, expectedLength, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_InvalidPagingIsBadRequest() {
+	sut := implAmbulanceWaitingListAPI{}
+	for _, url := range []string{
+		"/waiting-list/test-ambulance/entries?offset=-1",
+		"/waiting-list/test-ambulance/entries?limit=abc",
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+		sut.GetWaitingListEntries(ctx)
+		suite.Equal(http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_UnchangedListNotModified() {
+	// ARRANGE
+	// the estimates of the entries are stale, they are refreshed at the same time by both reads
+	suite.givenClock(time.Now())
+	suite.givenEntries(2)
+	sut := implAmbulanceWaitingListAPI{}
+	firstCtx, firstRecorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut.GetWaitingListEntries(firstCtx)
+	etag := firstRecorder.Header().Get("ETag")
+
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	ctx.Request.Header.Set("If-None-Match", etag)
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.NotEmpty(etag)
+	suite.Equal(http.StatusNotModified, recorder.Code)
+	suite.Empty(recorder.Body.String())
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_ChangedListProvidedWithNewETag() {
+	// ARRANGE
+	suite.givenEntries(2)
+	sut := implAmbulanceWaitingListAPI{}
+	firstCtx, firstRecorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut.GetWaitingListEntries(firstCtx)
+	etag := firstRecorder.Header().Get("ETag")
+
+	suite.givenEntries(3)
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	ctx.Request.Header.Set("If-None-Match", etag)
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.NotEqual(etag, recorder.Header().Get("ETag"))
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	suite.Len(entries, 3)
+}
+
+func (suite *AmbulanceWlSuite) Test_TransferEntry_MovesEntryPreservingArrival() {
+	// ARRANGE
+	arrival := time.Now().Add(-45 * time.Minute).UTC().Truncate(time.Second)
+	source := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "moved", PatientId: "p1", WaitingSince: arrival, EstimatedDurationMinutes: 15},
+			{Id: "stays", PatientId: "p2", WaitingSince: arrival, EstimatedDurationMinutes: 15},
+		},
+	}
+	target := &Ambulance{
+		Id:          "target-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "other", PatientId: "p3", WaitingSince: arrival.Add(-time.Minute), EstimatedDurationMinutes: 15}},
+	}
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return(source, nil)
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "target-ambulance").Return(target, nil)
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	ctx, recorder := suite.newRequestContext(
+		http.MethodPost, "/waiting-list/test-ambulance/entries/moved/transfer", `{"toAmbulanceId": "target-ambulance"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "moved"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.TransferWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var moved WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &moved))
+	suite.Equal("moved", moved.Id)
+	suite.True(arrival.Equal(moved.WaitingSince))
+
+	suite.Len(source.WaitingList, 1)
+	suite.Equal("stays", source.WaitingList[0].Id)
+	suite.Len(target.WaitingList, 2)
+	suite.Equal("moved", target.WaitingList[1].Id)
+	suite.True(arrival.Equal(target.WaitingList[1].WaitingSince))
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "target-ambulance", target, mock.Anything)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance", source, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_TransferEntry_PatientInTarget_Conflict() {
+	// ARRANGE
+	source := &Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "moved", PatientId: "p1", WaitingSince: time.Now()}},
+	}
+	target := &Ambulance{
+		Id:          "target-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "other", PatientId: "p1", WaitingSince: time.Now()}},
+	}
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "test-ambulance").Return(source, nil)
+	suite.dbServiceMock.On("FindDocument", mock.Anything, "target-ambulance").Return(target, nil)
+
+	ctx, recorder := suite.newRequestContext(
+		http.MethodPost, "/waiting-list/test-ambulance/entries/moved/transfer", `{"toAmbulanceId": "target-ambulance"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "moved"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.TransferWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_ConcurrentSlotsServeInParallel() {
+	// ARRANGE
+	now := time.Now()
+	queue := func() []WaitingListEntry {
+		return []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 30},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(time.Minute), EstimatedDurationMinutes: 10},
+			{Id: "e3", PatientId: "p3", WaitingSince: now.Add(2 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "e4", PatientId: "p4", WaitingSince: now.Add(3 * time.Minute), EstimatedDurationMinutes: 10},
+		}
+	}
+	single := &Ambulance{Id: "single", WaitingList: queue()}
+	double := &Ambulance{Id: "double", WaitingList: queue(), ConcurrentSlots: 2}
+
+	// ACT
+	single.reconcileWaitingList(context.Background())
+	double.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	starts := func(ambulance *Ambulance) map[string]time.Time {
+		result := map[string]time.Time{}
+		for _, entry := range ambulance.WaitingList {
+			result[entry.Id] = entry.EstimatedStart
+		}
+		return result
+	}
+	serial, parallel := starts(single), starts(double)
+
+	// entries are served one after another
+	suite.WithinDuration(now, serial["e1"], time.Second)
+	suite.Equal(serial["e1"].Add(30*time.Minute), serial["e2"])
+	suite.Equal(serial["e2"].Add(10*time.Minute), serial["e3"])
+	suite.Equal(serial["e3"].Add(20*time.Minute), serial["e4"])
+
+	// first two entries start at once, followers take the slot freed first
+	suite.WithinDuration(now, parallel["e1"], time.Second)
+	suite.WithinDuration(now.Add(time.Minute), parallel["e2"], time.Second)
+	suite.Equal(parallel["e2"].Add(10*time.Minute), parallel["e3"])
+	suite.Equal(parallel["e1"].Add(30*time.Minute), parallel["e4"])
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_SortedByField() {
+	// ARRANGE
+	now := time.Now()
+	// estimates are not stale, the stored order is not reconciled
+	suite.givenClock(now.Add(-time.Hour))
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p3", WaitingSince: now, EstimatedStart: now.Add(20 * time.Minute)},
+			{Id: "second", PatientId: "p1", WaitingSince: now.Add(time.Minute), EstimatedStart: now.Add(time.Minute)},
+			{Id: "third", PatientId: "p2", WaitingSince: now.Add(-time.Minute), EstimatedStart: now.Add(10 * time.Minute)},
+		},
+	})
+	sut := implAmbulanceWaitingListAPI{}
+
+	for url, expected := range map[string][]string{
+		"/waiting-list/test-ambulance/entries":                                     {"first", "second", "third"},
+		"/waiting-list/test-ambulance/entries?sortBy=waitingSince":                 {"third", "first", "second"},
+		"/waiting-list/test-ambulance/entries?sortBy=estimatedStart":               {"second", "third", "first"},
+		"/waiting-list/test-ambulance/entries?sortBy=patientId":                    {"second", "third", "first"},
+		"/waiting-list/test-ambulance/entries?sortBy=waitingSince&order=desc":      {"second", "first", "third"},
+		"/waiting-list/test-ambulance/entries?sortBy=patientId&order=desc&limit=2": {"first", "third"},
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+
+		// ACT
+		sut.GetWaitingListEntries(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code, url)
+		var entries []WaitingListEntry
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+		ids := []string{}
+		for _, entry := range entries {
+			ids = append(ids, entry.Id)
+		}
+		suite.Equal(expected, ids, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_Reads_DoNotWriteDocument() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "test-entry", PatientId: "p1", WaitingSince: now.Add(-time.Hour), EstimatedStart: now.Add(-time.Hour)},
+		},
+	})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	entriesCtx, entriesRecorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut.GetWaitingListEntries(entriesCtx)
+	entryCtx, entryRecorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/test-entry", "")
+	entryCtx.Params = append(entryCtx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut.GetWaitingListEntry(entryCtx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, entriesRecorder.Code)
+	suite.Equal(http.StatusOK, entryRecorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpsertDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntry_StaleEstimatesRefreshed() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: now.Add(-time.Hour), EstimatedStart: now.Add(-50 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "test-entry", PatientId: "p2", WaitingSince: now.Add(-30 * time.Minute), EstimatedStart: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 15},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/test-entry", "")
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal(now.Add(20*time.Minute), entry.EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_EstimatesStale() {
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		entry    WaitingListEntry
+		expected bool
+	}{
+		{WaitingListEntry{WaitingSince: now.Add(-time.Hour), EstimatedStart: now.Add(time.Minute)}, false},
+		{WaitingListEntry{WaitingSince: now.Add(-time.Hour), EstimatedStart: now.Add(-time.Minute)}, true},
+		{WaitingListEntry{WaitingSince: now.Add(time.Hour), EstimatedStart: now.Add(time.Minute)}, true},
+		{WaitingListEntry{WaitingSince: now.Add(-time.Hour), EstimatedStart: now.Add(-time.Minute), Status: statusInExamination}, false},
+		{WaitingListEntry{WaitingSince: now.Add(-time.Hour), Status: statusDone}, false},
+	}
+	for i, testCase := range cases {
+		ambulance := &Ambulance{WaitingList: []WaitingListEntry{testCase.entry}}
+		suite.Equal(testCase.expected, ambulance.estimatesStale(now), i)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_InvalidSort_BadRequest() {
+	// ARRANGE
+	sut := implAmbulanceWaitingListAPI{}
+
+	for _, url := range []string{
+		"/waiting-list/test-ambulance/entries?sortBy=name",
+		"/waiting-list/test-ambulance/entries?sortBy=patientId&order=up",
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+
+		// ACT
+		sut.GetWaitingListEntries(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, url)
+	}
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "FindDocument", mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_FutureWaitingSince() {
+	scheduled := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"patientId": "test-patient", "waitingSince": %q}`, scheduled.Format(time.RFC3339))
+
+	for allowFuture, expected := range map[bool]func(entry WaitingListEntry){
+		false: func(entry WaitingListEntry) {
+			suite.WithinDuration(time.Now(), entry.WaitingSince, 5*time.Second)
+			suite.WithinDuration(time.Now(), entry.EstimatedStart, 5*time.Second)
+		},
+		true: func(entry WaitingListEntry) {
+			suite.True(scheduled.Equal(entry.WaitingSince))
+			suite.True(scheduled.Equal(entry.EstimatedStart), "scheduled entry cannot start before its arrival")
+		},
+	} {
+		// ARRANGE
+		suite.givenAmbulance(&Ambulance{Id: "test-ambulance", AllowFutureWaitingSince: allowFuture})
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", body)
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.CreateWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusCreated, recorder.Code)
+		var entry WaitingListEntry
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+		expected(entry)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_WaitingSince_CreateAndUpdateAcceptSameValues() {
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	cases := []struct {
+		waitingSince   time.Time
+		allowFuture    bool
+		expectedStatus int
+	}{
+		{now.Add(-23 * time.Hour), false, http.StatusOK},
+		{now.Add(-25 * time.Hour), false, http.StatusBadRequest},
+		{now.Add(2 * time.Hour), false, http.StatusOK},
+		{now.Add(2 * time.Hour), true, http.StatusOK},
+		{now.Add(400 * 24 * time.Hour), true, http.StatusBadRequest},
+		{now.Add(400 * 24 * time.Hour), false, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		body := fmt.Sprintf(`{"patientId": "test-patient", "waitingSince": %q}`, c.waitingSince.Format(time.RFC3339))
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT - create
+		suite.givenAmbulance(&Ambulance{Id: "test-ambulance", AllowFutureWaitingSince: c.allowFuture})
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", body)
+		sut.CreateWaitingListEntry(ctx)
+
+		// ASSERT
+		expectedCreateStatus := c.expectedStatus
+		if expectedCreateStatus == http.StatusOK {
+			expectedCreateStatus = http.StatusCreated
+		}
+		suite.Equal(expectedCreateStatus, recorder.Code, "create %v, future allowed %v", c.waitingSince, c.allowFuture)
+
+		// ACT - update
+		ambulance := &Ambulance{
+			Id:                      "test-ambulance",
+			AllowFutureWaitingSince: c.allowFuture,
+			WaitingList:             []WaitingListEntry{{Id: "test-entry", PatientId: "test-patient", WaitingSince: now}},
+		}
+		suite.givenAmbulance(ambulance)
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		ctx, recorder = suite.newRequestContext("PUT", "/waiting-list/test-ambulance/entries/test-entry", body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+		sut.UpdateWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(c.expectedStatus, recorder.Code, "update %v, future allowed %v", c.waitingSince, c.allowFuture)
+		if c.expectedStatus == http.StatusOK && c.waitingSince.After(now) && !c.allowFuture {
+			suite.True(now.Equal(ambulance.WaitingList[0].WaitingSince), "future arrival is replaced by now")
+		}
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_UnchangedOldWaitingSinceAccepted() {
+	// ARRANGE
+	arrival := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	suite.givenAmbulance(&Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "test-entry", PatientId: "test-patient", WaitingSince: arrival}},
+	})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	body := fmt.Sprintf(`{"waitingSince": %q, "note": "needs wheelchair"}`, arrival.Format(time.RFC3339))
+	ctx, recorder := suite.newRequestContext("PUT", "/waiting-list/test-ambulance/entries/test-entry", body)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_DatabaseUnavailable_ServiceUnavailable() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return((*Ambulance)(nil), fmt.Errorf("%w: connection refused", db_service.ErrUnavailable))
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusServiceUnavailable, recorder.Code)
+	suite.Equal("5", recorder.Header().Get("Retry-After"))
+	suite.Contains(recorder.Body.String(), "Database is unavailable")
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_OtherDatabaseError_NotUnavailable() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return((*Ambulance)(nil), fmt.Errorf("document failed to decode"))
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadGateway, recorder.Code)
+	suite.Empty(recorder.Header().Get("Retry-After"))
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntryPosition_MidQueueEntry() {
+	// ARRANGE
+	now := time.Now()
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "examined", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 10, Status: statusInExamination},
+			{Id: "first", PatientId: "p2", WaitingSince: now.Add(time.Minute), EstimatedDurationMinutes: 10},
+			{Id: "other-room", PatientId: "p3", WaitingSince: now.Add(2 * time.Minute), EstimatedDurationMinutes: 10, Room: "b"},
+			{Id: "gone", PatientId: "p4", WaitingSince: now.Add(3 * time.Minute), EstimatedDurationMinutes: 10, Status: statusNoShow},
+			{Id: "mid", PatientId: "p5", WaitingSince: now.Add(4 * time.Minute), EstimatedDurationMinutes: 10},
+			{Id: "last", PatientId: "p6", WaitingSince: now.Add(5 * time.Minute), EstimatedDurationMinutes: 10},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/mid/position", "")
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "mid"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntryPosition(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var position WaitingListEntryPosition
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &position))
+	suite.Equal(int32(2), position.Position)
+	// waits for the examined and the first entry of its room
+	suite.WithinDuration(now.Add(20*time.Minute), position.EstimatedStart, 5*time.Second)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntryPosition_OrderedByEstimateAndStrategy() {
+	// the emergency and the first routine entry start at once in the two slots
+	now := time.Now()
+	expected := map[string]int32{"emergency": 1, "routine": 2, "late-routine": 3}
+	for entryId, expectedPosition := range expected {
+		// ARRANGE
+		suite.givenAmbulance(&Ambulance{
+			Id:                "test-ambulance",
+			ReconcileStrategy: "priority",
+			ConcurrentSlots:   2,
+			WaitingList: []WaitingListEntry{
+				{Id: "routine", PatientId: "p1", WaitingSince: now.Add(-3 * time.Minute), EstimatedDurationMinutes: 10, Priority: priorityRoutine},
+				{Id: "late-routine", PatientId: "p2", WaitingSince: now.Add(-2 * time.Minute), EstimatedDurationMinutes: 10, Priority: priorityRoutine},
+				{Id: "emergency", PatientId: "p3", WaitingSince: now.Add(-time.Minute), EstimatedDurationMinutes: 10, Priority: priorityEmergency},
+			},
+		})
+		ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/"+entryId+"/position", "")
+		ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: entryId})
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.GetWaitingListEntryPosition(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code, entryId)
+		var position WaitingListEntryPosition
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &position))
+		suite.Equal(expectedPosition, position.Position, entryId)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntryPosition_DoneEntry_Conflict() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "done", PatientId: "p1", WaitingSince: time.Now(), Status: statusDone}},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries/done/position", "")
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "done"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntryPosition(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.JSONEq(
+		`{"status": 409, "message": "Entry is not waiting anymore", "entryStatus": "done"}`,
+		recorder.Body.String())
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_MatchesAddedEntry() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	newAmbulance := func() *Ambulance {
+		return &Ambulance{
+			Id: "test-ambulance",
+			WaitingList: []WaitingListEntry{
+				{Id: "examined", PatientId: "p1", WaitingSince: now.Add(-time.Hour), EstimatedDurationMinutes: 10, Status: statusInExamination},
+				{Id: "first", PatientId: "p2", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 15},
+				{Id: "other-room", PatientId: "p3", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 30, Room: "b"},
+				{Id: "second", PatientId: "p4", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 5},
+			},
+		}
+	}
+	suite.givenAmbulance(newAmbulance())
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate?durationMinutes=20", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEstimate(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var estimate WaitingListEntryPosition
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &estimate))
+	suite.Equal(int32(3), estimate.Position)
+	suite.True(now.Add(30*time.Minute).Equal(estimate.EstimatedStart), estimate.EstimatedStart)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// the same entry added for real gets the same estimate
+	ambulance := newAmbulance()
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder = suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p5", "estimatedDurationMinutes": 20}`)
+	sut.CreateWaitingListEntry(ctx)
+	suite.Equal(http.StatusCreated, recorder.Code)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.True(estimate.EstimatedStart.Equal(entry.EstimatedStart))
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_ShortestJobFirstMovesAhead() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id:                "test-ambulance",
+		ReconcileStrategy: "shortest-job-first",
+		WaitingList: []WaitingListEntry{
+			{Id: "long", PatientId: "p1", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 40},
+			{Id: "longer", PatientId: "p2", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 60},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate?durationMinutes=5", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEstimate(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var estimate WaitingListEntryPosition
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &estimate))
+	suite.Equal(int32(1), estimate.Position)
+	suite.True(now.Equal(estimate.EstimatedStart), estimate.EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_EmergencyPriorityMovesAhead() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id:                "test-ambulance",
+		ReconcileStrategy: "priority",
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "second", PatientId: "p2", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 20},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate?priority=emergency", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEstimate(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var estimate WaitingListEntryPosition
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &estimate))
+	suite.Equal(int32(1), estimate.Position)
+	suite.True(now.Equal(estimate.EstimatedStart), estimate.EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_ClosedAmbulance_EstimatesNextOpening() {
+	// ARRANGE - friday evening, the ambulance is closed over the weekend
+	now := time.Date(2038, 12, 24, 18, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		OfficeHours: []OfficeHours{
+			{Weekday: "monday", Open: "08:00", Close: "16:00"},
+			{Weekday: "friday", Open: "08:00", Close: "16:00"},
+		},
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: now.Add(-time.Hour), EstimatedDurationMinutes: 20},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEstimate(ctx)
+
+	// ASSERT - served on monday after the patient already waiting
+	suite.Equal(http.StatusOK, recorder.Code)
+	var estimate WaitingListEntryPosition
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &estimate))
+	suite.Equal(int32(2), estimate.Position)
+	monday := time.Date(2038, 12, 27, 8, 20, 0, 0, time.UTC)
+	suite.True(monday.Equal(estimate.EstimatedStart), estimate.EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_InvalidPriority_BadRequest() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate?priority=asap", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEstimate(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEstimate_InvalidDuration_BadRequest() {
+	for _, duration := range []string{"abc", "0", "100000"} {
+		// ARRANGE
+		ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/estimate?durationMinutes="+duration, "")
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.GetWaitingListEstimate(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, "duration %s", duration)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntries_TicketNumbersSequential() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "earlier", PatientId: "p1", WaitingSince: now.Add(-time.Hour), TicketNumber: 7, Status: statusDone},
+		},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/batch",
+		`[{"patientId": "p2", "ticketNumber": 100}, {"patientId": "p3"}]`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var created []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &created))
+	suite.Require().Len(created, 2)
+	suite.Equal(int32(8), created[0].TicketNumber)
+	suite.Equal(int32(9), created[1].TicketNumber)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_TicketNumberResetsAtLocalMidnight() {
+	// ARRANGE - 00:30 in Bratislava is still the previous day in UTC
+	now := time.Date(2038, 12, 24, 23, 30, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id:       "test-ambulance",
+		TimeZone: "Europe/Bratislava",
+		WaitingList: []WaitingListEntry{
+			{Id: "yesterday", PatientId: "p1", WaitingSince: now.Add(-time.Hour), TicketNumber: 42},
+		},
+	})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p2"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal(int32(1), entry.TicketNumber)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetPublicWaitingList_MasksPatients() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{
+				Id: "first", Name: "Ľudomír Zlostný", PatientId: "74895-ludomir-zlostny", Note: "needs wheelchair",
+				Condition: Condition{Value: "Nevoľnosť"}, WaitingSince: now.Add(-time.Hour), EstimatedDurationMinutes: 10,
+			},
+			{Id: "done", PatientId: "done-patient", WaitingSince: now.Add(-50 * time.Minute), Status: statusDone},
+			{Id: "second", PatientId: "ab", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 10},
+			{Id: "other-room", PatientId: "other-patient", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 10, Room: "b"},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/public", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetPublicWaitingList(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[
+		{"ticket": "***tny", "position": 1, "estimatedStart": "2038-12-24T10:00:00Z"},
+		{"ticket": "***ent", "room": "b", "position": 1, "estimatedStart": "2038-12-24T10:00:00Z"},
+		{"ticket": "***", "position": 2, "estimatedStart": "2038-12-24T10:10:00Z"}
+	]`, recorder.Body.String())
+	for _, sensitive := range []string{"74895", "ludomir", "Ľudomír", "wheelchair", "Nevoľnosť", "done-patient", "other-patient"} {
+		suite.NotContains(recorder.Body.String(), sensitive)
+	}
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntries_PartialStoresValidEntries() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "existing", PatientId: "p1", WaitingSince: time.Now()}},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	body := `[
+		{"id": "first", "patientId": "p2"},
+		{"id": "duplicate", "patientId": "p1"},
+		{"id": "missing-patient"},
+		{"id": "second", "patientId": "p3", "estimatedDurationMinutes": 20}
+	]`
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/batch?partial=true", body)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusMultiStatus, recorder.Code)
+	var results []WaitingListBatchResult
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &results))
+	suite.Require().Len(results, 4)
+	statuses := []int32{}
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	suite.Equal([]int32{http.StatusCreated, http.StatusConflict, http.StatusBadRequest, http.StatusCreated}, statuses)
+	suite.Equal("first", results[0].Entry.Id)
+	suite.Nil(results[1].Entry)
+	suite.Equal("Patient ID is required", results[2].Message)
+	suite.Equal(results[0].Entry.EstimatedStart.Add(15*time.Minute), results[3].Entry.EstimatedStart)
+
+	suite.Len(ambulance.WaitingList, 3)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntries_AllOrNothingByDefault() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "existing", PatientId: "p1", WaitingSince: time.Now()}},
+	}
+	suite.givenAmbulance(ambulance)
+	body := `[{"id": "first", "patientId": "p2"}, {"id": "duplicate", "patientId": "p1"}]`
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/batch", body)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(float64(1), response["index"])
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// fixedClock provides always the same time
+type fixedClock time.Time
+
+func (this fixedClock) Now() time.Time {
+	return time.Time(this)
+}
+
+// givenClock fixes the current time of the waiting list logic for the rest of the test
+func (suite *AmbulanceWlSuite) givenClock(now time.Time) {
+	previous := clock
+	clock = fixedClock(now)
+	suite.T().Cleanup(func() { clock = previous })
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_FixedClockGivesExactEstimates() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 15},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	suite.Equal(now, ambulance.WaitingList[0].EstimatedStart)
+	suite.Equal(now.Add(20*time.Minute), ambulance.WaitingList[1].EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_SameWaitingSinceIsDeterministic() {
+	defer func(previous serverConfig) { config = previous }(config)
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	imported := now.Add(-time.Hour)
+	entries := []WaitingListEntry{
+		{Id: "e3", PatientId: "p1", WaitingSince: imported, EstimatedDurationMinutes: 10, TicketNumber: 2},
+		{Id: "e1", PatientId: "p3", WaitingSince: imported, EstimatedDurationMinutes: 10, TicketNumber: 3},
+		{Id: "e4", PatientId: "p2", WaitingSince: imported, EstimatedDurationMinutes: 10, TicketNumber: 1},
+		{Id: "e2", PatientId: "p4", WaitingSince: imported, EstimatedDurationMinutes: 10, TicketNumber: 4},
+	}
+	reconciled := func() []string {
+		ids := []string{}
+		// every rotation of the stored order must give the same result
+		for shift := range entries {
+			ambulance := &Ambulance{Id: "test-ambulance"}
+			ambulance.WaitingList = append(slices.Clone(entries[shift:]), entries[:shift]...)
+			ambulance.reconcileWaitingList(context.Background())
+			order := []string{}
+			for _, entry := range ambulance.WaitingList {
+				order = append(order, entry.Id)
+			}
+			if shift > 0 {
+				suite.Equal(ids, order)
+			}
+			ids = order
+		}
+		return ids
+	}
+
+	// ACT & ASSERT
+	suite.Equal([]string{"e1", "e2", "e3", "e4"}, reconciled())
+
+	config.TieBreakDescending = true
+	suite.Equal([]string{"e4", "e3", "e2", "e1"}, reconciled())
+
+	config.TieBreakField, config.TieBreakDescending = tieBreakTicketNumber, false
+	suite.Equal([]string{"e4", "e3", "e1", "e2"}, reconciled())
+
+	config.TieBreakField = tieBreakPatientId
+	suite.Equal([]string{"e3", "e4", "e1", "e2"}, reconciled())
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_FixedClockSetsWaitingSince() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+
+	// ACT
+	entry := suite.createEntry(`{"patientId": "test-patient", "waitingSince": "2038-12-24T09:00:00Z"}`)
+
+	// ASSERT
+	suite.True(now.Equal(entry.WaitingSince))
+	suite.True(now.Equal(entry.EstimatedStart))
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_DurationLimits() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.MaxDurationMinutes = 480
+
+	for duration, expectedStatus := range map[int]int{
+		480:  http.StatusCreated,
+		481:  http.StatusBadRequest,
+		9999: http.StatusBadRequest,
+		-5:   http.StatusBadRequest,
+	} {
+		// ARRANGE
+		suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		body := fmt.Sprintf(`{"patientId": "test-patient", "estimatedDurationMinutes": %d}`, duration)
+		ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", body)
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.CreateWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(expectedStatus, recorder.Code, "duration %d", duration)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_DurationOverLimit_BadRequest() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.MaxDurationMinutes = 480
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"estimatedDurationMinutes": 481}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), "must not exceed 480 minutes")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_NoteSanitized() {
+	// ACT
+	entry := suite.createEntry(`{"patientId": "test-patient", "note": "needs\u0007 wheelchair\n"}`)
+
+	// ASSERT
+	suite.Equal("needs wheelchair", entry.Note)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_IllegalStatusTransition_Conflict() {
+	// ARRANGE
+	completedAt := time.Now().Add(-time.Hour)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "test-entry", PatientId: "test-patient", Status: statusDone, CompletedAt: &completedAt},
+		},
+	})
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"status": "waiting"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), "Cannot change status from done to waiting")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_NoteReplaced() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "test-entry", PatientId: "test-patient", Note: "needs wheelchair"}},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"note": "allergic to penicillin"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var entry WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal("allergic to penicillin", entry.Note)
+	suite.Equal("allergic to penicillin", ambulance.WaitingList[0].Note)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_EmptyNoteClears_MissingNoteKeeps() {
+	expected := map[string]string{`{"note": ""}`: "", `{"priority": "urgent"}`: "needs wheelchair"}
+	for body, note := range expected {
+		// ARRANGE
+		ambulance := &Ambulance{
+			Id:          "test-ambulance",
+			WaitingList: []WaitingListEntry{{Id: "test-entry", PatientId: "test-patient", Note: "needs wheelchair"}},
+		}
+		suite.givenAmbulance(ambulance)
+		suite.dbServiceMock.
+			On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		ctx, recorder := suite.newRequestContext("PUT", "/waiting-list/test-ambulance/entries/test-entry", body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+		sut := implAmbulanceWaitingListAPI{}
+
+		// ACT
+		sut.UpdateWaitingListEntry(ctx)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code, body)
+		suite.Equal(note, ambulance.WaitingList[0].Note, body)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_NoteTooLong_BadRequest() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	body := fmt.Sprintf(`{"patientId": "test-patient", "note": %q}`, strings.Repeat("á", maxNoteLength+1))
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", body)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), "Note must not exceed 500 characters")
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_MalformedJson_ReportsOffset() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p1",, }`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(msgInvalidRequestBody, response["code"])
+	suite.Equal(float64(20), response["offset"])
+	suite.NotContains(response, "field")
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateEntry_WrongFieldType_ReportsFieldAndExpectedType() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "test-entry", PatientId: "test-patient"}},
+	})
+	ctx, recorder := suite.newRequestContext(
+		"PUT", "/waiting-list/test-ambulance/entries/test-entry", `{"estimatedDurationMinutes": "twenty"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "test-entry"})
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal("estimatedDurationMinutes", response["field"])
+	suite.Equal("number", response["expectedType"])
+	suite.Equal("string", response["actualType"])
+	suite.Contains(response, "offset")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_UnknownField_RejectedInStrictMode() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.StrictJson = true
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p1", "estimatedDuration": 20}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(msgInvalidRequestBody, response["code"])
+	suite.Equal("estimatedDuration", response["field"])
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_UnknownField_IgnoredByDefault() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"patientId": "p1", "estimatedDuration": 20}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_SourceDefaultsToWalkin() {
+	// ACT
+	entry := suite.createEntry(`{"patientId": "test-patient"}`)
+
+	// ASSERT
+	suite.Equal(sourceWalkin, entry.Source)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_ValidSourcesKept() {
+	for _, source := range []string{sourceWalkin, sourcePhone, sourceReferral, sourceOnline} {
+		// ACT
+		entry := suite.createEntry(fmt.Sprintf(`{"patientId": "test-patient", "source": %q}`, source))
+
+		// ASSERT
+		suite.Equal(source, entry.Source)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_InvalidSource_BadRequest() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"patientId": "test-patient", "source": "carrier-pigeon"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), "Invalid entry source")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_DeleteEntries_RemovesOnlyGivenStatuses() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "done", PatientId: "p1", WaitingSince: time.Now(), Status: statusDone},
+			{Id: "waiting", PatientId: "p2", WaitingSince: time.Now()},
+			{Id: "examined", PatientId: "p3", WaitingSince: time.Now(), Status: statusInExamination},
+			{Id: "no-show", PatientId: "p4", WaitingSince: time.Now(), Status: statusNoShow},
+		},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("DELETE", "/waiting-list/test-ambulance/entries?status=done", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.DeleteWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{"removed": 1}`, recorder.Body.String())
+	ids := []string{}
+	for _, entry := range ambulance.WaitingList {
+		ids = append(ids, entry.Id)
+	}
+	suite.ElementsMatch([]string{"waiting", "examined", "no-show"}, ids)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_DeleteEntries_WithoutStatusRequiresConfirmation() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("DELETE", "/waiting-list/test-ambulance/entries", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.DeleteWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "FindDocument", mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_MissingAmbulanceNotFound() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return((*Ambulance)(nil), db_service.ErrNotFound)
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusNotFound, recorder.Code)
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Equal(msgAmbulanceNotFound, response["code"])
+}
+
+func (suite *AmbulanceWlSuite) Test_GetEntries_EmptyWaitingList() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListEntries(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[]`, recorder.Body.String())
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_RecordsAudit() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	audit := &DbServiceMock[AuditEntry]{}
+	audit.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"id": "new-entry", "patientId": "new-patient"}`)
+	ctx.Set("audit_log", audit)
+	ctx.Request.Header.Set("X-Forwarded-User", "dr-house")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	audit.AssertNumberOfCalls(suite.T(), "CreateDocument", 1)
+	record := audit.Calls[0].Arguments.Get(2).(*AuditEntry)
+	suite.Equal(auditActionCreate, record.Action)
+	suite.Equal("test-ambulance", record.AmbulanceId)
+	suite.Equal("new-entry", record.EntryId)
+	suite.Equal("dr-house", record.Actor)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_AuditFailureDoesNotFailRequest() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	audit := &DbServiceMock[AuditEntry]{}
+	audit.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrUnavailable)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"id": "new-entry", "patientId": "new-patient"}`)
+	ctx.Set("audit_log", audit)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	audit.AssertNumberOfCalls(suite.T(), "CreateDocument", 1)
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileStrategies_OrderDiffers() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	queue := func() []*WaitingListEntry {
+		return []*WaitingListEntry{
+			{Id: "long", PatientId: "p1", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 40},
+			{Id: "medium", PatientId: "p2", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "short", PatientId: "p3", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 5},
+		}
+	}
+	order := func(queue []*WaitingListEntry) []string {
+		sorted := slices.Clone(queue)
+		slices.SortFunc(sorted, func(left, right *WaitingListEntry) int {
+			return left.EstimatedStart.Compare(right.EstimatedStart)
+		})
+		ids := []string{}
+		for _, entry := range sorted {
+			ids = append(ids, entry.Id)
+		}
+		return ids
+	}
+	fifo, shortestFirst := queue(), queue()
+
+	// ACT
+	reconcileStrategies["fifo"](&Ambulance{ConcurrentSlots: 1}, fifo, now)
+	reconcileStrategies["shortest-job-first"](&Ambulance{ConcurrentSlots: 1}, shortestFirst, now)
+
+	// ASSERT
+	suite.Equal([]string{"long", "medium", "short"}, order(fifo))
+	suite.Equal([]string{"short", "medium", "long"}, order(shortestFirst))
+	suite.Equal(now.Add(5*time.Minute), shortestFirst[1].EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileStrategies_ExaminedEntryKeepsSlot() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id:                "test-ambulance",
+		ReconcileStrategy: "shortest-job-first",
+		WaitingList: []WaitingListEntry{
+			{Id: "examined", PatientId: "p1", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 40, Status: statusInExamination},
+			{Id: "short", PatientId: "p2", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 5},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	suite.Equal(now, reconciledEntry(ambulance, "examined").EstimatedStart)
+	suite.Equal(now.Add(40*time.Minute), reconciledEntry(ambulance, "short").EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileStrategies_PriorityAging_RoutineOvertakesNewUrgent() {
+	defer func(previous serverConfig) { config = previous }(config)
+	arrived := time.Date(2038, 12, 24, 8, 0, 0, 0, time.UTC)
+	for _, rate := range []float64{0.5, 1, 4} {
+		// the routine patient overtakes the urgent one arriving 1/rate hours later
+		overtakeAfter := time.Duration(float64(time.Hour) / rate)
+		for _, waited := range []time.Duration{overtakeAfter - time.Minute, overtakeAfter + time.Minute} {
+			// ARRANGE
+			config.PriorityAgingRate = rate
+			now := arrived.Add(waited)
+			ambulance := &Ambulance{
+				Id:                "test-ambulance",
+				ReconcileStrategy: "priority",
+				WaitingList: []WaitingListEntry{
+					{Id: "routine", PatientId: "p1", WaitingSince: arrived, EstimatedDurationMinutes: 15, Priority: priorityRoutine},
+					{Id: "urgent", PatientId: "p2", WaitingSince: now, EstimatedDurationMinutes: 15, Priority: priorityUrgent},
+				},
+			}
+			suite.givenClock(now)
+
+			// ACT
+			ambulance.reconcileWaitingList(context.Background())
+
+			// ASSERT
+			first, second := "urgent", "routine"
+			if waited > overtakeAfter {
+				first, second = second, first
+			}
+			suite.Equal(now, reconciledEntry(ambulance, first).EstimatedStart, "rate %v, waited %v", rate, waited)
+			suite.Equal(now.Add(15*time.Minute), reconciledEntry(ambulance, second).EstimatedStart, "rate %v, waited %v", rate, waited)
+		}
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_ReconcileStrategies_PriorityAging_EmergencyAlwaysFirst() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.PriorityAgingRate = 1
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id:                "test-ambulance",
+		ReconcileStrategy: "priority",
+		WaitingList: []WaitingListEntry{
+			{Id: "routine", PatientId: "p1", WaitingSince: now.Add(-10 * time.Hour), EstimatedDurationMinutes: 15},
+			{Id: "urgent", PatientId: "p2", WaitingSince: now.Add(-5 * time.Hour), EstimatedDurationMinutes: 15, Priority: priorityUrgent},
+			{Id: "emergency", PatientId: "p3", WaitingSince: now, EstimatedDurationMinutes: 15, Priority: priorityEmergency},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	suite.Equal(now, reconciledEntry(ambulance, "emergency").EstimatedStart)
+	suite.Equal(now.Add(15*time.Minute), reconciledEntry(ambulance, "routine").EstimatedStart)
+	suite.Equal(now.Add(30*time.Minute), reconciledEntry(ambulance, "urgent").EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_CreateEntry_InvalidPriority_BadRequest() {
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/waiting-list/test-ambulance/entries", `{"patientId": "test-patient", "priority": "whenever"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.CreateWaitingListEntry(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.Contains(recorder.Body.String(), "Invalid entry priority")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateStatuses_ChangesAllEntries() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: time.Now(), Status: statusInExamination},
+			{Id: "e2", PatientId: "p2", WaitingSince: time.Now()},
+			{Id: "e3", PatientId: "p3", WaitingSince: time.Now()},
+		},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PATCH", "/waiting-list/test-ambulance/status", `{"entryIds": ["e1", "e2"], "status": "done"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListStatuses(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+	suite.Equal(statusDone, reconciledEntry(ambulance, "e1").Status)
+	suite.Equal(statusDone, reconciledEntry(ambulance, "e2").Status)
+	suite.Equal("", reconciledEntry(ambulance, "e3").Status)
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateStatuses_DoneRecordsCompletion() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-time.Hour), Status: statusInExamination},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-time.Hour)},
+		},
+	}
+	suite.givenAmbulance(ambulance)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PATCH", "/waiting-list/test-ambulance/status", `{"entryIds": ["e1"], "status": "done"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListStatuses(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Require().NotNil(reconciledEntry(ambulance, "e1").CompletedAt)
+	suite.Equal(now, *reconciledEntry(ambulance, "e1").CompletedAt)
+	suite.Nil(reconciledEntry(ambulance, "e2").CompletedAt)
+}
+
+func (suite *AmbulanceWlSuite) drainTime(ambulance *Ambulance) WaitingListDrainTime {
+	suite.givenAmbulance(ambulance)
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/draintime", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	sut.GetWaitingListDrainTime(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	var result WaitingListDrainTime
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &result))
+	return result
+}
+
+func (suite *AmbulanceWlSuite) Test_GetDrainTime_SerialQueue() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-time.Hour), Status: statusInExamination,
+				EstimatedStart: now, EstimatedDurationMinutes: 20},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-50 * time.Minute), EstimatedDurationMinutes: 15},
+			{Id: "e3", PatientId: "p3", WaitingSince: now.Add(-40 * time.Minute), EstimatedDurationMinutes: 30},
+			{Id: "e4", PatientId: "p4", WaitingSince: now.Add(-2 * time.Hour), Status: statusDone, EstimatedDurationMinutes: 60},
+		},
+	}
+
+	// ACT
+	result := suite.drainTime(ambulance)
+
+	// ASSERT
+	suite.Equal(int32(3), result.ActiveEntries)
+	suite.True(now.Add(65*time.Minute).Equal(result.DrainTime), result.DrainTime)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetDrainTime_EmptyQueueIsNow() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+
+	// ACT
+	result := suite.drainTime(&Ambulance{Id: "test-ambulance"})
+
+	// ASSERT
+	suite.Equal(int32(0), result.ActiveEntries)
+	suite.True(now.Equal(result.DrainTime), result.DrainTime)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetDrainTime_MultiSlotQueue() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id:              "test-ambulance",
+		ConcurrentSlots: 2,
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-40 * time.Minute), EstimatedDurationMinutes: 30},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 30},
+			{Id: "e3", PatientId: "p3", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "e4", PatientId: "p4", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 10},
+		},
+	}
+
+	// ACT
+	result := suite.drainTime(ambulance)
+
+	// ASSERT - slots serve e1+e3 and e2+e4 in parallel
+	suite.Equal(int32(4), result.ActiveEntries)
+	suite.True(now.Add(50*time.Minute).Equal(result.DrainTime), result.DrainTime)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetDrainTime_RollsOverToNextOpenDay() {
+	// ARRANGE - friday afternoon, the ambulance is closed over the weekend
+	now := time.Date(2038, 12, 24, 15, 30, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		OfficeHours: []OfficeHours{
+			{Weekday: "monday", Open: "08:00", Close: "16:00"},
+			{Weekday: "friday", Open: "08:00", Close: "16:00"},
+		},
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 30},
+		},
+	}
+
+	// ACT
+	result := suite.drainTime(ambulance)
+
+	// ASSERT - 30 minutes are served before the closing, the remaining 20 minutes on monday morning
+	monday := time.Date(2038, 12, 27, 8, 20, 0, 0, time.UTC)
+	suite.True(monday.Equal(result.DrainTime), result.DrainTime)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetSchedule_MultiSlotQueueSpansClosing() {
+	// ARRANGE - friday afternoon, the ambulance is closed over the weekend
+	now := time.Date(2038, 12, 24, 15, 30, 0, 0, time.UTC)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id:              "test-ambulance",
+		ConcurrentSlots: 2,
+		OfficeHours: []OfficeHours{
+			{Weekday: "monday", Open: "08:00", Close: "16:00"},
+			{Weekday: "friday", Open: "08:00", Close: "16:00"},
+		},
+		WaitingList: []WaitingListEntry{
+			{Id: "examined", PatientId: "p1", WaitingSince: now.Add(-time.Hour), EstimatedDurationMinutes: 20, Status: statusInExamination},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 40},
+			{Id: "e3", PatientId: "p3", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 15},
+			{Id: "e4", PatientId: "p4", WaitingSince: now.Add(-5 * time.Minute), EstimatedDurationMinutes: 10},
+			{Id: "done", PatientId: "p5", WaitingSince: now.Add(-2 * time.Hour), EstimatedDurationMinutes: 10, Status: statusDone},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/schedule", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListSchedule(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var schedule []ScheduledWaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &schedule))
+	friday := func(hour, minute int) time.Time { return time.Date(2038, 12, 24, hour, minute, 0, 0, time.UTC) }
+	monday := func(hour, minute int) time.Time { return time.Date(2038, 12, 27, hour, minute, 0, 0, time.UTC) }
+	expected := []struct {
+		id         string
+		position   int32
+		slot       int32
+		start, end time.Time
+	}{
+		{"examined", 0, 1, friday(15, 30), friday(15, 50)},
+		// 30 minutes are served before the closing, the remaining 10 minutes on monday morning
+		{"e2", 1, 2, friday(15, 30), monday(8, 10)},
+		{"e3", 2, 1, friday(15, 50), monday(8, 5)},
+		// the slot is free during the weekend, the examination starts when the slot is free on monday
+		{"e4", 3, 1, monday(8, 5), monday(8, 15)},
+	}
+	suite.Len(schedule, len(expected))
+	for i, entry := range expected {
+		if i >= len(schedule) {
+			break
+		}
+		suite.Equal(entry.id, schedule[i].Id)
+		suite.Equal(entry.position, schedule[i].Position, entry.id)
+		suite.Equal(entry.slot, schedule[i].Slot, entry.id)
+		suite.True(entry.start.Equal(schedule[i].EstimatedStart), "%v start %v", entry.id, schedule[i].EstimatedStart)
+		suite.True(entry.end.Equal(schedule[i].EstimatedEnd), "%v end %v", entry.id, schedule[i].EstimatedEnd)
+	}
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetSchedule_StartsAtNextOpening() {
+	// ARRANGE - saturday, the ambulance opens on monday
+	now := time.Date(2038, 12, 25, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		OfficeHours: []OfficeHours{
+			{Weekday: "monday", Open: "08:00", Close: "16:00"},
+		},
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 20},
+		},
+	}
+
+	// ACT
+	schedule := ambulance.schedule(now)
+
+	// ASSERT
+	suite.Require().Len(schedule, 1)
+	suite.Equal(time.Date(2038, 12, 27, 8, 0, 0, 0, time.UTC), schedule[0].EstimatedStart)
+	suite.Equal(time.Date(2038, 12, 27, 8, 20, 0, 0, time.UTC), schedule[0].EstimatedEnd)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetThroughput_CountsCompletionsPerBucket() {
+	// ARRANGE
+	now := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	suite.givenClock(now)
+	completedAt := func(offset time.Duration) *time.Time {
+		completed := now.Add(offset)
+		return &completed
+	}
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", Status: statusDone, CompletedAt: completedAt(-170 * time.Minute), Source: sourcePhone},
+			{Id: "e2", PatientId: "p2", Status: statusDone, CompletedAt: completedAt(-125 * time.Minute), Source: sourceReferral},
+			{Id: "e3", PatientId: "p3", Status: statusDone, CompletedAt: completedAt(-10 * time.Minute)},
+			{Id: "e4", PatientId: "p4", Status: statusDone, CompletedAt: completedAt(-60 * time.Minute)},
+			// out of the window
+			{Id: "e5", PatientId: "p5", Status: statusDone, CompletedAt: completedAt(-4 * time.Hour)},
+			// not completed
+			{Id: "e6", PatientId: "p6", Status: statusWaiting},
+			{Id: "e7", PatientId: "p7", Status: statusDone},
+		},
+	})
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/throughput?window=3h&bucket=1h", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListThroughput(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var buckets []ThroughputBucket
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &buckets))
+	suite.Equal([]ThroughputBucket{
+		{Start: now.Add(-3 * time.Hour), End: now.Add(-2 * time.Hour), Completed: 2,
+			BySource: map[string]int32{sourcePhone: 1, sourceReferral: 1}},
+		{Start: now.Add(-2 * time.Hour), End: now.Add(-1 * time.Hour), Completed: 0,
+			BySource: map[string]int32{}},
+		// entries without the source arrived as walkin
+		{Start: now.Add(-1 * time.Hour), End: now, Completed: 2,
+			BySource: map[string]int32{sourceWalkin: 2}},
+	}, buckets)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetThroughput_InvalidDurations_BadRequest() {
+	suite.givenEntries(1)
+	sut := implAmbulanceWaitingListAPI{}
+
+	for _, url := range []string{
+		"/waiting-list/test-ambulance/throughput?window=abc",
+		"/waiting-list/test-ambulance/throughput?bucket=-1h",
+		"/waiting-list/test-ambulance/throughput?window=30m&bucket=1h",
+		"/waiting-list/test-ambulance/throughput?window=720h&bucket=1m",
+	} {
+		ctx, recorder := suite.newRequestContext("GET", url, "")
+		sut.GetWaitingListThroughput(ctx)
+		suite.Equal(http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+func (suite *AmbulanceWlSuite) Test_UpdateStatuses_IllegalTransitionFailsBatch() {
+	// ARRANGE
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: time.Now(), Status: statusDone},
+			{Id: "e2", PatientId: "p2", WaitingSince: time.Now()},
+		},
+	}
+	suite.givenAmbulance(ambulance)
+	ctx, recorder := suite.newRequestContext(
+		"PATCH", "/waiting-list/test-ambulance/status", `{"entryIds": ["e1", "e2"], "status": "in-examination"}`)
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.UpdateWaitingListStatuses(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	var response struct {
+		InvalidEntries []struct {
+			EntryId string `json:"entryId"`
+		} `json:"invalidEntries"`
+	}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.InvalidEntries, 1)
+	suite.Equal("e1", response.InvalidEntries[0].EntryId)
+	suite.Equal("", reconciledEntry(ambulance, "e2").Status)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_TimeZoneEstimatesInUtc() {
+	// ARRANGE
+	location, err := time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+	now := time.Date(2038, 12, 24, 9, 0, 0, 0, location)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id:       "test-ambulance",
+		TimeZone: "America/New_York",
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 20},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-5 * time.Minute), EstimatedDurationMinutes: 15},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT
+	second := reconciledEntry(ambulance, "e2").EstimatedStart
+	suite.Equal(time.UTC, second.Location())
+	suite.Equal(time.Date(2038, 12, 24, 14, 20, 0, 0, time.UTC), second)
+	suite.Equal(9, second.In(location).Hour())
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_AcrossDaylightSavingTransition() {
+	// ARRANGE
+	location, err := time.LoadLocation("Europe/Bratislava")
+	suite.Require().NoError(err)
+	// clocks move from 02:00 to 03:00 on the last Sunday of March, the ambulance opens at 03:00 local time
+	now := time.Date(2038, 3, 28, 1, 30, 0, 0, location)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id:          "test-ambulance",
+		TimeZone:    "Europe/Bratislava",
+		OfficeHours: []OfficeHours{{Weekday: "sunday", Open: "03:00", Close: "04:30"}},
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 60},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(time.Minute), EstimatedDurationMinutes: 60},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT - the first patient waits for the opening, the second one for the first
+	first := reconciledEntry(ambulance, "e1").EstimatedStart
+	second := reconciledEntry(ambulance, "e2").EstimatedStart
+	suite.Equal(time.UTC, first.Location())
+	suite.Equal(time.Date(2038, 3, 28, 3, 0, 0, 0, location), first.In(location))
+	suite.Equal(time.Hour, second.Sub(first))
+	suite.Equal(4, second.In(location).Hour())
+	suite.Equal(0, second.In(location).Minute())
+}
+
+func (suite *AmbulanceWlSuite) Test_Reconcile_WorkLeftAtClosingContinuesNextOpening() {
+	// ARRANGE - friday afternoon, the ambulance is closed over the weekend
+	now := time.Date(2038, 12, 24, 15, 30, 0, 0, time.UTC)
+	suite.givenClock(now)
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		OfficeHours: []OfficeHours{
+			{Weekday: "monday", Open: "08:00", Close: "16:00"},
+			{Weekday: "friday", Open: "08:00", Close: "16:00"},
+		},
+		WaitingList: []WaitingListEntry{
+			{Id: "e1", PatientId: "p1", WaitingSince: now.Add(-20 * time.Minute), EstimatedDurationMinutes: 40},
+			{Id: "e2", PatientId: "p2", WaitingSince: now.Add(-10 * time.Minute), EstimatedDurationMinutes: 30},
+		},
+	}
+
+	// ACT
+	ambulance.reconcileWaitingList(context.Background())
+
+	// ASSERT - 30 minutes of the first examination are served before the closing, the rest on monday
+	suite.Equal(now, reconciledEntry(ambulance, "e1").EstimatedStart)
+	suite.Equal(time.Date(2038, 12, 27, 8, 10, 0, 0, time.UTC), reconciledEntry(ambulance, "e2").EstimatedStart)
+}
+
+func (suite *AmbulanceWlSuite) Test_ValidateTimeZone_RejectsUnknownZone() {
+	suite.NoError((&Ambulance{}).validateTimeZone())
+	suite.NoError((&Ambulance{TimeZone: "Europe/Bratislava"}).validateTimeZone())
+	suite.Error((&Ambulance{TimeZone: "Mars/Olympus_Mons"}).validateTimeZone())
+}
+
+// syncChanges requests the changes of the test-ambulance since the token
+func (suite *AmbulanceWlSuite) syncChanges(since string) WaitingListChanges {
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/changes?since="+url.QueryEscape(since), "")
+	sut := implAmbulanceWaitingListAPI{}
+	sut.GetWaitingListChanges(ctx)
+	suite.Require().Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	var changes WaitingListChanges
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &changes))
+	return changes
+}
+
+func (suite *AmbulanceWlSuite) Test_GetChanges_AddUpdateRemoveBetweenSyncPoints() {
+	// ARRANGE
+	created := time.Date(2038, 12, 24, 9, 0, 0, 0, time.UTC)
+	now := created.Add(time.Hour)
+	suite.givenClock(now)
+	suite.givenAmbulance(&Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "updated-entry", PatientId: "p1", WaitingSince: created, EstimatedDurationMinutes: 15,
+				EstimatedStart: now, CreatedAt: &created, UpdatedAt: &created},
+			{Id: "removed-entry", PatientId: "p2", WaitingSince: created.Add(time.Minute), EstimatedDurationMinutes: 15,
+				EstimatedStart: now.Add(15 * time.Minute), CreatedAt: &created, UpdatedAt: &created},
+		},
+	})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	sut := implAmbulanceWaitingListAPI{}
+	initial := suite.syncChanges("")
+
+	// ACT
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+		`{"id": "added-entry", "patientId": "p3"}`)
+	sut.CreateWaitingListEntry(ctx)
+	suite.Require().Equal(http.StatusCreated, recorder.Code)
+
+	ctx, recorder = suite.newRequestContext("PUT", "/waiting-list/test-ambulance/entries/updated-entry",
+		`{"id": "updated-entry", "patientId": "p1", "estimatedDurationMinutes": 15, "note": "needs wheelchair"}`)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "updated-entry"})
+	sut.UpdateWaitingListEntry(ctx)
+	suite.Require().Equal(http.StatusOK, recorder.Code)
+
+	ctx, recorder = suite.newRequestContext("DELETE", "/waiting-list/test-ambulance/entries/removed-entry", "")
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "removed-entry"})
+	sut.DeleteWaitingListEntry(ctx)
+	suite.Require().Equal(http.StatusNoContent, recorder.Code)
+
+	changes := suite.syncChanges(initial.SyncToken)
+
+	// ASSERT
+	suite.True(initial.Reset)
+	suite.Len(initial.Added, 2)
+	suite.Equal(created.Format(time.RFC3339Nano), initial.SyncToken)
+
+	suite.False(changes.Reset)
+	suite.Require().Len(changes.Added, 1)
+	suite.Equal("added-entry", changes.Added[0].Id)
+	suite.Require().Len(changes.Updated, 1)
+	suite.Equal("needs wheelchair", changes.Updated[0].Note)
+	suite.True(created.Equal(*changes.Updated[0].CreatedAt))
+	suite.True(now.Equal(*changes.Updated[0].UpdatedAt))
+	suite.Equal([]string{"removed-entry"}, changes.Removed)
+	suite.Equal(now.Format(time.RFC3339Nano), changes.SyncToken)
+}
+
+func (suite *AmbulanceWlSuite) Test_GetChanges_MalformedToken_BadRequest() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/changes?since=yesterday", "")
+	sut := implAmbulanceWaitingListAPI{}
+
+	// ACT
+	sut.GetWaitingListChanges(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "FindDocument", mock.Anything, mock.Anything)
 }
@@ -1,15 +1,24 @@
 package ambulance_wl
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // CreateAmbulance - Saves new ambulance definition
 func (this *implAmbulancesAPI) CreateAmbulance(ctx *gin.Context) {
+	// gin context does not carry the request span, the db calls must use the span context
+	spanctx, span := tracer.Start(ctx.Request.Context(), "CreateAmbulance")
+	defer span.End()
+
 	value, exists := ctx.Get("db_service")
 	if !exists {
 		ctx.JSON(
@@ -35,28 +44,57 @@ func (this *implAmbulancesAPI) CreateAmbulance(ctx *gin.Context) {
 	}
 
 	ambulance := Ambulance{}
-	err := ctx.BindJSON(&ambulance)
+	err := bindJSON(ctx, &ambulance)
 	if err != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			invalidBodyResponse(ctx, "Bad Request", err))
+		return
+	}
+
+	if err := ambulance.validateTimeZone(); err != nil {
 		ctx.JSON(
 			http.StatusBadRequest,
 			gin.H{
 				"status":  "Bad Request",
-				"message": "Invalid request body",
+				"message": "Invalid time zone of the ambulance",
 				"error":   err.Error(),
 			})
 		return
 	}
 
-	if ambulance.Id == "" {
-		ambulance.Id = uuid.New().String()
+	if problems := validateOfficeHours(ambulance.OfficeHours); len(problems) > 0 {
+		ctx.JSON(http.StatusBadRequest, invalidOfficeHoursResponse("Bad Request", problems))
+		return
+	}
+
+	generatedId := ambulance.Id == ""
+	if generatedId {
+		ambulance.Id = newId()
+	} else if !isValidId(ambulance.Id) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "id", ambulance.Id))
+		return
 	}
 
-	err = db.CreateDocument(ctx, ambulance.Id, &ambulance)
+	// upsert replaces the existing ambulance of the same id, so that repeated imports succeed
+	upsert, _ := strconv.ParseBool(ctx.Query("upsert"))
+	status := http.StatusCreated
+	if upsert {
+		status = http.StatusOK
+		err = db.UpsertDocument(spanctx, ambulance.Id, &ambulance)
+	} else {
+		err = db.CreateDocument(spanctx, ambulance.Id, &ambulance)
+		// collision of the generated id is not caused by the client, another id is generated instead
+		for attempt := 0; generatedId && err == db_service.ErrConflict && attempt < config.IdCollisionRetries; attempt++ {
+			ambulance.Id = newId()
+			err = db.CreateDocument(spanctx, ambulance.Id, &ambulance)
+		}
+	}
 
 	switch err {
 	case nil:
 		ctx.JSON(
-			http.StatusCreated,
+			status,
 			ambulance,
 		)
 	case db_service.ErrConflict:
@@ -64,7 +102,8 @@ func (this *implAmbulancesAPI) CreateAmbulance(ctx *gin.Context) {
 			http.StatusConflict,
 			gin.H{
 				"status":  "Conflict",
-				"message": "Ambulance already exists",
+				"code":    msgAmbulanceConflict,
+				"message": localize(ctx, msgAmbulanceConflict),
 				"error":   err.Error(),
 			},
 		)
@@ -82,6 +121,9 @@ func (this *implAmbulancesAPI) CreateAmbulance(ctx *gin.Context) {
 
 // DeleteAmbulance - Deletes specific ambulance
 func (this *implAmbulancesAPI) DeleteAmbulance(ctx *gin.Context) {
+	spanctx, span := tracer.Start(ctx.Request.Context(), "DeleteAmbulance")
+	defer span.End()
+
 	value, exists := ctx.Get("db_service")
 	if !exists {
 		ctx.JSON(
@@ -107,7 +149,27 @@ func (this *implAmbulancesAPI) DeleteAmbulance(ctx *gin.Context) {
 	}
 
 	ambulanceId := ctx.Param("ambulanceId")
-	err := db.DeleteDocument(ctx, ambulanceId)
+	if !isValidId(ambulanceId) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "ambulanceId", ambulanceId))
+		return
+	}
+	// buffered changes must not recreate the deleted ambulance
+	writes.flush(ambulanceId)
+	// the patients of the deleted ambulance are released, a failed lookup leaves orphaned registrations
+	registry := patientRegistry(ctx)
+	var deleted *Ambulance
+	if registry != nil {
+		deleted, _ = db.FindDocument(db_service.ReadForUpdate(spanctx), ambulanceId)
+	}
+	err := db.DeleteDocument(spanctx, ambulanceId)
+	if err == nil && deleted != nil {
+		releaseEntryPatients(ctx, spanctx, ambulanceId, deleted.WaitingList)
+	}
+	// the ambulance already absent is deleted as well for the idempotent clients
+	if err == db_service.ErrNotFound && config.IdempotentDelete {
+		span.AddEvent("ambulance already absent")
+		err = nil
+	}
 
 	switch err {
 	case nil:
@@ -117,7 +179,8 @@ func (this *implAmbulancesAPI) DeleteAmbulance(ctx *gin.Context) {
 			http.StatusNotFound,
 			gin.H{
 				"status":  "Not Found",
-				"message": "Ambulance not found",
+				"code":    msgAmbulanceNotFound,
+				"message": localize(ctx, msgAmbulanceNotFound),
 				"error":   err.Error(),
 			},
 		)
@@ -132,3 +195,367 @@ func (this *implAmbulancesAPI) DeleteAmbulance(ctx *gin.Context) {
 	}
 
 }
+
+// ExportAmbulance - Provides snapshot of the ambulance document
+func (this *implAmbulancesAPI) ExportAmbulance(ctx *gin.Context) {
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		_, span := tracer.Start(c.Request.Context(), "ExportAmbulance")
+		defer span.End()
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ambulance.Id+".json"))
+		// the version of the snapshot is the precondition of the later overwriting import or patch
+		c.Header("ETag", versionETag(ambulance.Version))
+		// return nil ambulance - no need to update it in db
+		return nil, ambulance, http.StatusOK
+	})
+}
+
+// ImportAmbulance - Restores the ambulance document from the snapshot
+func (this *implAmbulancesAPI) ImportAmbulance(ctx *gin.Context) {
+	spanctx, span := tracer.Start(ctx.Request.Context(), "ImportAmbulance")
+	defer span.End()
+
+	value, exists := ctx.Get("db_service")
+	if !exists {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service not found",
+				"error":   "db_service not found",
+			})
+		return
+	}
+
+	db, ok := value.(db_service.DbService[Ambulance])
+	if !ok {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service context is not of type db_service.DbService",
+				"error":   "cannot cast db_service context to db_service.DbService",
+			})
+		return
+	}
+
+	ambulance := Ambulance{}
+	if err := bindJSON(ctx, &ambulance); err != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			invalidBodyResponse(ctx, "Bad Request", err))
+		return
+	}
+
+	ambulanceId := ctx.Param("ambulanceId")
+	if !isValidId(ambulanceId) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "ambulanceId", ambulanceId))
+		return
+	}
+	if ambulance.Id == "" {
+		ambulance.Id = ambulanceId
+	}
+	if ambulance.Id != ambulanceId {
+		ctx.JSON(
+			http.StatusUnprocessableEntity,
+			gin.H{
+				"status":  "Unprocessable Entity",
+				"message": "Snapshot belongs to other ambulance",
+				"error":   "snapshot id " + ambulance.Id + " does not match " + ambulanceId,
+			})
+		return
+	}
+
+	if err := ambulance.validateTimeZone(); err != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "Invalid time zone of the ambulance",
+				"error":   err.Error(),
+			})
+		return
+	}
+
+	if problems := validateOfficeHours(ambulance.OfficeHours); len(problems) > 0 {
+		ctx.JSON(http.StatusBadRequest, invalidOfficeHoursResponse("Bad Request", problems))
+		return
+	}
+
+	if err := ambulance.validateWaitingList(); err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, errWaitingListConflict) {
+			status = http.StatusConflict
+		}
+		ctx.JSON(
+			status,
+			gin.H{
+				"status":  status,
+				"message": "Snapshot has invalid waiting list",
+				"error":   err.Error(),
+			})
+		return
+	}
+	ambulance.reconcileWaitingList(spanctx)
+
+	// the version is maintained by the service, the version of the snapshot would make the stale ETags valid again
+	ambulance.Version = 0
+	overwrite, _ := strconv.ParseBool(ctx.Query("overwrite"))
+	ifMatch := ctx.GetHeader("If-Match")
+	if overwrite && ifMatch == "" {
+		ctx.JSON(
+			http.StatusPreconditionRequired,
+			gin.H{
+				"status":  "Precondition Required",
+				"message": "Overwriting import requires the If-Match header with the ETag of the replaced ambulance",
+			})
+		return
+	}
+
+	var err error
+	writes.flush(ambulanceId)
+	if overwrite {
+		var stored *Ambulance
+		stored, err = db.FindDocument(db_service.ReadForUpdate(spanctx), ambulanceId)
+		if err == nil && !ifMatchSatisfied(ifMatch, stored.Version) {
+			ctx.Header("ETag", versionETag(stored.Version))
+			ctx.JSON(http.StatusPreconditionFailed, changedAmbulanceResponse(ctx, http.StatusPreconditionFailed, nil))
+			return
+		}
+		if err == nil {
+			// the snapshot replaces the version of the ambulance the client has seen
+			ambulance.Version = stored.Version
+			err = storeAmbulance(spanctx, db, ambulanceId, &ambulance)
+		}
+	}
+	if !overwrite || err == db_service.ErrNotFound {
+		err = db.CreateDocument(spanctx, ambulanceId, &ambulance)
+	}
+
+	switch err {
+	case nil:
+		ctx.Header("ETag", versionETag(ambulance.Version))
+		ctx.JSON(http.StatusOK, ambulance)
+	case db_service.ErrPreconditionFailed:
+		ctx.JSON(http.StatusConflict, changedAmbulanceResponse(ctx, http.StatusConflict, err))
+	case db_service.ErrConflict:
+		ctx.JSON(
+			http.StatusConflict,
+			gin.H{
+				"status":  "Conflict",
+				"code":    msgAmbulanceConflict,
+				"message": localize(ctx, msgAmbulanceConflict),
+				"error":   err.Error(),
+			},
+		)
+	default:
+		ctx.JSON(
+			http.StatusBadGateway,
+			gin.H{
+				"status":  "Bad Gateway",
+				"message": "Failed to store ambulance in database",
+				"error":   err.Error(),
+			})
+	}
+}
+
+// PatchAmbulance - Applies JSON Patch to specific ambulance
+func (this *implAmbulancesAPI) PatchAmbulance(ctx *gin.Context) {
+	if ctx.ContentType() != "application/json-patch+json" {
+		ctx.JSON(
+			http.StatusUnsupportedMediaType,
+			gin.H{
+				"status":  "Unsupported Media Type",
+				"message": "Request body must be of application/json-patch+json type",
+				"error":   "unsupported content type " + ctx.ContentType(),
+			})
+		return
+	}
+
+	updateAmbulanceFunc(ctx, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		spanctx, span := tracer.Start(c.Request.Context(), "PatchAmbulance")
+		defer span.End()
+
+		// the patch is based on the version of the ambulance the client has seen, if provided
+		if !ifMatchSatisfied(c.GetHeader("If-Match"), ambulance.Version) {
+			c.Header("ETag", versionETag(ambulance.Version))
+			return nil, changedAmbulanceResponse(c, http.StatusPreconditionFailed, nil), http.StatusPreconditionFailed
+		}
+
+		var patch []JsonPatchOperation
+		err := bindJSON(c, &patch)
+		if err == nil {
+			err = validateJsonPatch(patch)
+		}
+		if err != nil {
+			return nil, gin.H{
+				"status":  http.StatusBadRequest,
+				"message": "Invalid JSON Patch document",
+				"error":   err.Error(),
+			}, http.StatusBadRequest
+		}
+
+		document, err := json.Marshal(ambulance)
+		if err == nil {
+			document, err = applyJsonPatch(document, patch)
+		}
+		patched := &Ambulance{}
+		if err == nil {
+			// operations adding unknown properties are rejected as the unknown fields of the request
+			err = decodeJSON(bytes.NewReader(document), patched)
+		}
+		if err != nil {
+			return nil, gin.H{
+				"status":  http.StatusUnprocessableEntity,
+				"message": "Patch cannot be applied to the ambulance",
+				"error":   err.Error(),
+			}, http.StatusUnprocessableEntity
+		}
+
+		if err := patched.validateTimeZone(); err != nil {
+			return nil, gin.H{
+				"status":  http.StatusUnprocessableEntity,
+				"message": "Invalid time zone of the ambulance",
+				"error":   err.Error(),
+			}, http.StatusUnprocessableEntity
+		}
+
+		if problems := validateOfficeHours(patched.OfficeHours); len(problems) > 0 {
+			return nil, invalidOfficeHoursResponse(http.StatusUnprocessableEntity, problems), http.StatusUnprocessableEntity
+		}
+
+		if patched.Id != ambulance.Id {
+			return nil, gin.H{
+				"status":  http.StatusUnprocessableEntity,
+				"message": "Ambulance ID cannot be changed",
+			}, http.StatusUnprocessableEntity
+		}
+
+		if err := patched.validateWaitingList(); err != nil {
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, errWaitingListConflict) {
+				status = http.StatusConflict
+			}
+			return nil, gin.H{
+				"status":  status,
+				"message": "Patched ambulance has invalid waiting list",
+				"error":   err.Error(),
+			}, status
+		}
+
+		patched.reconcileWaitingList(spanctx)
+		return patched, patched, http.StatusOK
+	})
+}
+
+// UpdateAmbulanceMetadata - Updates the ambulance properties without touching the waiting list
+func (this *implAmbulancesAPI) UpdateAmbulanceMetadata(ctx *gin.Context) {
+	// the response is read back after the update, it must include the update
+	spanctx, span := tracer.Start(db_service.ReadForUpdate(ctx.Request.Context()), "UpdateAmbulanceMetadata")
+	defer span.End()
+
+	value, exists := ctx.Get("db_service")
+	if !exists {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service not found",
+				"error":   "db_service not found",
+			})
+		return
+	}
+
+	db, ok := value.(db_service.DbService[Ambulance])
+	if !ok {
+		ctx.JSON(
+			http.StatusInternalServerError,
+			gin.H{
+				"status":  "Internal Server Error",
+				"message": "db_service context is not of type db_service.DbService",
+				"error":   "cannot cast db_service context to db_service.DbService",
+			})
+		return
+	}
+
+	metadata := AmbulanceMetadata{}
+	if err := bindJSON(ctx, &metadata); err != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			invalidBodyResponse(ctx, "Bad Request", err))
+		return
+	}
+
+	// all problems are reported at once, before anything is stored
+	if problems := metadata.validate(); len(problems) > 0 {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":   "Bad Request",
+				"message":  "Invalid metadata of the ambulance",
+				"problems": problems,
+			})
+		return
+	}
+
+	fields := metadata.storedFields()
+	if len(fields) == 0 {
+		ctx.JSON(
+			http.StatusBadRequest,
+			gin.H{
+				"status":  "Bad Request",
+				"message": "No ambulance property to change",
+			})
+		return
+	}
+
+	ambulanceId := ctx.Param("ambulanceId")
+	if !isValidId(ambulanceId) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "ambulanceId", ambulanceId))
+		return
+	}
+	var err error
+	if isDryRun(ctx) {
+		ctx.Header(dryRunHeader, "true")
+	} else {
+		// buffered changes are stored first, otherwise they would overwrite the updated metadata
+		writes.flush(ambulanceId)
+		// the new version rejects the concurrent writes of the whole ambulance read before this update
+		err = db.UpdateFields(spanctx, ambulanceId, fields, bson.M{"version": 1})
+	}
+
+	var ambulance *Ambulance
+	if err == nil {
+		// respond with the stored ambulance, including the waiting list changed meanwhile
+		ambulance, err = db.FindDocument(spanctx, ambulanceId)
+	}
+
+	switch {
+	case err == nil:
+		if isDryRun(ctx) {
+			metadata.applyTo(ambulance)
+		}
+		ctx.Header("ETag", versionETag(ambulance.Version))
+		ctx.JSON(http.StatusOK, ambulance)
+	case err == db_service.ErrNotFound:
+		ctx.JSON(
+			http.StatusNotFound,
+			gin.H{
+				"status":  "Not Found",
+				"code":    msgAmbulanceNotFound,
+				"message": localize(ctx, msgAmbulanceNotFound),
+				"error":   err.Error(),
+			},
+		)
+	case db_service.IsUnavailable(err):
+		respondDatabaseUnavailable(ctx, err)
+	default:
+		ctx.JSON(
+			http.StatusBadGateway,
+			gin.H{
+				"status":  "Bad Gateway",
+				"message": "Failed to update ambulance in database",
+				"error":   err.Error(),
+			})
+	}
+}
@@ -0,0 +1,800 @@
+package ambulance_wl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AmbulancesSuite struct {
+	suite.Suite
+	dbServiceMock *DbServiceMock[Ambulance]
+	// patient registry of the requests, not configured if nil
+	registryMock *DbServiceMock[PatientRegistration]
+}
+
+func TestAmbulancesSuite(t *testing.T) {
+	suite.Run(t, new(AmbulancesSuite))
+}
+
+func (suite *AmbulancesSuite) SetupTest() {
+	suite.dbServiceMock = &DbServiceMock[Ambulance]{}
+	suite.registryMock = nil
+
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(
+			&Ambulance{
+				Id:   "test-ambulance",
+				Name: "Test Ambulance",
+				WaitingList: []WaitingListEntry{
+					{
+						Id:                       "test-entry",
+						PatientId:                "test-patient",
+						WaitingSince:             time.Now(),
+						EstimatedDurationMinutes: 101,
+					},
+				},
+			},
+			nil,
+		)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+}
+
+func (suite *AmbulancesSuite) patchAmbulance(patch string) *httptest.ResponseRecorder {
+	return suite.patchAmbulanceIfMatch(patch, "")
+}
+
+// patchAmbulanceIfMatch applies the patch to the ambulance of the version given by the If-Match header, if not empty
+func (suite *AmbulancesSuite) patchAmbulanceIfMatch(patch string, ifMatch string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service", suite.dbServiceMock)
+	if suite.registryMock != nil {
+		ctx.Set("patient_registry", suite.registryMock)
+	}
+	ctx.Params = []gin.Param{
+		{Key: "ambulanceId", Value: "test-ambulance"},
+	}
+	ctx.Request = httptest.NewRequest("PATCH", "/ambulance/test-ambulance", strings.NewReader(patch))
+	ctx.Request.Header.Set("Content-Type", "application/json-patch+json")
+	if ifMatch != "" {
+		ctx.Request.Header.Set("If-Match", ifMatch)
+	}
+
+	sut := implAmbulancesAPI{}
+	sut.PatchAmbulance(ctx)
+	return recorder
+}
+
+func (suite *AmbulancesSuite) updatedAmbulance() *Ambulance {
+	for _, call := range suite.dbServiceMock.Calls {
+		if call.Method == "UpdateDocumentIf" {
+			return call.Arguments.Get(2).(*Ambulance)
+		}
+	}
+	suite.FailNow("UpdateDocumentIf was not called")
+	return nil
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_Replace() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "replace", "path": "/name", "value": "Renamed Ambulance"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	updated := suite.updatedAmbulance()
+	suite.Equal("Renamed Ambulance", updated.Name)
+	suite.Len(updated.WaitingList, 1)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_StoredConditionallyOnReadVersion() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(&Ambulance{Id: "test-ambulance", Name: "Test Ambulance", Version: 7}, nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	// ACT
+	recorder := suite.patchAmbulanceIfMatch(`[{"op": "replace", "path": "/name", "value": "Renamed Ambulance"}]`, `"7"`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(`"8"`, recorder.Header().Get("ETag"))
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool { return ambulance.Version == 8 }),
+		bson.M{"version": int64(7)})
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_CoalescedWrites_TaggedByStoredVersion() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.WriteCoalesceWindow = time.Hour
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(&Ambulance{Id: "test-ambulance", Name: "Test Ambulance", Version: 7}, nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	// ACT
+	results := make([]*httptest.ResponseRecorder, 2)
+	waits := make([]<-chan struct{}, 2)
+	for i, name := range []string{"First Rename", "Second Rename"} {
+		done := make(chan struct{})
+		waits[i] = done
+		go func(i int, name string) {
+			defer close(done)
+			results[i] = suite.patchAmbulance(fmt.Sprintf(`[{"op": "replace", "path": "/name", "value": %q}]`, name))
+		}(i, name)
+		suite.Eventually(func() bool {
+			buffered := writes.snapshot("test-ambulance")
+			return buffered != nil && buffered.Name == name
+		}, time.Second, time.Millisecond)
+	}
+	FlushPendingWrites()
+	for _, wait := range waits {
+		<-wait
+	}
+
+	// ASSERT
+	// both changes are stored by the single write
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+	for _, recorder := range results {
+		suite.Equal(http.StatusOK, recorder.Code)
+		suite.Equal(`"8"`, recorder.Header().Get("ETag"))
+		var ambulance Ambulance
+		suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &ambulance))
+		suite.Equal(int64(8), ambulance.Version)
+	}
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_StaleIfMatch_PreconditionFailed() {
+	// ACT
+	recorder := suite.patchAmbulanceIfMatch(`[{"op": "replace", "path": "/name", "value": "Renamed Ambulance"}]`, `"3"`)
+
+	// ASSERT
+	suite.Equal(http.StatusPreconditionFailed, recorder.Code)
+	suite.Equal(`"0"`, recorder.Header().Get("ETag"))
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_ChangedConcurrently_Conflict() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = suite.dbServiceMock.ExpectedCalls[:1]
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrPreconditionFailed)
+
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "replace", "path": "/name", "value": "Renamed Ambulance"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), msgAmbulanceChanged)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_UnknownProperty_RejectedInStrictMode() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.StrictJson = true
+
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "add", "path": "/nickname", "value": "Dr. Test"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.Contains(recorder.Body.String(), "nickname")
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_AddEntry() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{
+		"op": "add",
+		"path": "/waitingList/-",
+		"value": {
+			"id": "second-entry",
+			"patientId": "second-patient",
+			"waitingSince": "2038-12-24T10:05:00Z",
+			"estimatedDurationMinutes": 15
+		}
+	}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	updated := suite.updatedAmbulance()
+	suite.Len(updated.WaitingList, 2)
+
+	var response Ambulance
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Len(response.WaitingList, 2)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_RemoveEntry() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "remove", "path": "/waitingList/0"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Empty(suite.updatedAmbulance().WaitingList)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_AddEntry_RegistersPatient() {
+	// ARRANGE
+	suite.registryMock = &DbServiceMock[PatientRegistration]{}
+	suite.registryMock.
+		On("CreateDocument", mock.Anything, "test-ambulance/second-patient", mock.Anything).
+		Return(nil)
+
+	// ACT
+	recorder := suite.patchAmbulance(`[{
+		"op": "add",
+		"path": "/waitingList/-",
+		"value": {"id": "second-entry", "patientId": "second-patient", "estimatedDurationMinutes": 15}
+	}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.registryMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, "test-ambulance/second-patient",
+		mock.MatchedBy(func(registration *PatientRegistration) bool { return registration.EntryId == "second-entry" }))
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_AddRegisteredPatient_Conflict() {
+	// ARRANGE
+	suite.registryMock = &DbServiceMock[PatientRegistration]{}
+	suite.registryMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrConflict)
+	suite.registryMock.
+		On("FindDocument", mock.Anything, "test-ambulance/second-patient").
+		Return(&PatientRegistration{EntryId: "concurrent-entry", RegisteredAt: time.Now()}, nil)
+
+	// ACT
+	recorder := suite.patchAmbulance(`[{
+		"op": "add",
+		"path": "/waitingList/-",
+		"value": {"id": "second-entry", "patientId": "second-patient", "estimatedDurationMinutes": 15}
+	}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_RemoveEntry_ReleasesPatient() {
+	// ARRANGE
+	suite.registryMock = &DbServiceMock[PatientRegistration]{}
+	suite.registryMock.
+		On("DeleteDocument", mock.Anything, "test-ambulance/test-patient").
+		Return(nil)
+
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "remove", "path": "/waitingList/0"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.registryMock.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/test-patient")
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_InvalidPathFails() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "remove", "path": "/waitingList/5"}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_MissingValue_BadRequest() {
+	for _, operation := range []string{"add", "replace", "test"} {
+		// ACT
+		recorder := suite.patchAmbulance(fmt.Sprintf(`[{"op": %q, "path": "/name"}]`, operation))
+
+		// ASSERT
+		suite.Equal(http.StatusBadRequest, recorder.Code, operation)
+		suite.Contains(recorder.Body.String(), "value is required", operation)
+	}
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_NullValueApplied() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{"op": "replace", "path": "/name", "value": null},
+		{"op": "test", "path": "/name", "value": null}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Empty(suite.updatedAmbulance().Name)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_DuplicatePatientConflicts() {
+	// ACT
+	recorder := suite.patchAmbulance(`[{
+		"op": "add",
+		"path": "/waitingList/-",
+		"value": {
+			"id": "second-entry",
+			"patientId": "test-patient",
+			"waitingSince": "2038-12-24T10:05:00Z",
+			"estimatedDurationMinutes": 15
+		}
+	}]`)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_PatchAmbulance_UnsupportedContentType() {
+	// ARRANGE
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
+	ctx.Request = httptest.NewRequest("PATCH", "/ambulance/test-ambulance", strings.NewReader(`{"name": "x"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	// ACT
+	sut := implAmbulancesAPI{}
+	sut.PatchAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusUnsupportedMediaType, recorder.Code)
+}
+
+func (suite *AmbulancesSuite) newRequestContext(method string, url string, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
+	ctx.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+	return ctx, recorder
+}
+
+func (suite *AmbulancesSuite) Test_ExportImport_RoundTrip() {
+	// ARRANGE
+	waitingSince, _ := time.Parse(time.RFC3339, "2038-12-24T10:05:00Z")
+	exported := &Ambulance{
+		Id:                 "test-ambulance",
+		Name:               "Test Ambulance",
+		RoomNumber:         "101",
+		MaxWaitingListSize: 10,
+		WaitingList: []WaitingListEntry{
+			{Id: "first", PatientId: "p1", WaitingSince: waitingSince, EstimatedDurationMinutes: 15, Room: "a"},
+			{Id: "second", PatientId: "p2", WaitingSince: waitingSince.Add(time.Minute), EstimatedDurationMinutes: 20},
+		},
+		PredefinedConditions: []Condition{{Code: "followup", Value: "Kontrola"}},
+	}
+	exported.reconcileWaitingList(context.Background())
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(exported, nil)
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	exportCtx, exportRecorder := suite.newRequestContext("GET", "/ambulance/test-ambulance/export", "")
+	sut.ExportAmbulance(exportCtx)
+	snapshot := exportRecorder.Body.String()
+
+	importCtx, importRecorder := suite.newRequestContext("POST", "/ambulance/test-ambulance/import", snapshot)
+	sut.ImportAmbulance(importCtx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, exportRecorder.Code)
+	suite.Equal(`attachment; filename="test-ambulance.json"`, exportRecorder.Header().Get("Content-Disposition"))
+	suite.Equal(http.StatusOK, importRecorder.Code)
+
+	var stored *Ambulance
+	for _, call := range suite.dbServiceMock.Calls {
+		if call.Method == "CreateDocument" {
+			stored = call.Arguments.Get(2).(*Ambulance)
+		}
+	}
+	suite.Require().NotNil(stored)
+	storedJson, err := json.Marshal(stored)
+	suite.NoError(err)
+	suite.JSONEq(snapshot, string(storedJson))
+}
+
+func (suite *AmbulancesSuite) Test_Import_ExistingAmbulanceRequiresOverwrite() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrConflict)
+	sut := implAmbulancesAPI{}
+	snapshot := `{"id": "test-ambulance", "name": "Imported", "roomNumber": "1"}`
+
+	// ACT
+	conflictCtx, conflictRecorder := suite.newRequestContext("POST", "/ambulance/test-ambulance/import", snapshot)
+	sut.ImportAmbulance(conflictCtx)
+	overwriteCtx, overwriteRecorder := suite.newRequestContext(
+		"POST", "/ambulance/test-ambulance/import?overwrite=true", snapshot)
+	overwriteCtx.Request.Header.Set("If-Match", `"0"`)
+	sut.ImportAmbulance(overwriteCtx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, conflictRecorder.Code)
+	suite.Equal(http.StatusOK, overwriteRecorder.Code)
+	suite.Equal("Imported", suite.updatedAmbulance().Name)
+}
+
+func (suite *AmbulancesSuite) Test_Import_OverwriteRequiresMatchingIfMatch() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(&Ambulance{Id: "test-ambulance", Name: "Stored", Version: 5}, nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	sut := implAmbulancesAPI{}
+	// the snapshot of the older version must not move the version backwards
+	snapshot := `{"id": "test-ambulance", "name": "Imported", "roomNumber": "1", "version": 2}`
+
+	// ACT
+	missingCtx, missingRecorder := suite.newRequestContext("POST", "/ambulance/test-ambulance/import?overwrite=true", snapshot)
+	sut.ImportAmbulance(missingCtx)
+	staleCtx, staleRecorder := suite.newRequestContext("POST", "/ambulance/test-ambulance/import?overwrite=true", snapshot)
+	staleCtx.Request.Header.Set("If-Match", `"2"`)
+	sut.ImportAmbulance(staleCtx)
+	matchingCtx, matchingRecorder := suite.newRequestContext("POST", "/ambulance/test-ambulance/import?overwrite=true", snapshot)
+	matchingCtx.Request.Header.Set("If-Match", `"5"`)
+	sut.ImportAmbulance(matchingCtx)
+
+	// ASSERT
+	suite.Equal(http.StatusPreconditionRequired, missingRecorder.Code)
+	suite.Equal(http.StatusPreconditionFailed, staleRecorder.Code)
+	suite.Equal(`"5"`, staleRecorder.Header().Get("ETag"))
+	suite.Equal(http.StatusOK, matchingRecorder.Code)
+	suite.Equal(`"6"`, matchingRecorder.Header().Get("ETag"))
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "UpdateDocumentIf", 1)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool { return ambulance.Version == 6 && ambulance.Name == "Imported" }),
+		versionCondition(5))
+}
+
+func (suite *AmbulancesSuite) Test_Import_SnapshotOfOtherAmbulanceRejected() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/ambulance/test-ambulance/import", `{"id": "other-ambulance", "name": "Other", "roomNumber": "1"}`)
+
+	// ACT
+	sut := implAmbulancesAPI{}
+	sut.ImportAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_UpdateMetadata_KeepsWaitingList() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("UpdateFields", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"PATCH", "/ambulance/test-ambulance/metadata", `{"name": "Renamed Ambulance", "maxWaitingListSize": 0}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.UpdateAmbulanceMetadata(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateFields", mock.Anything, "test-ambulance", bson.M{
+		"name":               "Renamed Ambulance",
+		"maxwaitinglistsize": int32(0),
+	}, bson.M{"version": 1})
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ambulance := Ambulance{}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &ambulance))
+	suite.Len(ambulance.WaitingList, 1)
+}
+
+func (suite *AmbulancesSuite) Test_UpdateMetadata_EmptyBodyRejected() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("PATCH", "/ambulance/test-ambulance/metadata", `{}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.UpdateAmbulanceMetadata(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_CreateAmbulance_DbCallUnderHandlerSpan() {
+	// ARRANGE
+	recorder := tracetest.NewSpanRecorder()
+	defer func(previous trace.Tracer) { tracer = previous }(tracer)
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, "new-ambulance", mock.Anything).
+		Return(nil)
+	ctx, _ := suite.newRequestContext("POST", "/ambulance", `{"id": "new-ambulance", "name": "New", "roomNumber": "1"}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.CreateAmbulance(ctx)
+
+	// ASSERT
+	spans := recorder.Ended()
+	suite.Require().Len(spans, 1)
+	suite.Equal("CreateAmbulance", spans[0].Name())
+	for _, call := range suite.dbServiceMock.Calls {
+		if call.Method == "CreateDocument" {
+			dbctx := call.Arguments.Get(0).(context.Context)
+			suite.Equal(spans[0].SpanContext(), trace.SpanContextFromContext(dbctx))
+			return
+		}
+	}
+	suite.Fail("CreateDocument was not called")
+}
+
+func (suite *AmbulancesSuite) Test_CreateAmbulance_ExistingIdConflicts() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, "test-ambulance", mock.Anything).
+		Return(db_service.ErrConflict)
+	ctx, recorder := suite.newRequestContext("POST", "/ambulance", `{"id": "test-ambulance", "name": "Test", "roomNumber": "1"}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.CreateAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpsertDocument", mock.Anything, mock.Anything, mock.Anything)
+	// the id supplied by the client is not replaced
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "CreateDocument", 1)
+}
+
+func (suite *AmbulancesSuite) Test_DeleteAmbulance_Missing_NotFoundByDefault() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.IdempotentDelete = false
+	suite.dbServiceMock.
+		On("DeleteDocument", mock.Anything, "test-ambulance").
+		Return(db_service.ErrNotFound)
+	ctx, recorder := suite.newRequestContext("DELETE", "/ambulance/test-ambulance", "")
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.DeleteAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func (suite *AmbulancesSuite) Test_DeleteAmbulance_Missing_NoContentInIdempotentMode() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.IdempotentDelete = true
+	suite.dbServiceMock.
+		On("DeleteDocument", mock.Anything, "test-ambulance").
+		Return(db_service.ErrNotFound)
+	ctx, recorder := suite.newRequestContext("DELETE", "/ambulance/test-ambulance", "")
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.DeleteAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusNoContent, ctx.Writer.Status())
+	suite.Empty(recorder.Body.String())
+}
+
+func (suite *AmbulancesSuite) Test_DeleteAmbulance_FailureNotHiddenInIdempotentMode() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.IdempotentDelete = true
+	suite.dbServiceMock.
+		On("DeleteDocument", mock.Anything, "test-ambulance").
+		Return(errors.New("connection refused"))
+	ctx, recorder := suite.newRequestContext("DELETE", "/ambulance/test-ambulance", "")
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.DeleteAmbulance(ctx)
+
+	// ASSERT
+	suite.NotEqual(http.StatusNoContent, recorder.Code)
+}
+
+func (suite *AmbulancesSuite) Test_DeleteAmbulance_ReleasesPatients() {
+	// ARRANGE
+	registry := &DbServiceMock[PatientRegistration]{}
+	registry.
+		On("DeleteDocument", mock.Anything, "test-ambulance/test-patient").
+		Return(nil)
+	suite.dbServiceMock.
+		On("DeleteDocument", mock.Anything, "test-ambulance").
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("DELETE", "/ambulance/test-ambulance", "")
+	ctx.Set("patient_registry", registry)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.DeleteAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusNoContent, ctx.Writer.Status())
+	suite.Empty(recorder.Body.String())
+	registry.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "test-ambulance/test-patient")
+}
+
+func (suite *AmbulancesSuite) Test_CreateAmbulance_GeneratedIdCollision_Retried() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(db_service.ErrConflict).Once()
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("POST", "/ambulance", `{"name": "Generated", "roomNumber": "1"}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.CreateAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "CreateDocument", 2)
+	ambulance := Ambulance{}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &ambulance))
+	suite.Equal(suite.dbServiceMock.Calls[1].Arguments.String(1), ambulance.Id)
+	suite.NotEqual(suite.dbServiceMock.Calls[0].Arguments.String(1), ambulance.Id)
+}
+
+func (suite *AmbulancesSuite) Test_CreateAmbulance_UpsertReplacesExisting() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("UpsertDocument", mock.Anything, "test-ambulance", mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext(
+		"POST", "/ambulance?upsert=true", `{"id": "test-ambulance", "name": "Reimported", "roomNumber": "1"}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.CreateAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpsertDocument", mock.Anything, "test-ambulance",
+		mock.MatchedBy(func(ambulance *Ambulance) bool { return ambulance.Name == "Reimported" }))
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_CreateAmbulance_OverlappingOfficeHoursRejected() {
+	// ARRANGE
+	body := `{"id": "new-ambulance", "name": "New", "roomNumber": "1", "officeHours": [
+		{"weekday": "monday", "open": "07:30", "close": "12:00"},
+		{"weekday": "tuesday", "open": "07:30", "close": "12:00"},
+		{"weekday": "monday", "open": "11:00", "close": "15:00"},
+		{"weekday": "caturday", "open": "08:00", "close": "10:00"}
+	]}`
+	ctx, recorder := suite.newRequestContext("POST", "/ambulance", body)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.CreateAmbulance(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{
+		"status": "Bad Request",
+		"message": "Invalid office hours of the ambulance",
+		"problems": [
+			"officeHours[3]: unknown weekday \"caturday\"",
+			"officeHours[2]: overlaps officeHours[0] on monday"
+		]
+	}`, recorder.Body.String())
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_UpdateMetadata_ReversedOfficeHoursRejected() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("PATCH", "/ambulance/test-ambulance/metadata", `{"officeHours": [
+		{"weekday": "friday", "open": "15:00", "close": "08:00"},
+		{"weekday": "friday", "open": "9:00", "close": "noon"}
+	]}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.UpdateAmbulanceMetadata(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.JSONEq(`{
+		"status": "Bad Request",
+		"message": "Invalid metadata of the ambulance",
+		"problems": [
+			"officeHours[0]: close 08:00 is not after open 15:00",
+			"officeHours[1]: close \"noon\" is not in the format HH:MM"
+		]
+	}`, recorder.Body.String())
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_UpdateMetadata_AllInvalidFieldsReported() {
+	// ARRANGE
+	ctx, recorder := suite.newRequestContext("PATCH", "/ambulance/test-ambulance/metadata", `{
+		"timeZone": "Europe/Atlantis",
+		"officeHours": [
+			{"weekday": "monday", "open": "08:00", "close": "12:00"},
+			{"weekday": "monday", "open": "11:00", "close": "15:00"}
+		],
+		"predefinedConditions": [
+			{"value": "Kontrola", "typicalDurationMinutes": 10},
+			{"value": "Teploty", "typicalDurationMinutes": -5}
+		]
+	}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.UpdateAmbulanceMetadata(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	var response struct {
+		Problems []string `json:"problems"`
+	}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Problems, 3)
+	suite.True(strings.HasPrefix(response.Problems[0], "timeZone: "))
+	suite.True(strings.HasPrefix(response.Problems[1], "officeHours[1]: "))
+	suite.Equal("predefinedConditions[1].typicalDurationMinutes: -5 is negative", response.Problems[2])
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AmbulancesSuite) Test_UpdateMetadata_OfficeHoursStored() {
+	// ARRANGE
+	suite.dbServiceMock.
+		On("UpdateFields", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).
+		Return(nil)
+	ctx, recorder := suite.newRequestContext("PATCH", "/ambulance/test-ambulance/metadata", `{"officeHours": [
+		{"weekday": "monday", "open": "07:30", "close": "12:00"},
+		{"weekday": "monday", "open": "12:00", "close": "15:00"}
+	]}`)
+	sut := implAmbulancesAPI{}
+
+	// ACT
+	sut.UpdateAmbulanceMetadata(ctx)
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateFields", mock.Anything, "test-ambulance", bson.M{
+		"officehours": []OfficeHours{
+			{Weekday: "monday", Open: "07:30", Close: "12:00"},
+			{Weekday: "monday", Open: "12:00", Close: "15:00"},
+		},
+	}, bson.M{"version": 1})
+}
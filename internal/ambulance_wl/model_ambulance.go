@@ -23,4 +23,28 @@ type Ambulance struct {
 	WaitingList []WaitingListEntry `json:"waitingList,omitempty"`
 
 	PredefinedConditions []Condition `json:"predefinedConditions,omitempty"`
+
+	// Maximum number of active (waiting or in examination) entries in the waiting list, new entries are rejected when the limit is reached. Zero means unlimited.
+	MaxWaitingListSize int32 `json:"maxWaitingListSize,omitempty"`
+
+	// Number of patients examined at the same time in each room of the ambulance, estimated start times are computed for this many parallel examinations. Zero or one means the patients are examined one by one.
+	ConcurrentSlots int32 `json:"concurrentSlots,omitempty"`
+
+	// Accept new entries with the waitingSince in the future, e.g. appointments booked in advance, and schedule them from that time. If not set, the waitingSince of new entries is the time of their creation.
+	AllowFutureWaitingSince bool `json:"allowFutureWaitingSince,omitempty"`
+
+	// Policy used to order the waiting patients - fifo serves them in the order of their arrival, shortest-job-first serves the shortest estimated examinations first, priority serves the emergencies first and then the highest priority, which grows with the waiting time. Empty value means fifo.
+	ReconcileStrategy string `json:"reconcileStrategy,omitempty"`
+
+	// IANA name of the time zone of the ambulance, e.g. Europe/Bratislava. The schedule is computed in this zone, the timestamps are provided in UTC. Empty value means UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Intervals of the week when the ambulance accepts patients, in the time zone of the ambulance. Intervals of the same day must not overlap.
+	OfficeHours []OfficeHours `json:"officeHours,omitempty"`
+
+	// Entries recently removed from the waiting list, kept for the synchronization of the clients, see getWaitingListChanges
+	RemovedEntries []RemovedWaitingListEntry `json:"removedEntries,omitempty"`
+
+	// Number of the stored changes of the ambulance, provided also as the ETag of the ambulance. Ignored on input.
+	Version int64 `json:"version,omitempty"`
 }
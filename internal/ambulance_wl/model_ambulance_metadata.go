@@ -0,0 +1,34 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// AmbulanceMetadata - Properties of the ambulance changeable independently of its waiting list, properties not present are left unchanged
+type AmbulanceMetadata struct {
+
+	// Human readable display name of the ambulance
+	Name *string `json:"name,omitempty"`
+
+	RoomNumber *string `json:"roomNumber,omitempty"`
+
+	PredefinedConditions *[]Condition `json:"predefinedConditions,omitempty"`
+
+	// Maximum number of active entries in the waiting list, zero means unlimited
+	MaxWaitingListSize *int32 `json:"maxWaitingListSize,omitempty"`
+
+	// Accept new entries with the waitingSince in the future
+	AllowFutureWaitingSince *bool `json:"allowFutureWaitingSince,omitempty"`
+
+	// IANA name of the time zone of the ambulance, e.g. Europe/Bratislava
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// Intervals of the week when the ambulance accepts patients
+	OfficeHours *[]OfficeHours `json:"officeHours,omitempty"`
+}
@@ -0,0 +1,37 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// AuditEntry - Record of the single change of the waiting list
+type AuditEntry struct {
+
+	// Unique id of the record
+	Id string `json:"id"`
+
+	// Id of the ambulance whose waiting list was changed
+	AmbulanceId string `json:"ambulanceId"`
+
+	// Id of the changed waiting list entry
+	EntryId string `json:"entryId"`
+
+	// Kind of the change - create, update, delete, or transfer
+	Action string `json:"action"`
+
+	// Identity of the user who made the change, anonymous if not known
+	Actor string `json:"actor"`
+
+	// Time of the change
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,32 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"encoding/json"
+)
+
+// JsonPatchOperation - Single operation of the RFC 6902 JSON Patch document
+type JsonPatchOperation struct {
+
+	// Operation to perform
+	Op string `json:"op"`
+
+	// JSON Pointer to the target location of the operation
+	Path string `json:"path"`
+
+	// JSON Pointer to the source location of the move and copy operations
+	From string `json:"from,omitempty"`
+
+	// Value to add, replace, or test, required by these operations. Kept raw so that the missing value
+	// is told apart from the null value.
+	Value json.RawMessage `json:"value,omitempty"`
+}
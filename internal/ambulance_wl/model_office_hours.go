@@ -0,0 +1,24 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// OfficeHours - Interval of the day when the ambulance accepts patients
+type OfficeHours struct {
+
+	// Day of the week, lowercase english name, e.g. monday
+	Weekday string `json:"weekday"`
+
+	// Opening time in the format HH:MM, in the time zone of the ambulance
+	Open string `json:"open"`
+
+	// Closing time in the format HH:MM, in the time zone of the ambulance, must be after the opening time
+	Close string `json:"close"`
+}
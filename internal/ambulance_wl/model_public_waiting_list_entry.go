@@ -0,0 +1,34 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// PublicWaitingListEntry - Waiting entry as shown to the patients in the waiting room
+type PublicWaitingListEntry struct {
+
+	// Masked identifier of the patient, only the last characters of the patient id are shown
+	Ticket string `json:"ticket"`
+
+	// Short number of the entry, unique within the day of the arrival
+	TicketNumber int32 `json:"ticketNumber,omitempty"`
+
+	// Examination room the entry is queued for
+	Room string `json:"room,omitempty"`
+
+	// 1-based position among the waiting entries of the room
+	Position int32 `json:"position"`
+
+	// Estimated time of entering ambulance
+	EstimatedStart time.Time `json:"estimatedStart"`
+}
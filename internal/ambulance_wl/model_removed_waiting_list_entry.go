@@ -0,0 +1,25 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// RemovedWaitingListEntry - Entry removed from the waiting list
+type RemovedWaitingListEntry struct {
+
+	// Id of the removed entry
+	Id string `json:"id"`
+
+	// Time the entry was removed from the waiting list
+	RemovedAt time.Time `json:"removedAt"`
+}
@@ -0,0 +1,46 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// ScheduledWaitingListEntry - Active entry of the waiting list with its place in the computed schedule
+type ScheduledWaitingListEntry struct {
+
+	// Unique id of the entry in this waiting list
+	Id string `json:"id"`
+
+	// Unique identifier of the patient known to Web-In-Cloud system
+	PatientId string `json:"patientId"`
+
+	// Name of patient in waiting list
+	Name string `json:"name,omitempty"`
+
+	// Examination room the entry is queued for, empty for the default queue
+	Room string `json:"room,omitempty"`
+
+	// State of the entry, waiting or in-examination
+	Status string `json:"status"`
+
+	// 1-based position among the waiting entries of the room, zero for the entries in examination
+	Position int32 `json:"position"`
+
+	// 1-based number of the concurrent slot of the room serving the entry
+	Slot int32 `json:"slot"`
+
+	// Estimated time of entering ambulance, counting only the office hours
+	EstimatedStart time.Time `json:"estimatedStart"`
+
+	// Estimated time the examination is done, the work left at the closing continues at the next opening
+	EstimatedEnd time.Time `json:"estimatedEnd"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// ThroughputBucket - Number of the entries completed within the time interval
+type ThroughputBucket struct {
+
+	// Start of the interval, inclusive
+	Start time.Time `json:"start"`
+
+	// End of the interval, exclusive
+	End time.Time `json:"end"`
+
+	// Number of the entries marked as done within the interval
+	Completed int32 `json:"completed"`
+
+	// Number of the completed entries per their source - walkin, phone, referral or online
+	BySource map[string]int32 `json:"bySource"`
+}
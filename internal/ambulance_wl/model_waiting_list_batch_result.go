@@ -0,0 +1,26 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListBatchResult - Result of the single entry of the batch request
+type WaitingListBatchResult struct {
+
+	// Index of the entry in the request
+	Index int32 `json:"index"`
+
+	// HTTP status of the entry, 201 if the entry was stored
+	Status int32 `json:"status"`
+
+	Entry *WaitingListEntry `json:"entry,omitempty"`
+
+	// Reason of the rejection of the entry
+	Message string `json:"message,omitempty"`
+}
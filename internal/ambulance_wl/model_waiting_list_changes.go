@@ -0,0 +1,30 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListChanges - Changes of the waiting list since the synchronization token of the client
+type WaitingListChanges struct {
+
+	// Entries added since the token, all entries if reset is set
+	Added []WaitingListEntry `json:"added"`
+
+	// Entries changed since the token, except the added ones. The changes made at the time of the token are provided again, the entries not known to the client shall be added.
+	Updated []WaitingListEntry `json:"updated"`
+
+	// Ids of the entries removed since the token
+	Removed []string `json:"removed"`
+
+	// Opaque token to be passed as the since parameter of the next synchronization
+	SyncToken string `json:"syncToken"`
+
+	// The token is missing or too old to provide the changes, the client shall replace its entries by the added ones
+	Reset bool `json:"reset"`
+}
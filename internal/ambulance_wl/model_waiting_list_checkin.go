@@ -0,0 +1,21 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListCheckin - Self check-in of the patient at the kiosk of the ambulance
+type WaitingListCheckin struct {
+
+	// Unique identifier of the patient known to Web-In-Cloud system
+	PatientId string `json:"patientId"`
+
+	// Code of one of the predefined conditions of the ambulance, the condition is not set if not provided
+	ConditionCode string `json:"conditionCode,omitempty"`
+}
@@ -0,0 +1,25 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// WaitingListDrainTime - Estimated time when the current waiting list is fully served
+type WaitingListDrainTime struct {
+
+	// Estimated time when the last waiting or examined entry is done, current time for the empty waiting list
+	DrainTime time.Time `json:"drainTime"`
+
+	// Number of the waiting and examined entries
+	ActiveEntries int32 `json:"activeEntries"`
+}
@@ -0,0 +1,21 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListEntriesByIds - Entries found by their ids
+type WaitingListEntriesByIds struct {
+
+	// Found entries in the order of the requested ids
+	Entries []WaitingListEntry `json:"entries"`
+
+	// Requested ids not present in the waiting list
+	NotFound []string `json:"notFound"`
+}
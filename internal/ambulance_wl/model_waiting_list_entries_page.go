@@ -0,0 +1,25 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListEntriesPage - Page of the waiting list entries with the pagination metadata
+type WaitingListEntriesPage struct {
+	Items []WaitingListEntry `json:"items"`
+
+	// Number of all entries matching the request
+	Total int32 `json:"total"`
+
+	// Requested maximal number of entries, 0 if not limited
+	Limit int32 `json:"limit"`
+
+	// Number of skipped entries
+	Offset int32 `json:"offset"`
+}
@@ -35,4 +35,31 @@ type WaitingListEntry struct {
 	EstimatedDurationMinutes int32 `json:"estimatedDurationMinutes"`
 
 	Condition Condition `json:"condition,omitempty"`
+
+	// State of the entry in the waiting list, waiting if not provided. Done and no-show entries are kept in the list but are not scheduled anymore.
+	Status string `json:"status,omitempty"`
+
+	// Examination room the entry is queued for. Entries of each room are scheduled independently, entries without room form the default queue.
+	Room string `json:"room,omitempty"`
+
+	// Short free-text note of the triage staff, e.g. needs wheelchair. Control characters are removed.
+	Note string `json:"note,omitempty"`
+
+	// How the patient arrived to the waiting list - walkin, phone, referral or online, walkin if not provided.
+	Source string `json:"source,omitempty"`
+
+	// Triage priority of the patient - routine, urgent or emergency, routine if not provided. Used by the priority reconcile strategy only.
+	Priority string `json:"priority,omitempty"`
+
+	// Short number of the entry shown on the public displays, numbers start from 1 every day. Ignored on post.
+	TicketNumber int32 `json:"ticketNumber,omitempty"`
+
+	// Time the entry was marked as done, provided only for done entries. Ignored on post.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	// Time the entry was added to the waiting list. Ignored on post.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	// Time of the last change of the entry, including the change of its estimated start. Ignored on post.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
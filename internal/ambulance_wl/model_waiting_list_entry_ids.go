@@ -0,0 +1,18 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListEntryIds - Ids of the requested waiting list entries
+type WaitingListEntryIds struct {
+
+	// Ids of the entries to provide
+	EntryIds []string `json:"entryIds"`
+}
@@ -0,0 +1,25 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+import (
+	"time"
+)
+
+// WaitingListEntryPosition - Position of the waiting entry in the queue of its room
+type WaitingListEntryPosition struct {
+
+	// 1-based position among the waiting entries of the room
+	Position int32 `json:"position"`
+
+	// Estimated time of entering ambulance
+	EstimatedStart time.Time `json:"estimatedStart"`
+}
@@ -0,0 +1,18 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListEntryTransfer - Target of the waiting list entry transfer
+type WaitingListEntryTransfer struct {
+
+	// Id of the ambulance the entry is moved to
+	ToAmbulanceId string `json:"toAmbulanceId"`
+}
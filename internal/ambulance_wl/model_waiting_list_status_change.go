@@ -0,0 +1,21 @@
+/*
+ * Waiting List Api
+ *
+ * Ambulance Waiting List management for Web-In-Cloud system
+ *
+ * API version: 1.0.0
+ * Contact: pfx@google.com
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance_wl
+
+// WaitingListStatusChange - New status of the listed entries
+type WaitingListStatusChange struct {
+
+	// Ids of the entries to change
+	EntryIds []string `json:"entryIds"`
+
+	// New status of the entries
+	Status string `json:"status"`
+}
@@ -11,28 +11,35 @@
 package ambulance_wl
 
 import (
+		"log"
+
 		"github.com/gin-gonic/gin"
 )
 
 
-func AddRoutes(engine *gin.Engine) *gin.RouterGroup{
-	group := engine.Group("/api")
-	
-	{
-		api := newAmbulanceConditionsAPI()
-		api.addRoutes(group)
+func AddRoutes(router gin.IRouter) *gin.RouterGroup{
+	group := router.Group("/api")
+
+	// routes are collected first, so that the routes of the disabled features are not registered at all
+	// and the wiring mistakes are reported with the offending route
+	routes, err := collectRoutes(
+		newAmbulanceConditionsAPI().addRoutes,
+		newAmbulanceWaitingListAPI().addRoutes,
+		newAmbulancesAPI().addRoutes,
+	)
+	if err != nil {
+		log.Panicf("Invalid api routes: %v", err)
 	}
-	
-	{
-		api := newAmbulanceWaitingListAPI()
-		api.addRoutes(group)
+
+	enabled := gin.RoutesInfo{}
+	for _, route := range routes {
+		if FeatureEnabled(routeFeature(route.Path)) {
+			enabled = append(enabled, route)
+		}
 	}
-	
-	{
-		api := newAmbulancesAPI()
-		api.addRoutes(group)
+	if err := registerRoutes(group, enabled); err != nil {
+		log.Panicf("Invalid api routes: %v", err)
 	}
-	
 
 	return group
 }
@@ -0,0 +1,65 @@
+package ambulance_wl
+
+import (
+	"sync"
+)
+
+// capacity of the subscriber channels, changes are dropped for the subscribers not keeping up
+const subscriberBufferSize = 16
+
+// ambulanceBroker is in-process publish/subscribe hub of the ambulance changes keyed by the ambulance id
+type ambulanceBroker struct {
+	lock        sync.Mutex
+	subscribers map[string]map[chan *Ambulance]struct{}
+}
+
+var broker = newAmbulanceBroker()
+
+func newAmbulanceBroker() *ambulanceBroker {
+	return &ambulanceBroker{
+		subscribers: map[string]map[chan *Ambulance]struct{}{},
+	}
+}
+
+// subscribe registers for the changes of the ambulance, the returned function cancels the subscription.
+// Nil value is delivered when the ambulance was deleted.
+func (this *ambulanceBroker) subscribe(ambulanceId string) (<-chan *Ambulance, func()) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	channel := make(chan *Ambulance, subscriberBufferSize)
+	if _, ok := this.subscribers[ambulanceId]; !ok {
+		this.subscribers[ambulanceId] = map[chan *Ambulance]struct{}{}
+	}
+	this.subscribers[ambulanceId][channel] = struct{}{}
+
+	return channel, func() {
+		this.lock.Lock()
+		defer this.lock.Unlock()
+		if _, ok := this.subscribers[ambulanceId][channel]; ok {
+			delete(this.subscribers[ambulanceId], channel)
+			if len(this.subscribers[ambulanceId]) == 0 {
+				delete(this.subscribers, ambulanceId)
+			}
+			close(channel)
+		}
+	}
+}
+
+func (this *ambulanceBroker) publish(ambulanceId string, ambulance *Ambulance) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for channel := range this.subscribers[ambulanceId] {
+		select {
+		case channel <- ambulance:
+		default: // subscriber is not keeping up, skip this change
+		}
+	}
+}
+
+// PublishAmbulanceChange delivers the changed ambulance to the subscribers of this service instance,
+// used to propagate changes observed in the database - including changes made by other replicas
+func PublishAmbulanceChange(ambulanceId string, ambulance *Ambulance) {
+	broker.publish(ambulanceId, ambulance)
+}
@@ -0,0 +1,47 @@
+package ambulance_wl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AmbulanceBrokerSuite struct {
+	suite.Suite
+}
+
+func TestAmbulanceBrokerSuite(t *testing.T) {
+	suite.Run(t, new(AmbulanceBrokerSuite))
+}
+
+func (suite *AmbulanceBrokerSuite) Test_Publish_DeliversChangesOfSubscribedAmbulanceOnly() {
+	// ARRANGE
+	sut := newAmbulanceBroker()
+	changes, unsubscribe := sut.subscribe("ambulance-a")
+	defer unsubscribe()
+
+	// ACT
+	sut.publish("ambulance-b", &Ambulance{Id: "ambulance-b"})
+	sut.publish("ambulance-a", &Ambulance{Id: "ambulance-a", Name: "changed"})
+	sut.publish("ambulance-a", nil)
+
+	// ASSERT
+	suite.Equal("changed", (<-changes).Name)
+	suite.Nil(<-changes)
+	suite.Empty(changes)
+}
+
+func (suite *AmbulanceBrokerSuite) Test_Unsubscribe_ClosesChannel() {
+	// ARRANGE
+	sut := newAmbulanceBroker()
+	changes, unsubscribe := sut.subscribe("ambulance-a")
+
+	// ACT
+	unsubscribe()
+	sut.publish("ambulance-a", &Ambulance{Id: "ambulance-a"})
+
+	// ASSERT
+	_, open := <-changes
+	suite.False(open)
+	suite.Empty(sut.subscribers)
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -19,6 +22,7 @@ var (
 	dbMeter           = otel.Meter("waiting_list_access")
 	dbTimeSpent       metric.Float64Counter
 	waitingListLength = map[string]int64{}
+	waitingListLock   sync.Mutex
 	tracer            = otel.Tracer("ambulance-wl-api")
 )
 
@@ -37,6 +41,118 @@ func init() {
 	}
 }
 
+// header marking responses of the requests that were not persisted
+const dryRunHeader = "X-Dry-Run"
+
+// isDryRun is true if the request asks only to validate and compute the result, see `dryRun` query parameter
+func isDryRun(ctx *gin.Context) bool {
+	dryRun, err := strconv.ParseBool(ctx.Query("dryRun"))
+	return err == nil && dryRun
+}
+
+// seconds the clients shall wait before retrying the request when the database is unavailable
+const databaseRetryAfterSeconds = 5
+
+// respondDatabaseUnavailable reports the unreachable database as the temporary condition,
+// so that the clients and the load balancers back off instead of failing over
+func respondDatabaseUnavailable(ctx *gin.Context, err error) {
+	ctx.Header("Retry-After", strconv.Itoa(databaseRetryAfterSeconds))
+	ctx.JSON(
+		http.StatusServiceUnavailable,
+		gin.H{
+			"status":  "Service Unavailable",
+			"message": "Database is unavailable, retry later",
+			"error":   err.Error(),
+		})
+}
+
+// context key of the requests creating the missing ambulance instead of failing, see allowAmbulanceAutoCreate
+const autoCreateAmbulanceKey = "auto_create_ambulance"
+
+// allowAmbulanceAutoCreate lets the updateAmbulanceFunc create the minimal ambulance if it does not exist yet,
+// effective only if enabled by AMBULANCE_API_AUTO_CREATE_AMBULANCE
+func allowAmbulanceAutoCreate(ctx *gin.Context) {
+	ctx.Set(autoCreateAmbulanceKey, config.AutoCreateAmbulance)
+}
+
+// createMissingAmbulance stores the minimal ambulance of the given id, named by the id. Concurrent requests may
+// race to create the same ambulance - the unique id lets only one of them insert it, the others load the inserted one.
+// In the dry run the ambulance is provided without storing it.
+func createMissingAmbulance(ctx *gin.Context, db db_service.DbService[Ambulance], ambulanceId string) (*Ambulance, error) {
+	ambulance := &Ambulance{Id: ambulanceId, Name: ambulanceId, WaitingList: []WaitingListEntry{}}
+	if isDryRun(ctx) {
+		return ambulance, nil
+	}
+	switch err := db.CreateDocument(ctx.Request.Context(), ambulanceId, ambulance); err {
+	case nil:
+		log.Printf("Created missing ambulance %v", ambulanceId)
+		return ambulance, nil
+	case db_service.ErrConflict:
+		return db.FindDocument(ctx.Request.Context(), ambulanceId)
+	default:
+		return nil, err
+	}
+}
+
+// storeAmbulance replaces the stored ambulance only if it was not changed since the ambulance was read, the version
+// of the ambulance must be the version it was read with and it is increased by the write. Fails with
+// db_service.ErrPreconditionFailed if other writer changed the stored ambulance meanwhile.
+func storeAmbulance(ctx context.Context, db db_service.DbService[Ambulance], ambulanceId string, ambulance *Ambulance) error {
+	read := ambulance.Version
+	ambulance.Version = read + 1
+	err := db.UpdateDocumentIf(ctx, ambulanceId, ambulance, versionCondition(read))
+	if err != nil {
+		ambulance.Version = read
+	}
+	return err
+}
+
+// versionCondition matches the stored ambulance of the given version, the ambulances stored
+// before they were versioned have no version and match the zero version
+func versionCondition(version int64) bson.M {
+	if version == 0 {
+		return bson.M{"version": bson.M{"$in": bson.A{int64(0), nil}}}
+	}
+	return bson.M{"version": version}
+}
+
+// the background writers apply their change to the reloaded ambulance at most this many times
+// if the ambulance was changed concurrently
+const maxStoreRetries = 3
+
+// changeStoredAmbulance applies the change to the ambulance and stores it. If other writer changed the ambulance
+// meanwhile, the change is applied again to the reloaded ambulance. The change reports whether the ambulance
+// shall be stored at all. Intended for the writers without the client able to retry the request, e.g. the sweeper.
+func changeStoredAmbulance(ctx context.Context, db db_service.DbService[Ambulance], ambulance *Ambulance, change func(ambulance *Ambulance) bool) error {
+	for attempt := 0; ; attempt++ {
+		if !change(ambulance) {
+			return nil
+		}
+		err := storeAmbulance(ctx, db, ambulance.Id, ambulance)
+		if err != db_service.ErrPreconditionFailed || attempt >= maxStoreRetries {
+			return err
+		}
+		ambulance, err = db.FindDocument(db_service.ReadForUpdate(ctx), ambulance.Id)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// changedAmbulanceResponse reports the ambulance changed by other request since it was read or since
+// the version the client based its change on
+func changedAmbulanceResponse(ctx *gin.Context, status int, err error) gin.H {
+	response := gin.H{
+		"status":  status,
+		"code":    msgAmbulanceChanged,
+		"message": localize(ctx, msgAmbulanceChanged),
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	return response
+}
+
 type ambulanceUpdater = func(
 	ctx *gin.Context,
 	ambulance *Ambulance,
@@ -46,7 +162,13 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 	// special handling for gin context
 	// we need to extract the span context and create a new context to ensure span context propagation
 	// to the updater function
-	spanctx, span := tracer.Start(ctx.Request.Context(), "updateAmbulanceFunc")
+	requestCtx := ctx.Request.Context()
+	// the safe requests may read the replicated documents, the modifications must be based on the current ones
+	modifying := ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead
+	if modifying {
+		requestCtx = db_service.ReadForUpdate(requestCtx)
+	}
+	spanctx, span := tracer.Start(requestCtx, "updateAmbulanceFunc")
 	ctx.Request = ctx.Request.WithContext(spanctx)
 	defer span.End()
 	value, exists := ctx.Get("db_service")
@@ -74,28 +196,77 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 	}
 
 	ambulanceId := ctx.Param("ambulanceId")
+	if !isValidId(ambulanceId) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "ambulanceId", ambulanceId))
+		return
+	}
+	// entry routes are rejected before the ambulance is loaded, the malformed entry id cannot be found in it
+	if entryId, found := ctx.Params.Get("entryId"); found && !isValidId(entryId) {
+		ctx.JSON(http.StatusBadRequest, invalidIdResponse(ctx, "Bad Request", "entryId", entryId))
+		return
+	}
+
+	// with the coalesced writes the changes of the ambulance are serialized and based on the buffered state
+	var pending *pendingWrite
+	if writes.enabled() && modifying {
+		pending = writes.acquire(ambulanceId)
+		defer func() {
+			if pending != nil {
+				pending.release()
+			}
+		}()
+	}
 
 	start := time.Now()
-	ambulance, err := db.FindDocument(spanctx, ambulanceId)
+	var ambulance *Ambulance
+	var err error
+	switch {
+	case pending != nil:
+		ambulance = pending.current()
+	case writes.enabled():
+		ambulance = writes.snapshot(ambulanceId)
+	}
+	if ambulance == nil {
+		ambulance, err = db.FindDocument(spanctx, ambulanceId)
+	}
+	if err == nil && ambulance == nil {
+		// missing ambulance is always reported as not found, even if the service does not say so
+		err = db_service.ErrNotFound
+	}
+	if err == db_service.ErrNotFound && ctx.GetBool(autoCreateAmbulanceKey) {
+		ambulance, err = createMissingAmbulance(ctx, db, ambulanceId)
+		if err == nil && ambulance == nil {
+			err = db_service.ErrNotFound
+		}
+	}
+	// no ambulance is provided on failures
+	ambulanceName := ""
+	if ambulance != nil {
+		ambulanceName = ambulance.Name
+	}
 	dbTimeSpent.Add(ctx, float64(float64(time.Since(start)))/float64(time.Millisecond), metric.WithAttributes(
 		attribute.String("operation", "find"),
 		attribute.String("ambulance_id", ambulanceId),
-		attribute.String("ambulance_name", ambulance.Name),
+		attribute.String("ambulance_name", ambulanceName),
 	))
 
-	switch err {
-	case nil:
+	switch {
+	case err == nil:
 		// continue
-	case db_service.ErrNotFound:
+	case err == db_service.ErrNotFound:
 		ctx.JSON(
 			http.StatusNotFound,
 			gin.H{
 				"status":  "Not Found",
-				"message": "Ambulance not found",
+				"code":    msgAmbulanceNotFound,
+				"message": localize(ctx, msgAmbulanceNotFound),
 				"error":   err.Error(),
 			},
 		)
 		return
+	case db_service.IsUnavailable(err):
+		respondDatabaseUnavailable(ctx, err)
+		return
 	default:
 		ctx.JSON(
 			http.StatusBadGateway,
@@ -118,12 +289,70 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 		return
 	}
 
+	// the updater changes the ambulance in place, the previous state identifies the changed entries
+	var previous *Ambulance
+	if modifying {
+		previous = ambulance.clone()
+	}
+	readVersion := ambulance.Version
 	updatedAmbulance, responseObject, status := updater(ctx, ambulance)
 
+	if isDryRun(ctx) {
+		// the updater did all validations and computations, just skip the db write
+		ctx.Header(dryRunHeader, "true")
+		if updatedAmbulance != nil {
+			span.AddEvent("updateAmbulanceFunc: dry run, ambulance not updated in database")
+			updatedAmbulance = nil
+		}
+	}
+
+	if updatedAmbulance == nil {
+		// the patients claimed by the updater are not listed in any stored ambulance
+		releaseClaimedPatients(ctx, spanctx, ambulanceId)
+	} else if previous != nil {
+		if response, status := registerChangedPatients(ctx, spanctx, previous, updatedAmbulance); response != nil {
+			releaseClaimedPatients(ctx, spanctx, ambulanceId)
+			ctx.Writer.Header().Del("Location")
+			ctx.JSON(status, response)
+			return
+		}
+	}
+
 	if updatedAmbulance != nil {
 		span.AddEvent("updateAmbulanceFunc: updating ambulance in database")
+		if previous != nil {
+			updatedAmbulance.trackEntryChanges(previous, clock.Now())
+		}
+		// the write is conditional on the version read, the updater cannot change it
+		updatedAmbulance.Version = readVersion
 		start := time.Now()
-		err = db.UpdateDocument(spanctx, ambulanceId, updatedAmbulance)
+		if pending != nil {
+			// the buffer is released while waiting, so that the following changes join the same write
+			done := pending.buffer(db, updatedAmbulance)
+			pending.release()
+			pending = nil
+			stored := <-done
+			err = stored.err
+			// the version is incremented on the last buffered change only, the earlier ones of the same write
+			// are tagged by the stored version as well
+			if err == nil {
+				updatedAmbulance.Version = stored.version
+			}
+		} else {
+			err = storeAmbulance(spanctx, db, ambulanceId, updatedAmbulance)
+		}
+		if err != nil {
+			releaseClaimedPatients(ctx, spanctx, ambulanceId)
+		} else {
+			if previous != nil {
+				releaseRemovedPatients(ctx, spanctx, previous, updatedAmbulance)
+			}
+			storePendingAudit(ctx, spanctx)
+			// the stored ambulance is tagged by its new version
+			if responseObject == interface{}(updatedAmbulance) {
+				ctx.Header("ETag", versionETag(updatedAmbulance.Version))
+			}
+		}
 
 		// update metrics
 		dbTimeSpent.Add(ctx, float64(float64(time.Since(start)))/float64(time.Millisecond), metric.WithAttributes(
@@ -135,7 +364,12 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 		// demonstration of possible handling of async instruments:
 		// not really an operational metric, it would be more of a business metric/KPI.
 		// also UpDownCounter may be of better use in practical cases.
-		if _, ok := waitingListLength[ambulanceId]; !ok {
+		// the gauge snapshot is set under the lock, the concurrent requests and the metrics collection access it
+		waitingListLock.Lock()
+		_, registered := waitingListLength[ambulanceId]
+		waitingListLength[ambulanceId] = int64(len(updatedAmbulance.WaitingList))
+		waitingListLock.Unlock()
+		if !registered {
 			newGauge, err := dbMeter.Int64ObservableGauge(
 				fmt.Sprintf("%v_waiting_patients", ambulanceId),
 				metric.WithDescription(fmt.Sprintf("The length of the waiting list for the ambulance %v", ambulance.Name)),
@@ -144,11 +378,12 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 			if err != nil {
 				log.Printf("Failed to create waiting list length gauge for ambulance %v: %v", ambulanceId, err)
 			}
-			waitingListLength[ambulanceId] = 0
 
 			_, err = dbMeter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
 				// we could have looked up the ambulance in the database here, but we already have it in memory
 				// so use the latest snapshots to update the gauge
+				waitingListLock.Lock()
+				defer waitingListLock.Unlock()
 				o.ObserveInt64(newGauge, waitingListLength[ambulanceId])
 				return nil
 			}, newGauge)
@@ -158,33 +393,37 @@ func updateAmbulanceFunc(ctx *gin.Context, updater ambulanceUpdater) {
 			}
 		}
 
-		// set the gauge snapshot
-		waitingListLength[ambulanceId] = int64(len(updatedAmbulance.WaitingList))
-
 	} else {
 		err = nil // redundant but for clarity
 	}
 
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
+		// the created resource does not exist if it was not stored
+		ctx.Writer.Header().Del("Location")
 	}
 
-	switch err {
-	case nil:
+	switch {
+	case err == nil:
 		if responseObject != nil {
 			ctx.JSON(status, responseObject)
 		} else {
 			ctx.AbortWithStatus(status)
 		}
-	case db_service.ErrNotFound:
+	case err == db_service.ErrNotFound:
 		ctx.JSON(
 			http.StatusNotFound,
 			gin.H{
 				"status":  "Not Found",
+				"code":    msgAmbulanceNotFound,
 				"message": "Ambulance was deleted while processing the request",
 				"error":   err.Error(),
 			},
 		)
+	case err == db_service.ErrPreconditionFailed:
+		ctx.JSON(http.StatusConflict, changedAmbulanceResponse(ctx, http.StatusConflict, err))
+	case db_service.IsUnavailable(err):
+		respondDatabaseUnavailable(ctx, err)
 	default:
 		ctx.JSON(
 			http.StatusBadGateway,
@@ -0,0 +1,87 @@
+package ambulance_wl
+
+import (
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+)
+
+const (
+	auditActionCreate   = "create"
+	auditActionUpdate   = "update"
+	auditActionDelete   = "delete"
+	auditActionTransfer = "transfer"
+)
+
+// actor of the changes made by the service itself, e.g. by the no-show sweeper
+const auditSystemActor = "system"
+
+// context key of the audit records of the request, they are stored only after the ambulance is stored
+const pendingAuditKey = "pending_audit_entries"
+
+// headers set by the authentication proxy in front of the service, the service itself
+// does not authenticate the users
+var auditActorHeaders = []string{"X-Forwarded-User", "X-Forwarded-Email", "X-Auth-Request-User"}
+
+// auditLog provides the audit log service, nil if the audit log is not configured
+func auditLog(ctx *gin.Context) db_service.DbService[AuditEntry] {
+	value, exists := ctx.Get("audit_log")
+	if !exists {
+		return nil
+	}
+	audit, _ := value.(db_service.DbService[AuditEntry])
+	return audit
+}
+
+// auditActor identifies the user of the request
+func auditActor(ctx *gin.Context) string {
+	for _, header := range auditActorHeaders {
+		if actor := ctx.GetHeader(header); actor != "" {
+			return actor
+		}
+	}
+	return "anonymous"
+}
+
+// recordAudit notes the change of the entry, the record is stored by storePendingAudit
+// once the changed ambulance is stored
+func recordAudit(ctx *gin.Context, action string, ambulanceId string, entryId string) {
+	value, _ := ctx.Get(pendingAuditKey)
+	entries, _ := value.([]AuditEntry)
+	ctx.Set(pendingAuditKey, append(entries, newAuditEntry(action, ambulanceId, entryId, auditActor(ctx))))
+}
+
+func newAuditEntry(action string, ambulanceId string, entryId string, actor string) AuditEntry {
+	return AuditEntry{
+		Id:          newId(),
+		AmbulanceId: ambulanceId,
+		EntryId:     entryId,
+		Action:      action,
+		Actor:       actor,
+		Timestamp:   clock.Now(),
+	}
+}
+
+// storePendingAudit stores the audit records of the request, failures are only logged so that
+// the already stored change is not reported as failed
+func storePendingAudit(ctx *gin.Context, spanctx context.Context) {
+	value, _ := ctx.Get(pendingAuditKey)
+	entries, _ := value.([]AuditEntry)
+	ctx.Set(pendingAuditKey, nil)
+	storeAudit(spanctx, auditLog(ctx), entries)
+}
+
+// storeAudit stores the audit records, nothing is stored if the audit log is not configured
+func storeAudit(spanctx context.Context, audit db_service.DbService[AuditEntry], entries []AuditEntry) {
+	if audit == nil {
+		return
+	}
+	for i := range entries {
+		if err := audit.CreateDocument(spanctx, entries[i].Id, &entries[i]); err != nil {
+			log.Printf("Failed to store audit record %v of entry %v in ambulance %v: %v",
+				entries[i].Action, entries[i].EntryId, entries[i].AmbulanceId, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ambulance_wl
+
+import (
+	"sync"
+	"time"
+)
+
+// checkinLimiter throttles the self check-ins of the same patient, the kiosks are not trusted
+// and a repeated check-in is a mistake or an abuse of the kiosk
+type checkinLimiter struct {
+	mutex sync.Mutex
+	// time of the last check-in, keyed by the ambulance and patient id
+	checkins map[string]time.Time
+	// time of the last removal of the check-ins which do not limit anymore
+	pruned time.Time
+}
+
+var checkins = newCheckinLimiter()
+
+func newCheckinLimiter() *checkinLimiter {
+	return &checkinLimiter{checkins: map[string]time.Time{}}
+}
+
+func checkinKey(ambulanceId string, patientId string) string {
+	return ambulanceId + "\x00" + patientId
+}
+
+// allow reports how long the patient has to wait if the previous check-in of the patient
+// was recorded less than the interval ago
+func (this *checkinLimiter) allow(ambulanceId string, patientId string, now time.Time, interval time.Duration) (time.Duration, bool) {
+	if interval <= 0 {
+		return 0, true
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if checkin, found := this.checkins[checkinKey(ambulanceId, patientId)]; found && now.Before(checkin.Add(interval)) {
+		return checkin.Add(interval).Sub(now), false
+	}
+	return 0, true
+}
+
+// record notes the check-in of the patient once it is stored, the rejected and previewed check-ins
+// do not limit the patient
+func (this *checkinLimiter) record(ambulanceId string, patientId string, now time.Time, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	// the check-ins which do not limit anymore are removed at most once per interval,
+	// so the map does not grow without bounds and the check-ins do not scan it each time
+	if !now.Before(this.pruned.Add(interval)) {
+		for key, checkin := range this.checkins {
+			if !now.Before(checkin.Add(interval)) {
+				delete(this.checkins, key)
+			}
+		}
+		this.pruned = now
+	}
+	this.checkins[checkinKey(ambulanceId, patientId)] = now
+}
@@ -0,0 +1,17 @@
+package ambulance_wl
+
+import "time"
+
+// Clock provides the current time to the scheduling logic, so that the tests can fix the time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// clock used by the waiting list logic, replaced by the fixed clock in the tests
+var clock Clock = systemClock{}
@@ -0,0 +1,97 @@
+package ambulance_wl
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	idStrategyUuidV4 = "uuidv4"
+	idStrategyUuidV7 = "uuidv7"
+	idStrategyUlid   = "ulid"
+)
+
+// namespace of the deterministic (UUIDv5) waiting list entry ids
+var entryIdNamespace = uuid.MustParse("fe912c3e-24fa-4c99-959e-a75fb224ce39")
+
+// arrival time is truncated so that retried submissions of the same entry yield the same id
+const deterministicIdResolution = time.Minute
+
+// newEntryId generates id for the entry submitted without an id. In the deterministic mode the id is
+// derived from the ambulance, the patient, and the arrival time, so the duplicate submission collides
+// with the existing entry; otherwise the random UUIDv4 is used.
+func newEntryId(ambulanceId string, entry *WaitingListEntry) string {
+	if !config.DeterministicIds {
+		return newId()
+	}
+
+	arrival := entry.WaitingSince
+	if arrival.IsZero() {
+		arrival = clock.Now()
+	}
+	name := ambulanceId + "/" + entry.PatientId + "/" +
+		arrival.UTC().Truncate(deterministicIdResolution).Format(time.RFC3339)
+	return uuid.NewSHA1(entryIdNamespace, []byte(name)).String()
+}
+
+// newId generates the random id in the format of the configured id strategy. UUIDv7 and ULID ids start
+// with the creation time, so that the ids created later are sorted after the older ones.
+func newId() string {
+	switch config.IdStrategy {
+	case idStrategyUuidV7:
+		return newUuidV7(time.Now())
+	case idStrategyUlid:
+		return newUlid(time.Now())
+	default:
+		return uuid.NewString()
+	}
+}
+
+// newUuidV7 generates the RFC 9562 UUID version 7 - 48 bits of unix time in milliseconds followed by random bits
+func newUuidV7(now time.Time) string {
+	id := uuid.New() // random bits, version and variant are overwritten below
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(now.UnixMilli()))
+	copy(id[0:6], timestamp[2:])
+	id[6] = (id[6] & 0x0f) | 0x70
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id.String()
+}
+
+// alphabet of the Crockford's base32 encoding used by ULIDs
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newUlid generates the ULID - 48 bits of unix time in milliseconds followed by 80 random bits,
+// encoded as 26 characters of the Crockford's base32
+func newUlid(now time.Time) string {
+	var data [16]byte
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(now.UnixMilli()))
+	copy(data[0:6], timestamp[2:])
+	if _, err := rand.Read(data[6:]); err != nil {
+		panic(err)
+	}
+
+	// 128 bits are encoded into 130 bits, the 2 leading bits are zero
+	var result strings.Builder
+	result.Grow(26)
+	high := binary.BigEndian.Uint64(data[0:8])
+	low := binary.BigEndian.Uint64(data[8:16])
+	for shift := 125; shift >= 0; shift -= 5 {
+		var index uint64
+		switch {
+		case shift >= 64:
+			index = high >> (shift - 64)
+		case shift > 59:
+			index = high<<(64-shift) | low>>shift
+		default:
+			index = low >> shift
+		}
+		result.WriteByte(ulidAlphabet[index&0x1f])
+	}
+	return result.String()
+}
@@ -0,0 +1,55 @@
+package ambulance_wl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// weakETag computes the weak entity tag of the JSON representation of the value, the tag changes
+// whenever the content or the order of the items change
+func weakETag(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`, nil
+}
+
+// etagMatches evaluates the If-None-Match header against the current entity tag using the weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// versionETag provides the strong entity tag of the stored ambulance of the given version
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchSatisfied evaluates the If-Match header against the version of the stored ambulance using the strong
+// comparison, the missing header is always satisfied and the asterisk is satisfied by any stored ambulance
+func ifMatchSatisfied(ifMatch string, version int64) bool {
+	if strings.TrimSpace(ifMatch) == "" || strings.TrimSpace(ifMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == versionETag(version) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package ambulance_wl
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+// names of the endpoint groups which can be disabled per deployment by AMBULANCE_API_DISABLED_FEATURES,
+// the names are part of the deployment configuration and must stay stable
+const (
+	// management of the ambulances - create, delete, patch, and metadata
+	FeatureAmbulances = "ambulances"
+	// export and import of the ambulance documents
+	FeatureImportExport = "import-export"
+	// predefined conditions of the ambulance
+	FeatureConditions = "conditions"
+	// waiting list entries - list, create, update, delete, and status changes
+	FeatureWaitingList = "waiting-list"
+	// creating and fetching of multiple entries in a single request
+	FeatureBatch = "batch"
+	// self check-in of the patients at the kiosks
+	FeatureCheckin = "checkin"
+	// waiting list for the waiting room screen
+	FeaturePublicView = "public-view"
+	// estimated wait of the patients not yet in the waiting list and the drain time of the waiting list
+	FeatureEstimate = "estimate"
+	// moving of the entries between the ambulances
+	FeatureTransfer = "transfer"
+	// audit records of the waiting list changes
+	FeatureAudit = "audit"
+	// admin endpoints re-running the reconciliation of the waiting lists
+	FeatureAdminReconcile = "admin-reconcile"
+	// admin endpoint generating the synthetic entries
+	FeatureAdminGenerator = "admin-generator"
+)
+
+var knownFeatures = []string{
+	FeatureAmbulances, FeatureImportExport, FeatureConditions, FeatureWaitingList, FeatureBatch,
+	FeatureCheckin, FeaturePublicView, FeatureEstimate, FeatureTransfer, FeatureAudit, FeatureAdminReconcile, FeatureAdminGenerator,
+}
+
+// routes not belonging to the feature of their path prefix, keyed by the path relative to the api group
+var routeFeatures = map[string]string{
+	"/waiting-list/:ambulanceId/batch":                     FeatureBatch,
+	"/waiting-list/:ambulanceId/batch-get":                 FeatureBatch,
+	"/waiting-list/:ambulanceId/checkin":                   FeatureCheckin,
+	"/waiting-list/:ambulanceId/public":                    FeaturePublicView,
+	"/waiting-list/:ambulanceId/estimate":                  FeatureEstimate,
+	"/waiting-list/:ambulanceId/changes":                   FeatureWaitingList,
+	"/waiting-list/:ambulanceId/draintime":                 FeatureEstimate,
+	"/waiting-list/:ambulanceId/schedule":                  FeatureEstimate,
+	"/waiting-list/:ambulanceId/entries/:entryId/transfer": FeatureTransfer,
+	"/waiting-list/:ambulanceId/audit":                     FeatureAudit,
+	"/waiting-list/:ambulanceId/condition":                 FeatureConditions,
+	"/ambulance/:ambulanceId/export":                       FeatureImportExport,
+	"/ambulance/:ambulanceId/import":                       FeatureImportExport,
+}
+
+// routeFeature provides the feature the api route belongs to
+func routeFeature(path string) string {
+	if feature, ok := routeFeatures[path]; ok {
+		return feature
+	}
+	if strings.HasPrefix(path, "/ambulance") {
+		return FeatureAmbulances
+	}
+	return FeatureWaitingList
+}
+
+// FeatureEnabled reports whether the endpoints of the feature shall be registered
+func FeatureEnabled(feature string) bool {
+	return !slices.Contains(config.DisabledFeatures, feature)
+}
+
+// enviroFeatures provides the comma separated list of the feature names, unknown names are ignored
+func enviroFeatures(name string) []string {
+	features := []string{}
+	for _, feature := range strings.Split(os.Getenv(name), ",") {
+		feature = strings.TrimSpace(feature)
+		switch {
+		case feature == "":
+		case slices.Contains(knownFeatures, feature):
+			features = append(features, feature)
+		default:
+			log.Printf("Invalid %v value: unknown feature %v", name, feature)
+		}
+	}
+	return features
+}
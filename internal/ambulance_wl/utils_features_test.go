@@ -0,0 +1,59 @@
+package ambulance_wl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (suite *AmbulanceWlSuite) registeredRoutes() []string {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	AddRoutes(engine)
+	routes := []string{}
+	for _, route := range engine.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	return routes
+}
+
+func (suite *AmbulanceWlSuite) Test_AddRoutes_DisabledFeatureNotRegistered() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.DisabledFeatures = []string{FeaturePublicView, FeatureImportExport}
+
+	// ACT
+	routes := suite.registeredRoutes()
+
+	// ASSERT
+	suite.NotContains(routes, http.MethodGet+" /api/waiting-list/:ambulanceId/public")
+	suite.NotContains(routes, http.MethodGet+" /api/ambulance/:ambulanceId/export")
+	suite.NotContains(routes, http.MethodPost+" /api/ambulance/:ambulanceId/import")
+	suite.Contains(routes, http.MethodGet+" /api/waiting-list/:ambulanceId/entries")
+	suite.Contains(routes, http.MethodPost+" /api/ambulance")
+}
+
+func (suite *AmbulanceWlSuite) Test_AddRoutes_AllFeaturesEnabledByDefault() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.DisabledFeatures = nil
+
+	// ACT
+	routes := suite.registeredRoutes()
+
+	// ASSERT
+	suite.Contains(routes, http.MethodGet+" /api/waiting-list/:ambulanceId/public")
+	suite.Contains(routes, http.MethodPost+" /api/waiting-list/:ambulanceId/batch-get")
+	suite.Contains(routes, http.MethodGet+" /api/waiting-list/:ambulanceId/condition")
+}
+
+func (suite *AmbulanceWlSuite) Test_EnviroFeatures_IgnoresUnknownNames() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_DISABLED_FEATURES", " public-view, unknown,,admin-generator ")
+
+	// ACT
+	features := enviroFeatures("AMBULANCE_API_DISABLED_FEATURES")
+
+	// ASSERT
+	suite.Equal([]string{FeaturePublicView, FeatureAdminGenerator}, features)
+}
@@ -0,0 +1,33 @@
+package ambulance_wl
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maximal length of the ambulance and entry ids
+const maxIdLength = 128
+
+// ids consist of the unreserved characters of URIs, so that they are used in the paths without escaping;
+// the generated UUIDs and ULIDs always match the format
+var idFormat = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9][A-Za-z0-9._~-]{0,%d}$`, maxIdLength-1))
+
+// isValidId checks the id has the format of the ambulance and entry ids
+func isValidId(id string) bool {
+	return idFormat.MatchString(id)
+}
+
+// invalidIdResponse provides the error response of the malformed id, such id cannot identify any stored
+// document so it is reported as the bad request rather than as not found
+func invalidIdResponse(ctx *gin.Context, status interface{}, name string, id string) gin.H {
+	return gin.H{
+		"status":  status,
+		"code":    msgInvalidId,
+		"message": localize(ctx, msgInvalidId),
+		"error": fmt.Sprintf(
+			"%v %q must be at most %d letters, digits, '.', '_', '~', or '-', starting with a letter or digit",
+			name, id, maxIdLength),
+	}
+}
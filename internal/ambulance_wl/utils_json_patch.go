@@ -0,0 +1,273 @@
+package ambulance_wl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	errJsonPatchInvalidPath  = errors.New("path does not exist in the document")
+	errJsonPatchTestFailed   = errors.New("test operation failed")
+	errJsonPatchMissingValue = errors.New("value is required by the operation")
+)
+
+// validateJsonPatch checks the operations carry the members required by RFC 6902, e.g. the value
+// of the add operation; the explicit null value is a valid value
+func validateJsonPatch(patch []JsonPatchOperation) error {
+	for i, operation := range patch {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if len(operation.Value) == 0 {
+				return fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, errJsonPatchMissingValue)
+			}
+		}
+	}
+	return nil
+}
+
+// value decodes the value of the operation
+func (this *JsonPatchOperation) value() (interface{}, error) {
+	if len(this.Value) == 0 {
+		return nil, errJsonPatchMissingValue
+	}
+	var value interface{}
+	err := json.Unmarshal(this.Value, &value)
+	return value, err
+}
+
+// applyJsonPatch applies the RFC 6902 operations on the json document, operations are applied
+// in order and the first failing operation aborts the whole patch
+func applyJsonPatch(document []byte, patch []JsonPatchOperation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, err
+	}
+
+	for i, operation := range patch {
+		var err error
+		switch operation.Op {
+		case "add":
+			var value interface{}
+			value, err = operation.value()
+			if err == nil {
+				root, err = jsonPatchAdd(root, operation.Path, value)
+			}
+		case "remove":
+			root, _, err = jsonPatchRemove(root, operation.Path)
+		case "replace":
+			var value interface{}
+			value, err = operation.value()
+			if err == nil {
+				root, _, err = jsonPatchRemove(root, operation.Path)
+			}
+			if err == nil {
+				root, err = jsonPatchAdd(root, operation.Path, value)
+			}
+		case "move":
+			var value interface{}
+			root, value, err = jsonPatchRemove(root, operation.From)
+			if err == nil {
+				root, err = jsonPatchAdd(root, operation.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			value, err = jsonPatchGet(root, operation.From)
+			if err == nil {
+				value, err = jsonDeepCopy(value)
+			}
+			if err == nil {
+				root, err = jsonPatchAdd(root, operation.Path, value)
+			}
+		case "test":
+			var value, expected interface{}
+			value, err = jsonPatchGet(root, operation.Path)
+			if err == nil {
+				// decoded to the same representation as the document
+				expected, err = operation.value()
+			}
+			if err == nil && !reflect.DeepEqual(value, expected) {
+				err = errJsonPatchTestFailed
+			}
+		default:
+			err = fmt.Errorf("unsupported operation %q", operation.Op)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+// parseJsonPointer splits the RFC 6901 pointer into unescaped reference tokens
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// jsonArrayIndex parses array index token, the index may point behind the last element
+// only if the allowEnd is set - used by the add operation
+func jsonArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > length || (!allowEnd && index == length) {
+		return 0, errJsonPatchInvalidPath
+	}
+	return index, nil
+}
+
+// jsonPatchUpdate walks the document to the parent of the last pointer token and replaces the parent
+// with the result of the update function
+func jsonPatchUpdate(
+	node interface{},
+	tokens []string,
+	update func(parent interface{}, token string) (interface{}, error),
+) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(node, tokens[0])
+	}
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[tokens[0]]
+		if !ok {
+			return nil, errJsonPatchInvalidPath
+		}
+		updated, err := jsonPatchUpdate(child, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = updated
+		return container, nil
+	case []interface{}:
+		index, err := jsonArrayIndex(tokens[0], len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := jsonPatchUpdate(container[index], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	default:
+		return nil, errJsonPatchInvalidPath
+	}
+}
+
+func jsonPatchAdd(root interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := parseJsonPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return jsonPatchUpdate(root, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		default:
+			return nil, errJsonPatchInvalidPath
+		}
+	})
+}
+
+func jsonPatchRemove(root interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := parseJsonPointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	var removed interface{}
+	root, err = jsonPatchUpdate(root, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, errJsonPatchInvalidPath
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[index]
+			return append(container[:index], container[index+1:]...), nil
+		default:
+			return nil, errJsonPatchInvalidPath
+		}
+	})
+	return root, removed, err
+}
+
+func jsonPatchGet(root interface{}, path string) (interface{}, error) {
+	tokens, err := parseJsonPointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node := root
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, errJsonPatchInvalidPath
+			}
+			node = value
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, errJsonPatchInvalidPath
+		}
+	}
+	return node, nil
+}
+
+func jsonDeepCopy(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
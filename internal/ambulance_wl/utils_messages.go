@@ -0,0 +1,75 @@
+package ambulance_wl
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// stable machine readable codes of the error messages, returned in the `code` field of the error response
+const (
+	msgInvalidRequestBody = "invalid_request_body"
+	msgInvalidId          = "invalid_id"
+	msgAmbulanceNotFound  = "ambulance_not_found"
+	msgAmbulanceConflict  = "ambulance_conflict"
+	msgAmbulanceChanged   = "ambulance_changed"
+	msgEntryNotFound      = "entry_not_found"
+	msgEntryConflict      = "entry_conflict"
+	msgCheckinTooSoon     = "checkin_too_soon"
+	msgAlreadyCheckedIn   = "already_checked_in"
+)
+
+// supported languages, the first one is the fallback for unknown languages
+var supportedLanguages = []language.Tag{language.English, language.Slovak}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+var messageCatalog = map[language.Tag]map[string]string{
+	language.English: {
+		msgInvalidRequestBody: "Invalid request body",
+		msgInvalidId:          "Malformed identifier",
+		msgAmbulanceNotFound:  "Ambulance not found",
+		msgAmbulanceConflict:  "Ambulance already exists",
+		msgAmbulanceChanged:   "Ambulance was changed meanwhile, reload it and try again",
+		msgEntryNotFound:      "Entry not found",
+		msgEntryConflict:      "Entry already exists",
+		msgCheckinTooSoon:     "Check-in was attempted recently, try again later",
+		msgAlreadyCheckedIn:   "Patient is already in the waiting list",
+	},
+	language.Slovak: {
+		msgInvalidRequestBody: "Neplatné telo požiadavky",
+		msgInvalidId:          "Chybný identifikátor",
+		msgAmbulanceNotFound:  "Ambulancia nebola nájdená",
+		msgAmbulanceConflict:  "Ambulancia už existuje",
+		msgAmbulanceChanged:   "Ambulancia bola medzičasom zmenená, načítajte ju a skúste znova",
+		msgEntryNotFound:      "Záznam nebol nájdený",
+		msgEntryConflict:      "Záznam už existuje",
+		msgCheckinTooSoon:     "Registrácia bola nedávno skúšaná, skúste to neskôr",
+		msgAlreadyCheckedIn:   "Pacient už je v poradovníku",
+	},
+}
+
+// LanguageMiddleware selects the language of the response messages from the Accept-Language header
+func LanguageMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tags, _, _ := language.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+		_, index, _ := languageMatcher.Match(tags...)
+		ctx.Set("language", supportedLanguages[index])
+		ctx.Next()
+	}
+}
+
+// localize provides the message text in the language selected for the request, English is used
+// if the language was not selected or the message is not translated
+func localize(ctx *gin.Context, code string) string {
+	if value, exists := ctx.Get("language"); exists {
+		if tag, ok := value.(language.Tag); ok {
+			if message, ok := messageCatalog[tag][code]; ok {
+				return message
+			}
+		}
+	}
+	if message, ok := messageCatalog[language.English][code]; ok {
+		return message
+	}
+	return code
+}
@@ -0,0 +1,123 @@
+package ambulance_wl
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RunNoShowSweeper periodically marks the entries of the patients who left without notice as no-show,
+// so that they do not skew the estimates of the others. Returns when the context is cancelled,
+// or immediately if the sweeper is disabled by the configuration. The marked entries are recorded
+// in the audit log, if it is provided.
+func RunNoShowSweeper(ctx context.Context, db db_service.DbService[Ambulance], audit db_service.DbService[AuditEntry]) {
+	if config.NoShowSweepInterval <= 0 {
+		return
+	}
+	log.Printf("No-show sweeper started, interval %v", config.NoShowSweepInterval)
+
+	ticker := time.NewTicker(config.NoShowSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sweepNoShows(ctx, db, audit, clock.Now(), config.NoShowGraceMultiple); err != nil {
+				log.Printf("No-show sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// sweepNoShows walks all ambulances and stores those with the abandoned entries marked as no-show,
+// each marked entry is recorded as updated by the system actor
+func sweepNoShows(ctx context.Context, db db_service.DbService[Ambulance], audit db_service.DbService[AuditEntry], now time.Time, graceMultiple float64) error {
+	// the swept ambulances are stored back, they must not be read from the replica
+	ctx, span := tracer.Start(db_service.ReadForUpdate(ctx), "sweepNoShows")
+	defer span.End()
+
+	afterId := ""
+	for {
+		ambulances, nextId, err := db.ListDocumentsAfter(ctx, afterId, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, ambulance := range ambulances {
+			swept, err := sweepAmbulance(ctx, db, ambulance, now, graceMultiple)
+			if err != nil {
+				// other ambulances may still be swept, this one is retried by the next sweep
+				log.Printf("Failed to store swept ambulance %v: %v", ambulance.Id, err)
+				continue
+			}
+			if len(swept) == 0 {
+				continue
+			}
+			span.AddEvent("entries marked as no-show", trace.WithAttributes(
+				attribute.String("ambulance_id", ambulance.Id),
+				attribute.Int("count", len(swept)),
+			))
+			entries := make([]AuditEntry, 0, len(swept))
+			for _, entryId := range swept {
+				entries = append(entries, newAuditEntry(auditActionUpdate, ambulance.Id, entryId, auditSystemActor))
+			}
+			storeAudit(ctx, audit, entries)
+		}
+
+		if nextId == "" || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		afterId = nextId
+	}
+}
+
+// sweepAmbulance marks the abandoned entries of the ambulance as no-show and stores it, provides the ids
+// of the marked entries. With the coalesced writes the sweep is serialized with the changes of the requests,
+// the buffered state is swept and joins the pending write instead of being overwritten by it.
+func sweepAmbulance(ctx context.Context, db db_service.DbService[Ambulance], ambulance *Ambulance, now time.Time, graceMultiple float64) ([]string, error) {
+	var swept []string
+	markNoShows := func(ambulance *Ambulance) bool {
+		previous := ambulance.clone()
+		// the reloaded ambulance is swept again
+		swept = nil
+		for i := range ambulance.WaitingList {
+			if entry := &ambulance.WaitingList[i]; entry.isAbandoned(now, graceMultiple) {
+				entry.Status = statusNoShow
+				swept = append(swept, entry.Id)
+			}
+		}
+		if len(swept) == 0 {
+			return false
+		}
+
+		ambulance.reconcileWaitingList(ctx)
+		ambulance.trackEntryChanges(previous, now)
+		return true
+	}
+
+	if !writes.enabled() {
+		err := changeStoredAmbulance(ctx, db, ambulance, markNoShows)
+		return swept, err
+	}
+
+	pending := writes.acquire(ambulance.Id)
+	buffered := pending.current()
+	if buffered == nil {
+		// the requests wait until the stored ambulance is swept
+		defer pending.release()
+		err := changeStoredAmbulance(ctx, db, ambulance, markNoShows)
+		return swept, err
+	}
+	if !markNoShows(buffered) {
+		pending.release()
+		return nil, nil
+	}
+	done := pending.buffer(db, buffered)
+	pending.release()
+	return swept, (<-done).err
+}
@@ -0,0 +1,116 @@
+package ambulance_wl
+
+import (
+	"context"
+	"time"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AmbulanceWlSuite) Test_SweepNoShows_MarksAgedEntries() {
+	// ARRANGE
+	now := time.Now()
+	ambulance := &Ambulance{
+		Id: "test-ambulance",
+		WaitingList: []WaitingListEntry{
+			{Id: "aged", PatientId: "p1", WaitingSince: now.Add(-2 * time.Hour), EstimatedDurationMinutes: 15},
+			{Id: "examined", PatientId: "p2", WaitingSince: now.Add(-2 * time.Hour), EstimatedDurationMinutes: 15, Status: statusInExamination},
+			{Id: "recent", PatientId: "p3", WaitingSince: now.Add(-30 * time.Minute), EstimatedDurationMinutes: 15},
+		},
+	}
+	untouched := &Ambulance{
+		Id:          "other-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "fresh", PatientId: "p4", WaitingSince: now, EstimatedDurationMinutes: 15}},
+	}
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "", mock.Anything).
+		Return([]*Ambulance{ambulance, untouched}, "", nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	audit := &DbServiceMock[AuditEntry]{}
+	audit.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	// ACT
+	err := sweepNoShows(context.Background(), suite.dbServiceMock, audit, now, 4)
+
+	// ASSERT
+	suite.NoError(err)
+	statuses := map[string]string{}
+	for _, entry := range ambulance.WaitingList {
+		statuses[entry.Id] = entry.effectiveStatus()
+	}
+	suite.Equal(map[string]string{
+		"aged":     statusNoShow,
+		"examined": statusInExamination,
+		"recent":   statusWaiting,
+	}, statuses)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance", ambulance, mock.Anything)
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "other-ambulance", mock.Anything, mock.Anything)
+	audit.AssertNumberOfCalls(suite.T(), "CreateDocument", 1)
+	record := audit.Calls[0].Arguments.Get(2).(*AuditEntry)
+	suite.Equal(auditActionUpdate, record.Action)
+	suite.Equal(auditSystemActor, record.Actor)
+	suite.Equal("test-ambulance", record.AmbulanceId)
+	suite.Equal("aged", record.EntryId)
+}
+
+func (suite *AmbulanceWlSuite) Test_SweepNoShows_ChangedConcurrently_SweepsReloadedAmbulance() {
+	// ARRANGE
+	now := time.Now()
+	aged := WaitingListEntry{Id: "aged", PatientId: "p1", WaitingSince: now.Add(-2 * time.Hour), EstimatedDurationMinutes: 15}
+	read := &Ambulance{Id: "test-ambulance", WaitingList: []WaitingListEntry{aged}}
+	// other writer added the entry after the sweeper listed the ambulance
+	reloaded := &Ambulance{Id: "test-ambulance", Version: 1, WaitingList: []WaitingListEntry{
+		aged,
+		{Id: "added", PatientId: "p2", WaitingSince: now, EstimatedDurationMinutes: 15},
+	}}
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "", mock.Anything).
+		Return([]*Ambulance{read}, "", nil)
+	suite.dbServiceMock.
+		On("FindDocument", mock.Anything, "test-ambulance").
+		Return(reloaded, nil)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, "test-ambulance", read, mock.Anything).
+		Return(db_service.ErrPreconditionFailed)
+	suite.dbServiceMock.
+		On("UpdateDocumentIf", mock.Anything, "test-ambulance", reloaded, mock.Anything).
+		Return(nil)
+
+	// ACT
+	err := sweepNoShows(context.Background(), suite.dbServiceMock, nil, now, 4)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.dbServiceMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "test-ambulance", reloaded, versionCondition(1))
+	suite.Len(reloaded.WaitingList, 2)
+	suite.Equal(statusNoShow, reloaded.WaitingList[0].effectiveStatus())
+}
+
+func (suite *AmbulanceWlSuite) Test_RunNoShowSweeper_StopsOnCancel() {
+	// ARRANGE
+	defer func(previous serverConfig) { config = previous }(config)
+	config.NoShowSweepInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// ACT
+	go func() {
+		RunNoShowSweeper(ctx, suite.dbServiceMock, nil)
+		close(done)
+	}()
+	cancel()
+
+	// ASSERT
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.Fail("sweeper did not stop on cancel")
+	}
+}
@@ -0,0 +1,122 @@
+package ambulance_wl
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+)
+
+// PatientRegistration records that the patient is listed in the ambulance waiting list. The registrations
+// are stored in the collection with the unique index of ambulance and patient id, so that concurrent
+// instances of the service cannot admit the same patient twice.
+type PatientRegistration struct {
+	Id           string    `json:"id"`
+	AmbulanceId  string    `json:"ambulanceId"`
+	PatientId    string    `json:"patientId"`
+	EntryId      string    `json:"entryId"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// registration without the entry in the stored waiting list is considered orphaned after this period,
+// e.g. the ambulance update failed after the patient was registered
+const orphanedRegistrationPeriod = time.Minute
+
+func patientRegistrationId(ambulanceId string, patientId string) string {
+	return ambulanceId + "/" + patientId
+}
+
+// patientRegistry provides the registry service, nil if the registry is not configured
+func patientRegistry(ctx *gin.Context) db_service.DbService[PatientRegistration] {
+	value, exists := ctx.Get("patient_registry")
+	if !exists {
+		return nil
+	}
+	registry, _ := value.(db_service.DbService[PatientRegistration])
+	return registry
+}
+
+// registerPatient claims the patient for the entry of the ambulance, returns db_service.ErrConflict if the patient
+// is already registered by other entry. Orphaned registrations are taken over.
+func registerPatient(ctx context.Context, registry db_service.DbService[PatientRegistration], ambulance *Ambulance, entry *WaitingListEntry) error {
+	registration := &PatientRegistration{
+		Id:           patientRegistrationId(ambulance.Id, entry.PatientId),
+		AmbulanceId:  ambulance.Id,
+		PatientId:    entry.PatientId,
+		EntryId:      entry.Id,
+		RegisteredAt: time.Now(),
+	}
+
+	err := registry.CreateDocument(ctx, registration.Id, registration)
+	if err != db_service.ErrConflict {
+		return err
+	}
+
+	existing, err := registry.FindDocument(ctx, registration.Id)
+	if err != nil {
+		return err
+	}
+	for _, waiting := range ambulance.WaitingList {
+		if waiting.Id == existing.EntryId {
+			return db_service.ErrConflict
+		}
+	}
+	if time.Since(existing.RegisteredAt) < orphanedRegistrationPeriod {
+		// the entry may be just being stored by other instance
+		return db_service.ErrConflict
+	}
+	return registry.UpdateDocument(ctx, registration.Id, registration)
+}
+
+// unregisterPatient releases the patient of the removed entry, failures leave the orphaned registration
+// which is taken over by the next registration of the patient
+func unregisterPatient(ctx context.Context, registry db_service.DbService[PatientRegistration], ambulanceId string, entry *WaitingListEntry) error {
+	err := registry.DeleteDocument(ctx, patientRegistrationId(ambulanceId, entry.PatientId))
+	if err == db_service.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// context key of the entries whose patients were registered by the request, see claimPatient
+const claimedPatientsKey = "claimed_patients"
+
+// claimPatient registers the patient of the entry added to the ambulance of the request, the registration
+// is released by releaseClaimedPatients if the ambulance is not stored after all
+func claimPatient(c *gin.Context, ctx context.Context, registry db_service.DbService[PatientRegistration], ambulance *Ambulance, entry *WaitingListEntry) error {
+	if err := registerPatient(ctx, registry, ambulance, entry); err != nil {
+		return err
+	}
+	c.Set(claimedPatientsKey, append(claimedPatients(c), *entry))
+	return nil
+}
+
+// claimedPatients provides the entries whose patients were registered by the request
+func claimedPatients(c *gin.Context) []WaitingListEntry {
+	value, _ := c.Get(claimedPatientsKey)
+	claimed, _ := value.([]WaitingListEntry)
+	return claimed
+}
+
+// reassignPatient moves the registration of the patient to the entry of the changed id, the patient
+// stays registered by the ambulance regardless of the entry listing it
+func reassignPatient(ctx context.Context, registry db_service.DbService[PatientRegistration], ambulance *Ambulance, entry *WaitingListEntry) error {
+	registration := &PatientRegistration{
+		Id:           patientRegistrationId(ambulance.Id, entry.PatientId),
+		AmbulanceId:  ambulance.Id,
+		PatientId:    entry.PatientId,
+		EntryId:      entry.Id,
+		RegisteredAt: time.Now(),
+	}
+	return registry.UpsertDocument(ctx, registration.Id, registration)
+}
+
+// patientEntries maps the patients listed in the waiting list of the ambulance to the ids of their entries
+func patientEntries(ambulance *Ambulance) map[string]string {
+	entries := make(map[string]string, len(ambulance.WaitingList))
+	for _, entry := range ambulance.WaitingList {
+		entries[entry.PatientId] = entry.Id
+	}
+	return entries
+}
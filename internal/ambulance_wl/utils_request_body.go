@@ -0,0 +1,84 @@
+package ambulance_wl
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prefix of the decoding error of the field not known to the decoded type, the error has no dedicated type
+const unknownFieldErrorPrefix = "json: unknown field "
+
+// decodeJSON decodes the JSON document into the value; with AMBULANCE_API_STRICT_JSON the fields not known
+// to the value are rejected, otherwise they are ignored. Note the field names are matched case-insensitively.
+func decodeJSON(reader io.Reader, value interface{}) error {
+	decoder := json.NewDecoder(reader)
+	if config.StrictJson {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(value)
+}
+
+// bindJSON decodes the request body into the value, see decodeJSON
+func bindJSON(ctx *gin.Context, value interface{}) error {
+	if ctx.Request == nil || ctx.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decodeJSON(ctx.Request.Body, value)
+}
+
+// invalidBodyResponse provides the error response of the request body that cannot be decoded; malformed
+// JSON is reported with the byte offset of the failure, mistyped fields with their path and expected type,
+// and unknown fields rejected by the strict decoding with their name
+func invalidBodyResponse(ctx *gin.Context, status interface{}, err error) gin.H {
+	response := gin.H{
+		"status":  status,
+		"code":    msgInvalidRequestBody,
+		"message": localize(ctx, msgInvalidRequestBody),
+		"error":   err.Error(),
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		response["offset"] = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		response["offset"] = typeErr.Offset
+		response["field"] = typeErr.Field
+		response["expectedType"] = jsonTypeName(typeErr.Type)
+		response["actualType"] = typeErr.Value
+	case strings.HasPrefix(err.Error(), unknownFieldErrorPrefix):
+		response["field"] = strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldErrorPrefix), `"`)
+	}
+	return response
+}
+
+// jsonTypeName provides the name of the JSON type the go type is decoded from
+func jsonTypeName(goType reflect.Type) string {
+	if goType == nil {
+		return "unknown"
+	}
+	switch goType.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonTypeName(goType.Elem())
+	default:
+		return goType.String()
+	}
+}
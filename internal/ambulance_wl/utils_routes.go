@@ -0,0 +1,56 @@
+package ambulance_wl
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectRoutes gathers the routes of the api groups without registering them to the served router,
+// so that the duplicate routes are reported with the offending route instead of the gin panic
+func collectRoutes(addRoutes ...func(routerGroup *gin.RouterGroup)) (gin.RoutesInfo, error) {
+	routes := gin.RoutesInfo{}
+	registeredBy := map[string]string{}
+	for _, add := range addRoutes {
+		staged, err := stageRoutes(add)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range staged {
+			key := route.Method + " " + route.Path
+			if handler, exists := registeredBy[key]; exists {
+				return nil, fmt.Errorf("duplicate route %v handled by %v and %v", key, handler, route.Handler)
+			}
+			registeredBy[key] = route.Handler
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// stageRoutes registers the routes of the single api group to the scratch engine
+func stageRoutes(addRoutes func(routerGroup *gin.RouterGroup)) (routes gin.RoutesInfo, err error) {
+	staging := gin.New()
+	defer func() {
+		if reason := recover(); reason != nil {
+			err = fmt.Errorf("conflicting routes of the api group: %v", reason)
+		}
+	}()
+	addRoutes(&staging.RouterGroup)
+	return staging.Routes(), nil
+}
+
+// registerRoutes registers the collected routes to the served router, the route conflicting
+// with the routes already registered, e.g. by the path parameter name, is reported
+func registerRoutes(router gin.IRoutes, routes gin.RoutesInfo) (err error) {
+	route := gin.RouteInfo{}
+	defer func() {
+		if reason := recover(); reason != nil {
+			err = fmt.Errorf("cannot register route %v %v handled by %v: %v", route.Method, route.Path, route.Handler, reason)
+		}
+	}()
+	for _, route = range routes {
+		router.Handle(route.Method, route.Path, route.HandlerFunc)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package ambulance_wl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func handleOk(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+}
+
+func (suite *AmbulanceWlSuite) Test_CollectRoutes_DuplicateAcrossGroupsReported() {
+	// ARRANGE
+	first := func(routerGroup *gin.RouterGroup) {
+		routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/entries", handleOk)
+	}
+	second := func(routerGroup *gin.RouterGroup) {
+		routerGroup.Handle(http.MethodPost, "/waiting-list/:ambulanceId/entries", handleOk)
+		routerGroup.Handle(http.MethodGet, "/waiting-list/:ambulanceId/entries", handleOk)
+	}
+
+	// ACT
+	var err error
+	suite.NotPanics(func() { _, err = collectRoutes(first, second) })
+
+	// ASSERT
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "duplicate route GET /waiting-list/:ambulanceId/entries")
+}
+
+func (suite *AmbulanceWlSuite) Test_CollectRoutes_DuplicateWithinGroupReported() {
+	// ARRANGE
+	duplicate := func(routerGroup *gin.RouterGroup) {
+		routerGroup.Handle(http.MethodGet, "/ambulance/:ambulanceId", handleOk)
+		routerGroup.Handle(http.MethodGet, "/ambulance/:ambulanceId", handleOk)
+	}
+
+	// ACT
+	var err error
+	suite.NotPanics(func() { _, err = collectRoutes(duplicate) })
+
+	// ASSERT
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "/ambulance/:ambulanceId")
+}
+
+func (suite *AmbulanceWlSuite) Test_RegisterRoutes_ConflictReported() {
+	// ARRANGE
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/ambulance/:id", handleOk)
+	routes, err := collectRoutes(func(routerGroup *gin.RouterGroup) {
+		routerGroup.Handle(http.MethodGet, "/ambulance/:ambulanceId", handleOk)
+	})
+	suite.Require().NoError(err)
+
+	// ACT
+	suite.NotPanics(func() { err = registerRoutes(engine, routes) })
+
+	// ASSERT
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "cannot register route GET /ambulance/:ambulanceId")
+}
+
+func (suite *AmbulanceWlSuite) Test_CollectRoutes_ApiRoutesAreUnique() {
+	_, err := collectRoutes(
+		newAmbulanceConditionsAPI().addRoutes,
+		newAmbulanceWaitingListAPI().addRoutes,
+		newAmbulancesAPI().addRoutes,
+	)
+	suite.NoError(err)
+}
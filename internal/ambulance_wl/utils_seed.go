@@ -0,0 +1,65 @@
+package ambulance_wl
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+)
+
+// value of the seed source selecting the embedded demo ambulances instead of a file
+const embeddedSeedSource = "embedded"
+
+//go:embed utils_seed_ambulances.json
+var embeddedSeed []byte
+
+// SeedAmbulances inserts the ambulances of the seed source which are not stored yet, the source is either
+// the path of the JSON file with the array of ambulances or `embedded` for the demo ambulances.
+// Stored ambulances are left untouched, so seeding is safe on every start. Returns the number of inserted ambulances.
+func SeedAmbulances(ctx context.Context, db db_service.DbService[Ambulance], source string) (int, error) {
+	content := embeddedSeed
+	if source != embeddedSeedSource {
+		var err error
+		if content, err = os.ReadFile(source); err != nil {
+			return 0, err
+		}
+	}
+
+	var ambulances []Ambulance
+	if err := json.Unmarshal(content, &ambulances); err != nil {
+		return 0, fmt.Errorf("invalid seed %v: %w", source, err)
+	}
+	return seedAmbulances(ctx, db, ambulances)
+}
+
+func seedAmbulances(ctx context.Context, db db_service.DbService[Ambulance], ambulances []Ambulance) (int, error) {
+	ctx, span := tracer.Start(ctx, "seedAmbulances")
+	defer span.End()
+
+	seeded := 0
+	for i := range ambulances {
+		ambulance := &ambulances[i]
+		if ambulance.Id == "" {
+			return seeded, fmt.Errorf("seed ambulance at index %d has no id", i)
+		}
+		if err := ambulance.validateWaitingList(); err != nil {
+			return seeded, fmt.Errorf("seed ambulance %v: %w", ambulance.Id, err)
+		}
+		ambulance.reconcileWaitingList(ctx)
+
+		switch err := db.CreateDocument(ctx, ambulance.Id, ambulance); err {
+		case nil:
+			seeded++
+		case db_service.ErrConflict:
+			// already stored, possibly modified since the previous seeding
+		default:
+			return seeded, err
+		}
+	}
+	log.Printf("Seeded %d of %d ambulances", seeded, len(ambulances))
+	return seeded, nil
+}
@@ -0,0 +1,52 @@
+package ambulance_wl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *AmbulanceWlSuite) Test_SeedAmbulances_InsertsMissingAndIsIdempotent() {
+	// ARRANGE
+	seedFile := filepath.Join(suite.T().TempDir(), "seed.json")
+	suite.Require().NoError(os.WriteFile(seedFile, []byte(`[
+		{"id": "first", "name": "First", "roomNumber": "1"},
+		{"id": "second", "name": "Second", "roomNumber": "2"}
+	]`), 0o600))
+
+	suite.dbServiceMock.ExpectedCalls = nil
+	for _, id := range []string{"first", "second"} {
+		// stored by the first seeding, already existing for the second one
+		suite.dbServiceMock.On("CreateDocument", mock.Anything, id, mock.Anything).Return(nil).Once()
+		suite.dbServiceMock.On("CreateDocument", mock.Anything, id, mock.Anything).Return(db_service.ErrConflict)
+	}
+
+	// ACT
+	seeded, err := SeedAmbulances(context.Background(), suite.dbServiceMock, seedFile)
+	reseeded, reseedErr := SeedAmbulances(context.Background(), suite.dbServiceMock, seedFile)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.Equal(2, seeded)
+	suite.NoError(reseedErr)
+	suite.Equal(0, reseeded)
+	suite.dbServiceMock.AssertNumberOfCalls(suite.T(), "CreateDocument", 4)
+}
+
+func (suite *AmbulanceWlSuite) Test_SeedAmbulances_EmbeddedSeedIsValid() {
+	// ARRANGE
+	suite.dbServiceMock.ExpectedCalls = nil
+	suite.dbServiceMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	// ACT
+	seeded, err := SeedAmbulances(context.Background(), suite.dbServiceMock, embeddedSeedSource)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.Positive(seeded)
+}
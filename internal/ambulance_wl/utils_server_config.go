@@ -0,0 +1,144 @@
+package ambulance_wl
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// serverConfig holds the behavior settings of the waiting list api, resolved from the environment
+type serverConfig struct {
+	// derive ids of new entries from the ambulance, patient, and arrival time instead of random ids
+	DeterministicIds bool
+	// format of the generated ids, one of idStrategyUuidV4, idStrategyUuidV7, idStrategyUlid
+	IdStrategy string
+	// attempts to generate another id if the generated one collides with an existing one, collisions fail if zero
+	IdCollisionRetries int
+	// period of the sweeps marking the abandoned entries as no-show, sweeper is disabled if zero
+	NoShowSweepInterval time.Duration
+	// waiting entry is abandoned if the patient waits longer than this multiple of its estimated duration
+	NoShowGraceMultiple float64
+	// increase of the entry priority for each hour of waiting, used by the priority reconcile strategy; the routine
+	// patient waiting longer than the newly arrived urgent one by 1/PriorityAgingRate hours is served first,
+	// priorities do not age if zero
+	PriorityAgingRate float64
+	// upper limit of the estimated duration of the entries, guards the estimates against typos
+	MaxDurationMinutes int32
+	// allows the admin endpoint generating the synthetic entries, intended for the load tests only
+	EntryGeneratorEnabled bool
+	// field ordering the entries with the same waiting since, one of tieBreakId, tieBreakPatientId, tieBreakTicketNumber
+	TieBreakField string
+	// orders the entries with the same waiting since in the descending order of TieBreakField
+	TieBreakDescending bool
+	// creates the missing ambulance on the first waiting list entry instead of responding 404 Not Found
+	AutoCreateAmbulance bool
+	// responds 204 No Content instead of 404 Not Found when deleting the ambulance which does not exist
+	IdempotentDelete bool
+	// changes of the same ambulance within this window are stored by a single write, not buffered if zero
+	WriteCoalesceWindow time.Duration
+	// rejects the request bodies with the fields not known to the api instead of ignoring them
+	StrictJson bool
+	// removed entries are provided to the synchronizing clients for this long, older clients reload the waiting list
+	SyncRetention time.Duration
+	// minimal interval between the self check-ins of the same patient, not limited if zero
+	CheckinInterval time.Duration
+	// endpoint groups not registered in this deployment, see the Feature constants
+	DisabledFeatures []string
+}
+
+var config = loadServerConfig()
+
+// ServerSettings provides the effective behavior settings of the waiting list api, for diagnostics
+func ServerSettings() interface{} {
+	return config
+}
+
+func loadServerConfig() serverConfig {
+	return serverConfig{
+		DeterministicIds:      enviroBool("AMBULANCE_API_DETERMINISTIC_IDS", false),
+		IdStrategy:            enviroChoice("AMBULANCE_API_ID_STRATEGY", idStrategyUuidV4, idStrategyUuidV7, idStrategyUlid),
+		IdCollisionRetries:    enviroInt("AMBULANCE_API_ID_COLLISION_RETRIES", 3, 0),
+		NoShowSweepInterval:   enviroDuration("AMBULANCE_API_NO_SHOW_SWEEP_INTERVAL", 0),
+		NoShowGraceMultiple:   enviroFloat("AMBULANCE_API_NO_SHOW_GRACE_MULTIPLE", 4, 0, false),
+		PriorityAgingRate:     enviroFloat("AMBULANCE_API_PRIORITY_AGING_RATE", 0.5, 0, true),
+		MaxDurationMinutes:    int32(enviroInt("AMBULANCE_API_MAX_DURATION_MINUTES", 480, 1)),
+		EntryGeneratorEnabled: enviroBool("AMBULANCE_API_ENABLE_ENTRY_GENERATOR", false),
+		TieBreakField:         enviroChoice("AMBULANCE_API_TIEBREAK_FIELD", tieBreakId, tieBreakPatientId, tieBreakTicketNumber),
+		TieBreakDescending:    enviroBool("AMBULANCE_API_TIEBREAK_DESCENDING", false),
+		AutoCreateAmbulance:   enviroBool("AMBULANCE_API_AUTO_CREATE_AMBULANCE", false),
+		IdempotentDelete:      enviroBool("AMBULANCE_API_IDEMPOTENT_DELETE", false),
+		WriteCoalesceWindow:   enviroDuration("AMBULANCE_API_WRITE_COALESCE_WINDOW", 0),
+		StrictJson:            enviroBool("AMBULANCE_API_STRICT_JSON", false),
+		SyncRetention:         enviroDuration("AMBULANCE_API_SYNC_RETENTION", 24*time.Hour),
+		CheckinInterval:       enviroDuration("AMBULANCE_API_CHECKIN_INTERVAL", time.Minute),
+		DisabledFeatures:      enviroFeatures("AMBULANCE_API_DISABLED_FEATURES"),
+	}
+}
+
+func enviroBool(name string, defaultValue bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	if result, err := strconv.ParseBool(value); err == nil {
+		return result
+	}
+	log.Printf("Invalid %v value: %v", name, value)
+	return defaultValue
+}
+
+// enviroInt provides the integer value of the variable, values below the minimum are rejected
+func enviroInt(name string, defaultValue int, minimum int) int {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	if result, err := strconv.Atoi(value); err == nil && result >= minimum {
+		return result
+	}
+	log.Printf("Invalid %v value: %v", name, value)
+	return defaultValue
+}
+
+func enviroDuration(name string, defaultValue time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	if result, err := time.ParseDuration(value); err == nil && result >= 0 {
+		return result
+	}
+	log.Printf("Invalid %v value: %v", name, value)
+	return defaultValue
+}
+
+// enviroFloat provides the number value of the variable, values below the minimum are rejected
+// as well as the values equal to the minimum unless the minimum is inclusive
+func enviroFloat(name string, defaultValue float64, minimum float64, inclusive bool) float64 {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	if result, err := strconv.ParseFloat(value, 64); err == nil &&
+		(result > minimum || (inclusive && result == minimum)) {
+		return result
+	}
+	log.Printf("Invalid %v value: %v", name, value)
+	return defaultValue
+}
+
+// enviroChoice provides the value of the variable if it is one of the choices, the first choice is the default
+func enviroChoice(name string, choices ...string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return choices[0]
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	log.Printf("Invalid %v value: %v", name, value)
+	return choices[0]
+}
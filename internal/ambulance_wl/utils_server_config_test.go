@@ -0,0 +1,29 @@
+package ambulance_wl
+
+func (suite *AmbulanceWlSuite) Test_LoadServerConfig_ZeroValuesAccepted() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_PRIORITY_AGING_RATE", "0")
+	suite.T().Setenv("AMBULANCE_API_ID_COLLISION_RETRIES", "0")
+
+	// ACT
+	loaded := loadServerConfig()
+
+	// ASSERT
+	suite.Equal(0.0, loaded.PriorityAgingRate)
+	suite.Equal(0, loaded.IdCollisionRetries)
+}
+
+func (suite *AmbulanceWlSuite) Test_LoadServerConfig_InvalidValuesFallBackToDefaults() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_PRIORITY_AGING_RATE", "-0.5")
+	suite.T().Setenv("AMBULANCE_API_ID_COLLISION_RETRIES", "many")
+	suite.T().Setenv("AMBULANCE_API_MAX_DURATION_MINUTES", "0")
+
+	// ACT
+	loaded := loadServerConfig()
+
+	// ASSERT
+	suite.Equal(0.5, loaded.PriorityAgingRate)
+	suite.Equal(3, loaded.IdCollisionRetries)
+	suite.Equal(int32(480), loaded.MaxDurationMinutes)
+}
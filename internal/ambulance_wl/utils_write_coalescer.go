@@ -0,0 +1,180 @@
+package ambulance_wl
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/milung/ambulance-webapi/internal/db_service"
+)
+
+// writeCoalescer buffers the changes of the ambulances for the AMBULANCE_API_WRITE_COALESCE_WINDOW, so that
+// the burst of the changes of the same ambulance, e.g. many check-ins at once, is stored by a single write.
+// Each ambulance is buffered independently. The requests wait until their change is stored, and reads of
+// the buffered ambulance see the buffered state. Changes made by other replicas of the service are not
+// visible to the buffer, the coalescing is intended for a single replica or for requests routed by the ambulance.
+type writeCoalescer struct {
+	lock    sync.Mutex
+	pending map[string]*pendingWrite
+}
+
+// pendingWrite holds the buffered state of a single ambulance, the lock serializes its changes and its flush
+type pendingWrite struct {
+	lock        sync.Mutex
+	coalescer   *writeCoalescer
+	ambulanceId string
+	db          db_service.DbService[Ambulance]
+	ambulance   *Ambulance
+	waiters     []chan storedWrite
+	timer       *time.Timer
+	// flushed buffer is removed from the coalescer, the requests still holding it must acquire a new one
+	closed bool
+}
+
+var writes = newWriteCoalescer()
+
+// storedWrite is the result of storing the buffered changes, the version is the version of the stored
+// ambulance, the same for all changes stored together
+type storedWrite struct {
+	version int64
+	err     error
+}
+
+func newWriteCoalescer() *writeCoalescer {
+	return &writeCoalescer{pending: map[string]*pendingWrite{}}
+}
+
+// enabled is true if the changes shall be buffered
+func (this *writeCoalescer) enabled() bool {
+	return config.WriteCoalesceWindow > 0
+}
+
+// acquire provides the locked buffer of the ambulance, the caller must release it
+func (this *writeCoalescer) acquire(ambulanceId string) *pendingWrite {
+	for {
+		this.lock.Lock()
+		pending, ok := this.pending[ambulanceId]
+		if !ok {
+			pending = &pendingWrite{coalescer: this, ambulanceId: ambulanceId}
+			this.pending[ambulanceId] = pending
+		}
+		this.lock.Unlock()
+
+		pending.lock.Lock()
+		if !pending.closed {
+			return pending
+		}
+		pending.lock.Unlock()
+	}
+}
+
+// snapshot provides the copy of the buffered ambulance, nil if there are no buffered changes of the ambulance
+func (this *writeCoalescer) snapshot(ambulanceId string) *Ambulance {
+	this.lock.Lock()
+	pending, ok := this.pending[ambulanceId]
+	this.lock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	pending.lock.Lock()
+	defer pending.lock.Unlock()
+	return pending.current()
+}
+
+// flush stores the buffered changes of the ambulance immediately, used before the ambulance is changed
+// by other means than the buffered writes, e.g. the metadata update
+func (this *writeCoalescer) flush(ambulanceId string) {
+	this.lock.Lock()
+	pending, ok := this.pending[ambulanceId]
+	this.lock.Unlock()
+	if ok {
+		pending.flush()
+	}
+}
+
+// FlushPendingWrites stores all buffered changes of the ambulances, called on shutdown of the service
+func FlushPendingWrites() {
+	writes.flushAll()
+}
+
+func (this *writeCoalescer) flushAll() {
+	this.lock.Lock()
+	pending := make([]*pendingWrite, 0, len(this.pending))
+	for _, write := range this.pending {
+		pending = append(pending, write)
+	}
+	this.lock.Unlock()
+
+	for _, write := range pending {
+		write.flush()
+	}
+}
+
+// current provides the copy of the buffered ambulance, nil if nothing is buffered yet. The copy can be changed
+// by the updater without affecting the buffer, e.g. if the change turns out to be invalid.
+func (this *pendingWrite) current() *Ambulance {
+	if this.ambulance == nil {
+		return nil
+	}
+	return this.ambulance.clone()
+}
+
+// release unlocks the acquired buffer, the buffer without changes is removed
+func (this *pendingWrite) release() {
+	if this.ambulance == nil && !this.closed {
+		this.close()
+	}
+	this.lock.Unlock()
+}
+
+// buffer replaces the buffered ambulance by its changed state, the returned channel reports the result
+// of storing it. The first change starts the window, all changes within the window are stored together.
+func (this *pendingWrite) buffer(db db_service.DbService[Ambulance], ambulance *Ambulance) <-chan storedWrite {
+	done := make(chan storedWrite, 1)
+	this.db = db
+	this.ambulance = ambulance
+	this.waiters = append(this.waiters, done)
+	if this.timer == nil {
+		this.timer = time.AfterFunc(config.WriteCoalesceWindow, this.flush)
+	}
+	return done
+}
+
+// flush stores the buffered ambulance and reports the result to all requests waiting for it
+func (this *pendingWrite) flush() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return
+	}
+	if this.timer != nil {
+		this.timer.Stop()
+	}
+
+	var result storedWrite
+	if this.ambulance != nil {
+		// the write must complete even if the request which started the window is cancelled
+		result.err = storeAmbulance(context.Background(), this.db, this.ambulanceId, this.ambulance)
+		result.version = this.ambulance.Version
+		if result.err != nil {
+			log.Printf("Failed to store %d buffered changes of ambulance %v: %v", len(this.waiters), this.ambulanceId, result.err)
+		}
+	}
+	// the buffer is dropped also on failure, the next change starts from the stored ambulance
+	this.close()
+	for _, waiter := range this.waiters {
+		waiter <- result
+	}
+}
+
+// close removes the buffer from the coalescer, must be called with the buffer locked
+func (this *pendingWrite) close() {
+	this.closed = true
+	this.coalescer.lock.Lock()
+	if this.coalescer.pending[this.ambulanceId] == this {
+		delete(this.coalescer.pending, this.ambulanceId)
+	}
+	this.coalescer.lock.Unlock()
+}
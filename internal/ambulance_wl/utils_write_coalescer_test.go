@@ -0,0 +1,178 @@
+package ambulance_wl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milung/ambulance-webapi/internal/db_service"
+	"github.com/stretchr/testify/mock"
+)
+
+// storedAmbulances provides the ambulances passed to the UpdateDocumentIf calls of the mock
+func (suite *AmbulanceWlSuite) storedAmbulances() []*Ambulance {
+	stored := []*Ambulance{}
+	for _, call := range suite.dbServiceMock.Calls {
+		if call.Method == "UpdateDocumentIf" {
+			stored = append(stored, call.Arguments.Get(2).(*Ambulance))
+		}
+	}
+	return stored
+}
+
+func (suite *AmbulanceWlSuite) Test_CoalescedWrites_BurstStoredByFewerWrites() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.WriteCoalesceWindow = 200 * time.Millisecond
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	sut := implAmbulanceWaitingListAPI{}
+	const burst = 5
+
+	// ACT
+	codes := make([]int, burst)
+	var wait sync.WaitGroup
+	for i := 0; i < burst; i++ {
+		ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries",
+			fmt.Sprintf(`{"patientId": "patient-%d"}`, i))
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			sut.CreateWaitingListEntry(ctx)
+			codes[i] = recorder.Code
+		}(i)
+	}
+	wait.Wait()
+
+	// ASSERT
+	for _, code := range codes {
+		suite.Equal(http.StatusCreated, code)
+	}
+	stored := suite.storedAmbulances()
+	suite.Less(len(stored), burst)
+	suite.Len(stored[len(stored)-1].WaitingList, burst)
+}
+
+func (suite *AmbulanceWlSuite) Test_CoalescedWrites_ReadSeesBufferedStateUntilFlushed() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.WriteCoalesceWindow = time.Hour
+	// ARRANGE
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance"})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	sut := implAmbulanceWaitingListAPI{}
+	createCtx, createRecorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "buffered"}`)
+	created := make(chan struct{})
+	go func() {
+		defer close(created)
+		sut.CreateWaitingListEntry(createCtx)
+	}()
+	suite.Eventually(func() bool { return writes.snapshot("test-ambulance") != nil }, time.Second, time.Millisecond)
+
+	// ACT
+	ctx, recorder := suite.newRequestContext("GET", "/waiting-list/test-ambulance/entries", "")
+	sut.GetWaitingListEntries(ctx)
+	FlushPendingWrites()
+	<-created
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	var entries []WaitingListEntry
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	suite.Len(entries, 1)
+	suite.Equal("buffered", entries[0].PatientId)
+
+	suite.Equal(http.StatusCreated, createRecorder.Code)
+	suite.Len(suite.storedAmbulances(), 1)
+	suite.Nil(writes.snapshot("test-ambulance"))
+}
+
+func (suite *AmbulanceWlSuite) Test_CoalescedWrites_TransferKeepsBufferedChangesOfTarget() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.WriteCoalesceWindow = time.Hour
+	// ARRANGE
+	db := db_service.NewMemoryService[Ambulance]()
+	now := time.Now()
+	suite.Require().NoError(db.CreateDocument(context.Background(), "test-ambulance", &Ambulance{
+		Id:          "test-ambulance",
+		WaitingList: []WaitingListEntry{{Id: "moved", PatientId: "p1", WaitingSince: now, EstimatedDurationMinutes: 15}},
+	}))
+	suite.Require().NoError(db.CreateDocument(context.Background(), "target-ambulance", &Ambulance{Id: "target-ambulance"}))
+	sut := implAmbulanceWaitingListAPI{}
+	// the entry created in the target is buffered, not stored yet
+	createCtx, createRecorder := suite.newRequestContext("POST", "/waiting-list/target-ambulance/entries", `{"patientId": "buffered"}`)
+	createCtx.Set("db_service", db)
+	createCtx.Params = []gin.Param{{Key: "ambulanceId", Value: "target-ambulance"}}
+	created := make(chan struct{})
+	go func() {
+		defer close(created)
+		sut.CreateWaitingListEntry(createCtx)
+	}()
+	suite.Eventually(func() bool { return writes.snapshot("target-ambulance") != nil }, time.Second, time.Millisecond)
+
+	// ACT
+	ctx, recorder := suite.newRequestContext(
+		http.MethodPost, "/waiting-list/test-ambulance/entries/moved/transfer", `{"toAmbulanceId": "target-ambulance"}`)
+	ctx.Set("db_service", db)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "entryId", Value: "moved"})
+	transferred := make(chan struct{})
+	go func() {
+		defer close(transferred)
+		sut.TransferWaitingListEntry(ctx)
+	}()
+	suite.Eventually(func() bool { return writes.snapshot("test-ambulance") != nil }, time.Second, time.Millisecond)
+	FlushPendingWrites()
+	<-created
+	<-transferred
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, createRecorder.Code)
+	suite.Equal(http.StatusOK, recorder.Code)
+	target, err := db.FindDocument(context.Background(), "target-ambulance")
+	suite.Require().NoError(err)
+	patients := []string{}
+	for _, entry := range target.WaitingList {
+		patients = append(patients, entry.PatientId)
+	}
+	suite.ElementsMatch([]string{"buffered", "p1"}, patients)
+	source, err := db.FindDocument(context.Background(), "test-ambulance")
+	suite.Require().NoError(err)
+	suite.Empty(source.WaitingList)
+}
+
+func (suite *AmbulanceWlSuite) Test_CoalescedWrites_SweepJoinsBufferedChanges() {
+	defer func(previous serverConfig) { config = previous }(config)
+	config.WriteCoalesceWindow = 100 * time.Millisecond
+	// ARRANGE
+	now := time.Now()
+	aged := WaitingListEntry{Id: "aged", PatientId: "p1", WaitingSince: now.Add(-2 * time.Hour), EstimatedDurationMinutes: 15}
+	suite.givenAmbulance(&Ambulance{Id: "test-ambulance", WaitingList: []WaitingListEntry{aged}})
+	suite.dbServiceMock.On("UpdateDocumentIf", mock.Anything, "test-ambulance", mock.Anything, mock.Anything).Return(nil)
+	// the sweeper lists the stored ambulance without the buffered entry
+	suite.dbServiceMock.
+		On("ListDocumentsAfter", mock.Anything, "", mock.Anything).
+		Return([]*Ambulance{{Id: "test-ambulance", WaitingList: []WaitingListEntry{aged}}}, "", nil)
+	sut := implAmbulanceWaitingListAPI{}
+	ctx, recorder := suite.newRequestContext("POST", "/waiting-list/test-ambulance/entries", `{"patientId": "buffered"}`)
+	created := make(chan struct{})
+	go func() {
+		defer close(created)
+		sut.CreateWaitingListEntry(ctx)
+	}()
+	suite.Eventually(func() bool { return writes.snapshot("test-ambulance") != nil }, time.Second, time.Millisecond)
+
+	// ACT
+	err := sweepNoShows(context.Background(), suite.dbServiceMock, nil, now, 4)
+	<-created
+
+	// ASSERT
+	suite.NoError(err)
+	suite.Equal(http.StatusCreated, recorder.Code)
+	stored := suite.storedAmbulances()
+	suite.Len(stored, 1)
+	suite.Len(stored[0].WaitingList, 2)
+	suite.Equal(statusNoShow, reconciledEntry(stored[0], "aged").Status)
+}
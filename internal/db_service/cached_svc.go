@@ -0,0 +1,142 @@
+package db_service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// cachedSvc keeps the recently read documents in memory for the configured time to live, so that the repeated
+// reads of the same document are not served by the database. Any write through the service evicts the written
+// document immediately. The writes made by other instances of the service become visible after the time to live
+// at latest. The reads for update always bypass the cache, see ReadForUpdate.
+type cachedSvc[DocType interface{}] struct {
+	DbService[DocType]
+	ttl        time.Duration
+	maxEntries int
+
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+	// incremented by each write, the reads started before the write may not cache their result
+	generation uint64
+}
+
+type cacheEntry struct {
+	// documents are cached in their BSON form, so that the callers never share the cached instances
+	raw     bson.Raw
+	expires time.Time
+}
+
+// NewCachedService wraps the service by the cache of the documents read by their id. At most maxEntries
+// documents are cached, the ones closest to the expiration are evicted first. The other interfaces
+// of the wrapped service, e.g. Pinger, are not provided by the cached service.
+func NewCachedService[DocType interface{}](service DbService[DocType], ttl time.Duration, maxEntries int) DbService[DocType] {
+	return &cachedSvc[DocType]{
+		DbService:  service,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]cacheEntry{},
+	}
+}
+
+func (this *cachedSvc[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
+	if isReadForUpdate(ctx) {
+		return this.DbService.FindDocument(ctx, id)
+	}
+
+	this.lock.Lock()
+	entry, ok := this.entries[id]
+	generation := this.generation
+	this.lock.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return decodeDocument[DocType](entry.raw)
+	}
+
+	document, err := this.DbService.FindDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		// not cacheable, the document is still valid for the caller
+		return document, nil
+	}
+	this.store(id, raw, generation)
+	return document, nil
+}
+
+// store caches the document unless it was written since the read started
+func (this *cachedSvc[DocType]) store(id string, raw bson.Raw, generation uint64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if generation != this.generation || this.maxEntries <= 0 {
+		return
+	}
+	now := time.Now()
+	if _, ok := this.entries[id]; !ok && len(this.entries) >= this.maxEntries {
+		this.evict(now)
+	}
+	this.entries[id] = cacheEntry{raw: raw, expires: now.Add(this.ttl)}
+}
+
+// evict removes the expired entries, or the entry closest to the expiration if none expired yet
+func (this *cachedSvc[DocType]) evict(now time.Time) {
+	oldestId := ""
+	var oldest time.Time
+	for id, entry := range this.entries {
+		if !now.Before(entry.expires) {
+			delete(this.entries, id)
+		} else if oldestId == "" || entry.expires.Before(oldest) {
+			oldestId, oldest = id, entry.expires
+		}
+	}
+	if len(this.entries) >= this.maxEntries {
+		delete(this.entries, oldestId)
+	}
+}
+
+// invalidate evicts the document of the id, called both before and after the write
+func (this *cachedSvc[DocType]) invalidate(id string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.entries, id)
+	this.generation++
+}
+
+func (this *cachedSvc[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.CreateDocument(ctx, id, document)
+}
+
+func (this *cachedSvc[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.UpdateDocument(ctx, id, document)
+}
+
+func (this *cachedSvc[DocType]) UpdateDocumentIf(ctx context.Context, id string, document *DocType, condition bson.M) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.UpdateDocumentIf(ctx, id, document, condition)
+}
+
+func (this *cachedSvc[DocType]) UpdateFields(ctx context.Context, id string, fields bson.M, increments bson.M) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.UpdateFields(ctx, id, fields, increments)
+}
+
+func (this *cachedSvc[DocType]) UpsertDocument(ctx context.Context, id string, document *DocType) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.UpsertDocument(ctx, id, document)
+}
+
+func (this *cachedSvc[DocType]) DeleteDocument(ctx context.Context, id string) error {
+	this.invalidate(id)
+	defer this.invalidate(id)
+	return this.DbService.DeleteDocument(ctx, id)
+}
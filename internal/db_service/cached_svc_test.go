@@ -0,0 +1,131 @@
+package db_service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CachedSvcSuite struct {
+	suite.Suite
+	backend *countingSvc
+}
+
+func TestCachedSvcSuite(t *testing.T) {
+	suite.Run(t, new(CachedSvcSuite))
+}
+
+// countingSvc counts the reads reaching the wrapped service
+type countingSvc struct {
+	DbService[testDocument]
+	reads atomic.Int32
+}
+
+func (this *countingSvc) FindDocument(ctx context.Context, id string) (*testDocument, error) {
+	this.reads.Add(1)
+	return this.DbService.FindDocument(ctx, id)
+}
+
+func (suite *CachedSvcSuite) SetupTest() {
+	suite.backend = &countingSvc{DbService: NewMemoryService[testDocument]()}
+	suite.Require().NoError(suite.backend.CreateDocument(context.Background(), "a", &testDocument{Id: "a", Name: "first"}))
+}
+
+func (suite *CachedSvcSuite) Test_FindDocument_CachedReadAvoidsDatabase() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewCachedService[testDocument](suite.backend, time.Minute, 10)
+
+	// ACT
+	first, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+	first.Name = "changed by caller"
+	second, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+
+	// ASSERT
+	suite.Equal(int32(1), suite.backend.reads.Load())
+	suite.Equal("first", second.Name)
+}
+
+func (suite *CachedSvcSuite) Test_UpdateDocument_InvalidatesEntry() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewCachedService[testDocument](suite.backend, time.Minute, 10)
+	_, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+
+	// ACT
+	suite.Require().NoError(sut.UpdateDocument(ctx, "a", &testDocument{Id: "a", Name: "updated"}))
+	found, err := sut.FindDocument(ctx, "a")
+
+	// ASSERT
+	suite.Require().NoError(err)
+	suite.Equal("updated", found.Name)
+	suite.Equal(int32(2), suite.backend.reads.Load())
+}
+
+func (suite *CachedSvcSuite) Test_FindDocument_ExpiredAndForUpdateReadsReachDatabase() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewCachedService[testDocument](suite.backend, 10*time.Millisecond, 10)
+	_, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+
+	// ACT
+	_, err = sut.FindDocument(ReadForUpdate(ctx), "a")
+	suite.Require().NoError(err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+
+	// ASSERT
+	suite.Equal(int32(3), suite.backend.reads.Load())
+}
+
+func (suite *CachedSvcSuite) Test_FindDocument_BoundedSize() {
+	// ARRANGE
+	ctx := context.Background()
+	suite.Require().NoError(suite.backend.CreateDocument(ctx, "b", &testDocument{Id: "b", Name: "second"}))
+	sut := NewCachedService[testDocument](suite.backend, time.Minute, 1)
+
+	// ACT
+	_, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+	_, err = sut.FindDocument(ctx, "b")
+	suite.Require().NoError(err)
+
+	// ASSERT
+	suite.Len(sut.(*cachedSvc[testDocument]).entries, 1)
+}
+
+func (suite *CachedSvcSuite) Test_ConcurrentReadsAndWrites_SeeLastWrite() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewCachedService[testDocument](suite.backend, time.Minute, 10)
+
+	// ACT
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(2)
+		go func() {
+			defer wait.Done()
+			_, _ = sut.FindDocument(ctx, "a")
+		}()
+		go func() {
+			defer wait.Done()
+			_ = sut.UpdateDocument(ctx, "a", &testDocument{Id: "a", Name: "concurrent"})
+		}()
+	}
+	wait.Wait()
+	suite.Require().NoError(sut.UpdateDocument(ctx, "a", &testDocument{Id: "a", Name: "last"}))
+	found, err := sut.FindDocument(ctx, "a")
+
+	// ASSERT
+	suite.Require().NoError(err)
+	suite.Equal("last", found.Name)
+}
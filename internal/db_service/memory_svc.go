@@ -0,0 +1,527 @@
+package db_service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// memorySvc keeps the documents in the process memory, intended for the local development and the tests
+// without the MongoDB server. Documents are stored in their BSON form, so that the callers never share
+// the stored instances and the filters refer to the same lowercased field names as with MongoDB.
+type memorySvc[DocType interface{}] struct {
+	lock          sync.RWMutex
+	documents     map[string]bson.Raw
+	uniqueIndexes [][]string
+}
+
+// NewMemoryService creates the service keeping the documents in memory, the documents are lost when
+// the process exits. Only the WithUniqueIndex options are relevant, the other options are ignored.
+func NewMemoryService[DocType interface{}](opts ...MongoServiceOption) DbService[DocType] {
+	config := MongoServiceConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &memorySvc[DocType]{
+		documents:     map[string]bson.Raw{},
+		uniqueIndexes: config.UniqueIndexes,
+	}
+}
+
+func (this *memorySvc[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, exists := this.documents[id]; exists {
+		return ErrConflict
+	}
+	if err := this.checkUniqueIndexes(id, raw); err != nil {
+		return err
+	}
+	this.documents[id] = raw
+	return nil
+}
+
+func (this *memorySvc[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	raw, exists := this.documents[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return decodeDocument[DocType](raw)
+}
+
+// FindDocuments returns the documents matching the filter. The filter supports the equality of the fields
+// and the $eq, $ne, $gt, $gte, $lt, $lte, and $in operators, nested fields are referred by the dotted names.
+func (this *memorySvc[DocType]) FindDocuments(ctx context.Context, filter bson.M, opts ...QueryOption) ([]*DocType, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	query := queryOptions{limit: maxQueryResults}
+	for _, opt := range opts {
+		opt(&query)
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	rawFilter, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	this.lock.RLock()
+	matching := []bson.Raw{}
+	for _, raw := range this.documents {
+		matches, err := matchesFilter(raw, rawFilter)
+		if err != nil {
+			this.lock.RUnlock()
+			return nil, err
+		}
+		if matches {
+			matching = append(matching, raw)
+		}
+	}
+	this.lock.RUnlock()
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		for _, key := range query.sort {
+			order := compareValues(lookupField(matching[i], key.Key), lookupField(matching[j], key.Key))
+			if direction, _ := key.Value.(int); direction < 0 {
+				order = -order
+			}
+			if order != 0 {
+				return order < 0
+			}
+		}
+		return false
+	})
+	if int64(len(matching)) > query.limit {
+		matching = matching[:query.limit]
+	}
+
+	documents := []*DocType{}
+	for _, raw := range matching {
+		document, err := decodeDocument[DocType](raw)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// ListDocumentsAfter returns the page of documents with the id greater than afterId, ordered by id,
+// with the same cursor semantics as the MongoDB service
+func (this *memorySvc[DocType]) ListDocumentsAfter(ctx context.Context, afterId string, limit int64) ([]*DocType, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	this.lock.RLock()
+	ids := []string{}
+	for id := range this.documents {
+		if afterId == "" || id > afterId {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if int64(len(ids)) > limit {
+		ids = ids[:limit]
+	}
+	page := make([]bson.Raw, 0, len(ids))
+	for _, id := range ids {
+		page = append(page, this.documents[id])
+	}
+	this.lock.RUnlock()
+
+	documents := []*DocType{}
+	nextId := ""
+	for i, raw := range page {
+		document, err := decodeDocument[DocType](raw)
+		if err != nil {
+			return nil, "", err
+		}
+		documents = append(documents, document)
+		nextId = ids[i]
+	}
+	return documents, nextId, nil
+}
+
+func (this *memorySvc[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, exists := this.documents[id]; !exists {
+		return ErrNotFound
+	}
+	if err := this.checkUniqueIndexes(id, raw); err != nil {
+		return err
+	}
+	this.documents[id] = raw
+	return nil
+}
+
+// UpdateDocumentIf replaces the document only if the stored one satisfies the condition, the condition
+// supports the same operators as the filter of FindDocuments
+func (this *memorySvc[DocType]) UpdateDocumentIf(ctx context.Context, id string, document *DocType, condition bson.M) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	rawCondition, err := bson.Marshal(condition)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	stored, exists := this.documents[id]
+	if !exists {
+		return ErrNotFound
+	}
+	matches, err := matchesFilter(stored, rawCondition)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return ErrPreconditionFailed
+	}
+	if err := this.checkUniqueIndexes(id, raw); err != nil {
+		return err
+	}
+	this.documents[id] = raw
+	return nil
+}
+
+// incremented adds the integer amount to the stored integer value, the missing value is zero
+func incremented(current interface{}, by interface{}) (interface{}, error) {
+	var amount int64
+	switch by := by.(type) {
+	case int:
+		amount = int64(by)
+	case int32:
+		amount = int64(by)
+	case int64:
+		amount = by
+	default:
+		return nil, fmt.Errorf("amount %v is not an integer", by)
+	}
+	switch current := current.(type) {
+	case nil:
+		return amount, nil
+	case int32:
+		return int64(current) + amount, nil
+	case int64:
+		return current + amount, nil
+	default:
+		return nil, fmt.Errorf("value %v is not an integer", current)
+	}
+}
+
+// UpdateFields sets only the given top-level fields of the document and increments the given numeric
+// fields, the missing ones are considered zero. The field names are the names of the stored document fields.
+func (this *memorySvc[DocType]) UpdateFields(ctx context.Context, id string, fields bson.M, increments bson.M) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	stored, exists := this.documents[id]
+	if !exists {
+		return ErrNotFound
+	}
+	document := bson.D{}
+	if err := bson.Unmarshal(stored, &document); err != nil {
+		return err
+	}
+	set := func(name string, value interface{}) {
+		for i := range document {
+			if document[i].Key == name {
+				document[i].Value = value
+				return
+			}
+		}
+		document = append(document, bson.E{Key: name, Value: value})
+	}
+	for name, value := range fields {
+		set(name, value)
+	}
+	for name, by := range increments {
+		var current interface{}
+		for _, element := range document {
+			if element.Key == name {
+				current = element.Value
+			}
+		}
+		value, err := incremented(current, by)
+		if err != nil {
+			return fmt.Errorf("cannot increment field %v: %w", name, err)
+		}
+		set(name, value)
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if err := this.checkUniqueIndexes(id, raw); err != nil {
+		return err
+	}
+	this.documents[id] = raw
+	return nil
+}
+
+func (this *memorySvc[DocType]) UpsertDocument(ctx context.Context, id string, document *DocType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if err := this.checkUniqueIndexes(id, raw); err != nil {
+		return err
+	}
+	this.documents[id] = raw
+	return nil
+}
+
+func (this *memorySvc[DocType]) DeleteDocument(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, exists := this.documents[id]; !exists {
+		return ErrNotFound
+	}
+	delete(this.documents, id)
+	return nil
+}
+
+// Disconnect keeps the documents, the service remains usable
+func (this *memorySvc[DocType]) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+// checkUniqueIndexes verifies no other document has the same values of the fields of any unique index,
+// missing fields are considered equal as with the MongoDB indexes. Must be called with the lock held.
+func (this *memorySvc[DocType]) checkUniqueIndexes(id string, raw bson.Raw) error {
+	for _, fields := range this.uniqueIndexes {
+		for otherId, other := range this.documents {
+			if otherId == id {
+				continue
+			}
+			duplicate := true
+			for _, field := range fields {
+				value, otherValue := lookupField(raw, field), lookupField(other, field)
+				if value.Type != otherValue.Type || !bytes.Equal(value.Value, otherValue.Value) {
+					duplicate = false
+					break
+				}
+			}
+			if duplicate {
+				return ErrConflict
+			}
+		}
+	}
+	return nil
+}
+
+// decodeDocument decodes the document from its stored BSON form
+func decodeDocument[DocType interface{}](raw bson.Raw) (*DocType, error) {
+	return decodeWith[DocType](func(value interface{}) error { return bson.Unmarshal(raw, value) })
+}
+
+// lookupField provides the value of the possibly nested field, missing fields are null as in MongoDB
+func lookupField(raw bson.Raw, field string) bson.RawValue {
+	value, err := raw.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return bson.RawValue{Type: bsontype.Null}
+	}
+	return value
+}
+
+// matchesFilter evaluates the filter marshaled to BSON against the stored document
+func matchesFilter(raw bson.Raw, filter bson.Raw) (bool, error) {
+	elements, err := filter.Elements()
+	if err != nil {
+		return false, err
+	}
+	for _, element := range elements {
+		value := lookupField(raw, element.Key())
+		condition := element.Value()
+
+		operators, isDocument := condition.DocumentOK()
+		if isDocument {
+			if first, err := operators.IndexErr(0); err == nil && strings.HasPrefix(first.Key(), "$") {
+				matches, err := matchesOperators(value, operators)
+				if err != nil || !matches {
+					return false, err
+				}
+				continue
+			}
+		}
+		if !equalValues(value, condition) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchesOperators(value bson.RawValue, operators bson.Raw) (bool, error) {
+	elements, err := operators.Elements()
+	if err != nil {
+		return false, err
+	}
+	for _, element := range elements {
+		operand := element.Value()
+		var matches bool
+		switch element.Key() {
+		case "$eq":
+			matches = equalValues(value, operand)
+		case "$ne":
+			matches = !equalValues(value, operand)
+		case "$gt":
+			matches = orderable(value, operand) && compareValues(value, operand) > 0
+		case "$gte":
+			matches = orderable(value, operand) && compareValues(value, operand) >= 0
+		case "$lt":
+			matches = orderable(value, operand) && compareValues(value, operand) < 0
+		case "$lte":
+			matches = orderable(value, operand) && compareValues(value, operand) <= 0
+		case "$in":
+			candidates, ok := operand.ArrayOK()
+			if !ok {
+				return false, fmt.Errorf("$in requires an array")
+			}
+			values, err := candidates.Values()
+			if err != nil {
+				return false, err
+			}
+			for _, candidate := range values {
+				if equalValues(value, candidate) {
+					matches = true
+					break
+				}
+			}
+		default:
+			return false, fmt.Errorf("unsupported filter operator %v", element.Key())
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// orderable reports whether the values are of the same kind, so that their order is meaningful
+func orderable(left, right bson.RawValue) bool {
+	return valueKind(left) == valueKind(right) && valueKind(left) != ""
+}
+
+func equalValues(left, right bson.RawValue) bool {
+	if orderable(left, right) {
+		return compareValues(left, right) == 0
+	}
+	return left.Type == right.Type && bytes.Equal(left.Value, right.Value)
+}
+
+func valueKind(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.Int32, bsontype.Int64, bsontype.Double:
+		return "number"
+	case bsontype.String:
+		return "string"
+	case bsontype.DateTime:
+		return "datetime"
+	case bsontype.Boolean:
+		return "boolean"
+	default:
+		return ""
+	}
+}
+
+// compareValues orders the values of the same kind, values of different kinds are ordered by their kind
+// with the missing values first
+func compareValues(left, right bson.RawValue) int {
+	if leftKind, rightKind := valueKind(left), valueKind(right); leftKind != rightKind {
+		return strings.Compare(leftKind, rightKind)
+	}
+	switch valueKind(left) {
+	case "number":
+		leftNumber, rightNumber := numberValue(left), numberValue(right)
+		switch {
+		case leftNumber < rightNumber:
+			return -1
+		case leftNumber > rightNumber:
+			return 1
+		}
+		return 0
+	case "string":
+		return strings.Compare(left.StringValue(), right.StringValue())
+	case "datetime":
+		leftTime, rightTime := left.DateTime(), right.DateTime()
+		switch {
+		case leftTime < rightTime:
+			return -1
+		case leftTime > rightTime:
+			return 1
+		}
+		return 0
+	case "boolean":
+		leftBool, rightBool := left.Boolean(), right.Boolean()
+		switch {
+		case leftBool == rightBool:
+			return 0
+		case !leftBool:
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(left.Value, right.Value)
+}
+
+func numberValue(value bson.RawValue) float64 {
+	switch value.Type {
+	case bsontype.Int32:
+		return float64(value.Int32())
+	case bsontype.Int64:
+		return float64(value.Int64())
+	default:
+		return value.Double()
+	}
+}
@@ -0,0 +1,211 @@
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type MemorySvcSuite struct {
+	suite.Suite
+}
+
+func TestMemorySvcSuite(t *testing.T) {
+	suite.Run(t, new(MemorySvcSuite))
+}
+
+type testEvent struct {
+	Id        string
+	Owner     string
+	Sequence  int
+	Timestamp time.Time
+}
+
+func (suite *MemorySvcSuite) Test_Crud_SameErrorsAsMongo() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testDocument]()
+
+	// ACT & ASSERT
+	suite.NoError(sut.CreateDocument(ctx, "a", &testDocument{Id: "a", Name: "first"}))
+	suite.ErrorIs(sut.CreateDocument(ctx, "a", &testDocument{Id: "a", Name: "again"}), ErrConflict)
+
+	found, err := sut.FindDocument(ctx, "a")
+	suite.Require().NoError(err)
+	suite.Equal("first", found.Name)
+	_, err = sut.FindDocument(ctx, "missing")
+	suite.ErrorIs(err, ErrNotFound)
+
+	suite.NoError(sut.UpdateDocument(ctx, "a", &testDocument{Id: "a", Name: "updated"}))
+	suite.ErrorIs(sut.UpdateDocument(ctx, "missing", &testDocument{Id: "missing"}), ErrNotFound)
+
+	suite.NoError(sut.UpdateFields(ctx, "a", bson.M{"name": "patched"}, nil))
+	suite.ErrorIs(sut.UpdateFields(ctx, "missing", bson.M{"name": "patched"}, nil), ErrNotFound)
+	found, _ = sut.FindDocument(ctx, "a")
+	suite.Equal(&testDocument{Id: "a", Name: "patched"}, found)
+
+	suite.NoError(sut.UpsertDocument(ctx, "a", &testDocument{Id: "a", Name: "replaced"}))
+	suite.NoError(sut.UpsertDocument(ctx, "b", &testDocument{Id: "b", Name: "inserted"}))
+	found, _ = sut.FindDocument(ctx, "a")
+	suite.Equal("replaced", found.Name)
+	found, _ = sut.FindDocument(ctx, "b")
+	suite.Equal("inserted", found.Name)
+
+	suite.NoError(sut.DeleteDocument(ctx, "a"))
+	suite.ErrorIs(sut.DeleteDocument(ctx, "a"), ErrNotFound)
+	_, err = sut.FindDocument(ctx, "a")
+	suite.ErrorIs(err, ErrNotFound)
+}
+
+func (suite *MemorySvcSuite) Test_FindDocument_ReturnsCopy() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testDocument]()
+	document := &testDocument{Id: "a", Name: "first"}
+	suite.Require().NoError(sut.CreateDocument(ctx, "a", document))
+
+	// ACT
+	document.Name = "changed by caller"
+	found, _ := sut.FindDocument(ctx, "a")
+	found.Name = "changed by reader"
+
+	// ASSERT
+	stored, _ := sut.FindDocument(ctx, "a")
+	suite.Equal("first", stored.Name)
+}
+
+func (suite *MemorySvcSuite) Test_UpdateFields_IncrementsCounters() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testEvent]()
+	suite.Require().NoError(sut.CreateDocument(ctx, "a", &testEvent{Id: "a", Sequence: 1}))
+
+	// ACT
+	err := sut.UpdateFields(ctx, "a", bson.M{"owner": "renamed"}, bson.M{"sequence": 2})
+
+	// ASSERT
+	suite.NoError(err)
+	found, _ := sut.FindDocument(ctx, "a")
+	suite.Equal(&testEvent{Id: "a", Owner: "renamed", Sequence: 3}, found)
+	suite.Error(sut.UpdateFields(ctx, "a", bson.M{}, bson.M{"owner": 1}))
+}
+
+func (suite *MemorySvcSuite) Test_UpdateDocumentIf_ReplacesOnlyMatchingDocument() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testEvent]()
+	suite.Require().NoError(sut.CreateDocument(ctx, "a", &testEvent{Id: "a", Sequence: 1}))
+
+	// ACT & ASSERT
+	suite.ErrorIs(sut.UpdateDocumentIf(ctx, "a", &testEvent{Id: "a", Sequence: 3}, bson.M{"sequence": 2}), ErrPreconditionFailed)
+	suite.NoError(sut.UpdateDocumentIf(ctx, "a", &testEvent{Id: "a", Sequence: 2}, bson.M{"sequence": 1}))
+	suite.ErrorIs(sut.UpdateDocumentIf(ctx, "missing", &testEvent{Id: "missing"}, bson.M{"sequence": 1}), ErrNotFound)
+	// missing fields match the null as in MongoDB
+	suite.NoError(sut.UpdateDocumentIf(ctx, "a", &testEvent{Id: "a", Sequence: 5}, bson.M{"owner": bson.M{"$in": bson.A{"", nil}}}))
+	found, _ := sut.FindDocument(ctx, "a")
+	suite.Equal(5, found.Sequence)
+}
+
+func (suite *MemorySvcSuite) Test_UniqueIndex_Conflicts() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testEvent](WithUniqueIndex("owner", "sequence"))
+	suite.Require().NoError(sut.CreateDocument(ctx, "a", &testEvent{Id: "a", Owner: "x", Sequence: 1}))
+
+	// ACT & ASSERT
+	suite.ErrorIs(sut.CreateDocument(ctx, "b", &testEvent{Id: "b", Owner: "x", Sequence: 1}), ErrConflict)
+	suite.NoError(sut.CreateDocument(ctx, "c", &testEvent{Id: "c", Owner: "y", Sequence: 1}))
+	suite.ErrorIs(sut.UpsertDocument(ctx, "c", &testEvent{Id: "c", Owner: "x", Sequence: 1}), ErrConflict)
+	suite.NoError(sut.UpdateDocument(ctx, "a", &testEvent{Id: "a", Owner: "x", Sequence: 1}))
+}
+
+func (suite *MemorySvcSuite) Test_FindDocuments_FiltersSortsAndLimits() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testEvent]()
+	start := time.Date(2038, 12, 24, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("event-%d", i)
+		owner := "x"
+		if i%2 == 1 {
+			owner = "y"
+		}
+		suite.Require().NoError(sut.CreateDocument(ctx, id, &testEvent{
+			Id: id, Owner: owner, Sequence: i, Timestamp: start.Add(time.Duration(4-i) * time.Minute),
+		}))
+	}
+
+	// ACT
+	found, err := sut.FindDocuments(
+		ctx,
+		bson.M{"owner": "x", "timestamp": bson.M{"$gte": start, "$lt": start.Add(4 * time.Minute)}},
+		WithSort("timestamp", true),
+	)
+	limited, _ := sut.FindDocuments(ctx, bson.M{"sequence": bson.M{"$in": []int{1, 2, 3}}}, WithSort("sequence", false), WithLimit(2))
+
+	// ASSERT
+	suite.Require().NoError(err)
+	suite.Require().Len(found, 2)
+	suite.Equal("event-4", found[0].Id)
+	suite.Equal("event-2", found[1].Id)
+	suite.Require().Len(limited, 2)
+	suite.Equal("event-3", limited[0].Id)
+	suite.Equal("event-2", limited[1].Id)
+}
+
+func (suite *MemorySvcSuite) Test_ListDocumentsAfter_WalksPageByPage() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testDocument]()
+	for _, id := range []string{"c", "a", "b"} {
+		suite.Require().NoError(sut.CreateDocument(ctx, id, &testDocument{Id: id}))
+	}
+
+	// ACT
+	first, cursor, err := sut.ListDocumentsAfter(ctx, "", 2)
+	suite.Require().NoError(err)
+	second, next, _ := sut.ListDocumentsAfter(ctx, cursor, 2)
+	last, end, _ := sut.ListDocumentsAfter(ctx, next, 2)
+
+	// ASSERT
+	suite.Equal([]*testDocument{{Id: "a"}, {Id: "b"}}, first)
+	suite.Equal("b", cursor)
+	suite.Equal([]*testDocument{{Id: "c"}}, second)
+	suite.Empty(last)
+	suite.Empty(end)
+}
+
+func (suite *MemorySvcSuite) Test_ConcurrentCreates_OnlyOneSucceeds() {
+	// ARRANGE
+	ctx := context.Background()
+	sut := NewMemoryService[testDocument]()
+	var wait sync.WaitGroup
+	results := make(chan error, 20)
+
+	// ACT
+	for i := 0; i < cap(results); i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			results <- sut.CreateDocument(ctx, "a", &testDocument{Id: "a", Name: fmt.Sprint(i)})
+		}(i)
+	}
+	wait.Wait()
+	close(results)
+
+	// ASSERT
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else {
+			suite.ErrorIs(err, ErrConflict)
+		}
+	}
+	suite.Equal(1, succeeded)
+}
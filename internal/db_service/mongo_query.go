@@ -0,0 +1,103 @@
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maximal number of documents returned by FindDocuments, guards against unbounded result sets
+const maxQueryResults int64 = 1000
+
+type queryOptions struct {
+	sort  bson.D
+	limit int64
+}
+
+type QueryOption func(options *queryOptions)
+
+// WithSort orders the query results by the field, options are applied in the order of the sort priority
+func WithSort(field string, ascending bool) QueryOption {
+	return func(options *queryOptions) {
+		direction := 1
+		if !ascending {
+			direction = -1
+		}
+		options.sort = append(options.sort, bson.E{Key: field, Value: direction})
+	}
+}
+
+// WithLimit limits the number of the query results, the limit is capped by the maximal number of results
+func WithLimit(limit int64) QueryOption {
+	return func(options *queryOptions) {
+		if limit <= 0 {
+			log.Printf("Invalid query limit: %v, limit must be positive", limit)
+			return
+		}
+		options.limit = min(limit, maxQueryResults)
+	}
+}
+
+// FindDocuments returns the documents matching the filter. Field names of the filter are the lowercased
+// names of the document fields. At most maxQueryResults documents are returned unless a lower limit is requested.
+func (this *mongoSvc[DocType]) FindDocuments(ctx context.Context, filter bson.M, opts ...QueryOption) ([]*DocType, error) {
+	ctx, span := tracer.Start(
+		ctx, "mongoSvc.FindDocuments",
+		trace.WithAttributes(attribute.String("filter", fmt.Sprint(filter))),
+	)
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "FindDocuments", "", time.Now())
+
+	query := queryOptions{limit: maxQueryResults}
+	for _, opt := range opts {
+		opt(&query)
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.readTimeout())
+	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	client, err := this.connectRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := &options.FindOptions{
+		Limit:   &query.limit,
+		Comment: traceComment(ctx),
+	}
+	if len(query.sort) > 0 {
+		findOptions.Sort = query.sort
+	}
+
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.FindDocuments failed")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents, _, err := this.decodeCursor(ctx, span, cursor)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.FindDocuments failed")
+		return nil, err
+	}
+	return documents, nil
+}
@@ -0,0 +1,61 @@
+package db_service
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func (suite *MongoSvcSuite) Test_FindDocuments_PassesFilterAndDecodesResults() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("filters", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "north"}},
+				bson.D{{Key: "id", Value: "c"}, {Key: "name", Value: "north"}},
+			),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "id", Value: "b"}, {Key: "name", Value: "south"}},
+			),
+		)
+
+		// ACT
+		north, err := sut.FindDocuments(context.Background(), bson.M{"name": "north"}, WithSort("id", false), WithLimit(10))
+		suite.Require().NoError(err)
+		northCommand := mt.GetStartedEvent().Command
+
+		south, err := sut.FindDocuments(context.Background(), bson.M{"id": bson.M{"$in": []string{"b"}}})
+		suite.Require().NoError(err)
+		southCommand := mt.GetStartedEvent().Command
+
+		// ASSERT
+		suite.Len(north, 2)
+		suite.Equal("a", north[0].Id)
+		suite.Equal("c", north[1].Id)
+		suite.Equal("north", northCommand.Lookup("filter", "name").StringValue())
+		suite.Equal(int64(-1), northCommand.Lookup("sort", "id").AsInt64())
+		suite.Equal(int64(10), northCommand.Lookup("limit").AsInt64())
+
+		suite.Len(south, 1)
+		suite.Equal("south", south[0].Name)
+		suite.Equal("b", southCommand.Lookup("filter", "id", "$in", "0").StringValue())
+		suite.Equal(maxQueryResults, southCommand.Lookup("limit").AsInt64())
+		_, hasSort := southCommand.LookupErr("sort")
+		suite.Error(hasSort, "unsorted query must not send sort")
+	})
+}
+
+func (suite *MongoSvcSuite) Test_WithLimit_CappedByMaxResults() {
+	// ARRANGE
+	query := queryOptions{limit: maxQueryResults}
+
+	// ACT
+	WithLimit(maxQueryResults * 10)(&query)
+
+	// ASSERT
+	suite.Equal(maxQueryResults, query.limit)
+}
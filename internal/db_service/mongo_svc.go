@@ -2,17 +2,24 @@ package db_service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -22,16 +29,102 @@ import (
 type DbService[DocType interface{}] interface {
 	CreateDocument(ctx context.Context, id string, document *DocType) error
 	FindDocument(ctx context.Context, id string) (*DocType, error)
+	FindDocuments(ctx context.Context, filter bson.M, opts ...QueryOption) ([]*DocType, error)
+	ListDocumentsAfter(ctx context.Context, afterId string, limit int64) ([]*DocType, string, error)
 	UpdateDocument(ctx context.Context, id string, document *DocType) error
+	UpdateDocumentIf(ctx context.Context, id string, document *DocType, condition bson.M) error
+	UpdateFields(ctx context.Context, id string, fields bson.M, increments bson.M) error
+	UpsertDocument(ctx context.Context, id string, document *DocType) error
 	DeleteDocument(ctx context.Context, id string) error
 	Disconnect(ctx context.Context) error
 }
 
+// Reconnector is implemented by the services able to replace their database connection at runtime,
+// for example after the credentials were rotated
+type Reconnector interface {
+	Reconnect(ctx context.Context) error
+}
+
+// IndexManager is implemented by the services able to create the indexes enforcing the constraints
+// of the stored documents
+type IndexManager interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// Pinger is implemented by the services able to verify the database is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ConfigProvider is implemented by the services able to report the configuration resolved
+// from the options and the environment
+type ConfigProvider interface {
+	EffectiveConfig() MongoServiceConfig
+}
+
+// context key marking the reads which are the part of an update
+type readForUpdateKey struct{}
+
+// ReadForUpdate marks the reads of the context as the part of an update of the read documents.
+// Such reads are served by the deployment receiving the writes, so that the update is not based on
+// the stale document of the read deployment.
+func ReadForUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readForUpdateKey{}, true)
+}
+
+func isReadForUpdate(ctx context.Context) bool {
+	forUpdate, _ := ctx.Value(readForUpdateKey{}).(bool)
+	return forUpdate
+}
+
 var ErrNotFound = fmt.Errorf("document not found")
 var ErrConflict = fmt.Errorf("conflict: document already exists")
+var ErrUnavailable = fmt.Errorf("database unavailable")
+
+// ErrPreconditionFailed is returned by the conditional updates when the stored document does not satisfy
+// the condition anymore, e.g. it was changed by other writer since it was read
+var ErrPreconditionFailed = fmt.Errorf("document does not satisfy the condition of the update")
+
+// ErrUndecodable is returned when the stored document cannot be decoded into the document type,
+// it wraps the cause of the failure
+var ErrUndecodable = fmt.Errorf("stored document cannot be decoded")
+
+// decodeWith decodes the document into the newly allocated value, so that the successful decode
+// always provides the non-nil document, even if the stored document has none of its fields
+func decodeWith[DocType interface{}](decode func(value interface{}) error) (*DocType, error) {
+	document := new(DocType)
+	if err := decode(document); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUndecodable, err)
+	}
+	return document, nil
+}
+
+// ErrOverloaded is returned when the operation did not get its turn before its deadline,
+// it is a kind of ErrUnavailable
+var ErrOverloaded = fmt.Errorf("%w: too many concurrent operations", ErrUnavailable)
+
+// IsUnavailable reports whether the operation failed because the database could not be reached,
+// in contrast to the failures of the operation itself. Such operations may succeed when retried later.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrUnavailable) ||
+		errors.As(err, &topology.ServerSelectionError{}) ||
+		mongo.IsNetworkError(err)
+}
 
 var tracer = otel.Tracer("db_service")
 
+// handling of the undecodable documents, see MongoServiceConfig.DecodeMode
+const (
+	DecodeStrict  = "strict"
+	DecodeLenient = "lenient"
+)
+
+// page size used by ListDocumentsAfter when no positive limit is provided
+const defaultPageSize int64 = 100
+
 type MongoServiceConfig struct {
 	ServerHost string
 	ServerPort int
@@ -40,17 +133,135 @@ type MongoServiceConfig struct {
 	DbName     string
 	Collection string
 	Timeout    time.Duration
+	// ConnectTimeout limits establishing of a new connection to the server, including
+	// the DNS resolution and the handshake; it is independent of the operation timeouts
+	ConnectTimeout time.Duration
+	// ServerSelectionTimeout limits waiting for a suitable server, e.g. for the primary during
+	// the network partition or the election; the driver default of 30 seconds is used if not set
+	ServerSelectionTimeout time.Duration
+	// HeartbeatInterval is the period of monitoring the servers of the deployment, shorter interval
+	// detects the failover sooner; the driver default of 10 seconds is used if not set
+	HeartbeatInterval time.Duration
+	// ReadTimeout limits the find operations, Timeout is used if not set
+	ReadTimeout time.Duration
+	// WriteTimeout limits the create, update, and delete operations, Timeout is used if not set.
+	// Writes waiting for the majority write concern may need longer than reads.
+	WriteTimeout time.Duration
+	// WriteConcern requested from the MongoDB server for write operations:
+	// "majority" waits until the write is replicated to the majority of the replica set
+	// members and survives a primary failover, but adds replication latency to each write;
+	// "1" acknowledges the write once the primary applied it, which may be rolled back on failover;
+	// "0" does not wait for any acknowledgement and write errors are not reported back.
+	// Empty value keeps the driver default.
+	WriteConcern string
+	// Compressors lists the wire compression algorithms offered to the server in the order of preference,
+	// any of "zstd", "snappy", "zlib". Compression reduces the bandwidth over the slow or metered links
+	// to a remote database at the cost of the CPU time spent on both sides; it is rarely worth it
+	// within the same data center. Empty list disables the compression.
+	Compressors []string
+	// SlowOperationThreshold is the duration of the operation after which the operation is reported
+	// as slow in the log and in the trace
+	SlowOperationThreshold time.Duration
+	// AppName identifies the connections of this service in the MongoDB server logs and in currentOp
+	AppName string
+	// MaxConcurrentOperations limits the number of the database operations in flight, further operations
+	// wait for their turn until their deadline. Zero means unlimited.
+	MaxConcurrentOperations int
+	// ReadConnectionString is the MongoDB URI of the deployment serving the reads, e.g. the cluster
+	// replicated from the one receiving the writes. The finds and listings use it, unless the read
+	// is the part of an update - see ReadForUpdate. Reads use the single client if not set.
+	ReadConnectionString string
+	// DecodeMode selects the handling of the stored documents which cannot be decoded, e.g. corrupted
+	// or written by a legacy version of the service: DecodeStrict fails the whole operation,
+	// DecodeLenient skips such documents in the listings and logs their ids. Single documents requested
+	// by their id always fail to load. Strict mode is used if not set.
+	DecodeMode string
+	// UniqueIndexes lists the combinations of the document fields that must be unique across
+	// the collection, the indexes are created by EnsureIndexes
+	UniqueIndexes [][]string
+}
+
+// MongoServiceOption customizes the service configuration for the specific call site,
+// options take precedence over the configuration provided by the environment
+type MongoServiceOption func(config *MongoServiceConfig)
+
+// WithCollection binds the service to the named collection, so that services of different
+// document types can share the database without sharing the collection
+func WithCollection(name string) MongoServiceOption {
+	return func(config *MongoServiceConfig) {
+		if name == "" {
+			log.Printf("Invalid collection name: collection name cannot be empty")
+			return
+		}
+		config.Collection = name
+	}
+}
+
+// used to create the database clients, replaced in tests
+var mongoConnect = mongo.Connect
+
+// used to limit the duration of the operations, replaced in tests
+var contextWithTimeout = context.WithTimeout
+
+// WithUniqueIndex requires the combination of the fields to be unique across the collection,
+// so that concurrent instances of the service cannot store conflicting documents.
+// Fields are referred by their stored (lowercase) names.
+func WithUniqueIndex(fields ...string) MongoServiceOption {
+	return func(config *MongoServiceConfig) {
+		if len(fields) == 0 {
+			log.Printf("Invalid unique index: at least one field is required")
+			return
+		}
+		config.UniqueIndexes = append(config.UniqueIndexes, fields)
+	}
 }
 
 type mongoSvc[DocType interface{}] struct {
 	MongoServiceConfig
-	client     atomic.Pointer[mongo.Client]
+	// configuration provided by the caller, resolved again against the environment on Reconnect
+	config MongoServiceConfig
+	opts   []MongoServiceOption
+	client atomic.Pointer[mongo.Client]
+	// client of the read deployment, nil if not connected or not configured
+	readClient atomic.Pointer[mongo.Client]
 	clientLock sync.Mutex
+	// held for reading by the database operations and exclusively by Reconnect,
+	// so that the configuration and client are not replaced under the running operation
+	operationsLock sync.RWMutex
+	// buffered channel used as the semaphore of the in-flight operations, nil if not limited
+	operationSlots chan struct{}
 }
 
 func NewMongoService[DocType interface{}](
 	config MongoServiceConfig,
+	opts ...MongoServiceOption,
 ) DbService[DocType] {
+	svc := &mongoSvc[DocType]{config: config, opts: opts}
+	svc.MongoServiceConfig = resolveConfig(config, opts)
+	if svc.MaxConcurrentOperations > 0 {
+		svc.operationSlots = make(chan struct{}, svc.MaxConcurrentOperations)
+	}
+	return svc
+}
+
+// acquireOperationSlot waits until the number of in-flight operations is below the limit,
+// the returned function must be called to release the slot when the operation is finished.
+// The limit is not changed on Reconnect.
+func (this *mongoSvc[DocType]) acquireOperationSlot(ctx context.Context) (func(), error) {
+	if this.operationSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case this.operationSlots <- struct{}{}:
+		return func() { <-this.operationSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrOverloaded, ctx.Err())
+	}
+}
+
+// resolveConfig applies the options on the provided configuration and fills the missing values
+// from the environment variables
+func resolveConfig(config MongoServiceConfig, opts []MongoServiceOption) MongoServiceConfig {
 	enviro := func(name string, defaultValue string) string {
 		if value, ok := os.LookupEnv(name); ok {
 			return value
@@ -58,65 +269,245 @@ func NewMongoService[DocType interface{}](
 		return defaultValue
 	}
 
-	svc := &mongoSvc[DocType]{}
-	svc.MongoServiceConfig = config
+	// optional timeouts, zero if not set or invalid
+	enviroSeconds := func(name string) time.Duration {
+		value := enviro(name, "")
+		if value == "" {
+			return 0
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			log.Printf("Invalid %v value: %v", name, value)
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
 
-	if svc.ServerHost == "" {
-		svc.ServerHost = enviro("AMBULANCE_API_MONGODB_HOST", "localhost")
+	if config.ServerHost == "" {
+		config.ServerHost = enviro("AMBULANCE_API_MONGODB_HOST", "localhost")
 	}
 
-	if svc.ServerPort == 0 {
+	if config.ServerPort == 0 {
 		port := enviro("AMBULANCE_API_MONGODB_PORT", "27017")
 		if port, err := strconv.Atoi(port); err == nil {
-			svc.ServerPort = port
+			config.ServerPort = port
 		} else {
 			log.Printf("Invalid port value: %v", port)
-			svc.ServerPort = 27017
+			config.ServerPort = 27017
 		}
 	}
 
-	if svc.UserName == "" {
-		svc.UserName = enviro("AMBULANCE_API_MONGODB_USERNAME", "")
+	if config.UserName == "" {
+		config.UserName = enviro("AMBULANCE_API_MONGODB_USERNAME", "")
 	}
 
-	if svc.Password == "" {
-		svc.Password = enviro("AMBULANCE_API_MONGODB_PASSWORD", "")
+	if config.Password == "" {
+		config.Password = enviro("AMBULANCE_API_MONGODB_PASSWORD", "")
 	}
 
-	if svc.DbName == "" {
-		svc.DbName = enviro("AMBULANCE_API_MONGODB_DATABASE", "milung-ambulance-wl")
+	if config.DbName == "" {
+		config.DbName = enviro("AMBULANCE_API_MONGODB_DATABASE", "milung-ambulance-wl")
 	}
 
-	if svc.Collection == "" {
-		svc.Collection = enviro("AMBULANCE_API_MONGODB_COLLECTION", "ambulance")
+	if config.Collection == "" {
+		config.Collection = enviro("AMBULANCE_API_MONGODB_COLLECTION", "ambulance")
 	}
 
-	if svc.Timeout == 0 {
+	if config.Timeout == 0 {
 		seconds := enviro("AMBULANCE_API_MONGODB_TIMEOUT_SECONDS", "10")
 		if seconds, err := strconv.Atoi(seconds); err == nil {
-			svc.Timeout = time.Duration(seconds) * time.Second
+			config.Timeout = time.Duration(seconds) * time.Second
 		} else {
 			log.Printf("Invalid timeout value: %v", seconds)
-			svc.Timeout = 10 * time.Second
+			config.Timeout = 10 * time.Second
+		}
+	}
+
+	if config.ConnectTimeout == 0 {
+		config.ConnectTimeout = enviroSeconds("AMBULANCE_API_MONGODB_CONNECT_TIMEOUT_SECONDS")
+		if config.ConnectTimeout == 0 {
+			config.ConnectTimeout = 10 * time.Second
+		}
+	}
+
+	if config.ServerSelectionTimeout == 0 {
+		config.ServerSelectionTimeout = enviroSeconds("AMBULANCE_API_MONGODB_SERVER_SELECTION_TIMEOUT_SECONDS")
+	}
+
+	if config.HeartbeatInterval == 0 {
+		config.HeartbeatInterval = enviroSeconds("AMBULANCE_API_MONGODB_HEARTBEAT_INTERVAL_SECONDS")
+	}
+
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = enviroSeconds("AMBULANCE_API_MONGODB_READ_TIMEOUT_SECONDS")
+	}
+
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = enviroSeconds("AMBULANCE_API_MONGODB_WRITE_TIMEOUT_SECONDS")
+	}
+
+	if config.SlowOperationThreshold == 0 {
+		milliseconds := enviro("AMBULANCE_API_SLOW_OP_MS", "500")
+		if milliseconds, err := strconv.Atoi(milliseconds); err == nil && milliseconds > 0 {
+			config.SlowOperationThreshold = time.Duration(milliseconds) * time.Millisecond
+		} else {
+			log.Printf("Invalid slow operation threshold value: %v", milliseconds)
+			config.SlowOperationThreshold = 500 * time.Millisecond
+		}
+	}
+
+	if config.AppName == "" {
+		config.AppName = enviro("AMBULANCE_API_MONGODB_APPNAME", "ambulance-webapi")
+	}
+
+	if config.MaxConcurrentOperations == 0 {
+		value := enviro("AMBULANCE_API_MONGODB_MAX_CONCURRENT", "0")
+		if value, err := strconv.Atoi(value); err == nil && value >= 0 {
+			config.MaxConcurrentOperations = value
+		} else {
+			log.Printf("Invalid maximum concurrent operations value: %v", value)
 		}
 	}
 
+	if config.WriteConcern == "" {
+		config.WriteConcern = enviro("AMBULANCE_API_MONGODB_WRITE_CONCERN", "")
+	}
+
+	if _, err := parseWriteConcern(config.WriteConcern); err != nil {
+		log.Printf("Invalid write concern value: %v", err)
+		config.WriteConcern = ""
+	}
+
+	if len(config.Compressors) == 0 {
+		config.Compressors = parseCompressors(enviro("AMBULANCE_API_MONGODB_COMPRESSORS", ""))
+	}
+
+	if err := validateCompressors(config.Compressors); err != nil {
+		log.Printf("Invalid compressors value: %v", err)
+		config.Compressors = nil
+	}
+
+	if config.ReadConnectionString == "" {
+		config.ReadConnectionString = enviro("AMBULANCE_API_MONGODB_READ_URI", "")
+	}
+
+	if config.DecodeMode == "" {
+		config.DecodeMode = enviro("AMBULANCE_API_MONGODB_DECODE_MODE", DecodeStrict)
+	}
+
+	switch config.DecodeMode = strings.ToLower(config.DecodeMode); config.DecodeMode {
+	case DecodeStrict, DecodeLenient:
+	default:
+		log.Printf("Invalid decode mode value: %v", config.DecodeMode)
+		config.DecodeMode = DecodeStrict
+	}
+
 	log.Printf(
 		"MongoDB config: //%v@%v:%v/%v/%v",
-		svc.UserName,
-		svc.ServerHost,
-		svc.ServerPort,
-		svc.DbName,
-		svc.Collection,
+		config.UserName,
+		config.ServerHost,
+		config.ServerPort,
+		config.DbName,
+		config.Collection,
 	)
-	return svc
+	return config
+}
+
+// placeholder of the secret values in the reported configuration
+const redactedValue = "***"
+
+// Redacted provides the copy of the configuration safe to be reported, the secrets are masked
+func (this MongoServiceConfig) Redacted() MongoServiceConfig {
+	if this.Password != "" {
+		this.Password = redactedValue
+	}
+	if this.ReadConnectionString != "" {
+		this.ReadConnectionString = redactedValue
+	}
+	return this
+}
+
+// EffectiveConfig provides the configuration currently used by the service, including secrets
+func (this *mongoSvc[DocType]) EffectiveConfig() MongoServiceConfig {
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	return this.MongoServiceConfig
+}
+
+// reportSlowOperation logs the operation taking longer than the configured threshold,
+// intended to be deferred at the start of the operation
+func (this *mongoSvc[DocType]) reportSlowOperation(span trace.Span, operation string, id string, start time.Time) {
+	elapsed := time.Since(start)
+	if this.SlowOperationThreshold <= 0 || elapsed < this.SlowOperationThreshold {
+		return
+	}
+	log.Printf(
+		"WARNING: slow database operation operation=%v collection=%v id=%v elapsed_ms=%v threshold_ms=%v",
+		operation, this.Collection, id, elapsed.Milliseconds(), this.SlowOperationThreshold.Milliseconds(),
+	)
+	span.AddEvent("slow operation", trace.WithAttributes(
+		attribute.String("operation", operation),
+		attribute.Int64("elapsed_ms", elapsed.Milliseconds()),
+	))
+}
+
+func (this *mongoSvc[DocType]) readTimeout() time.Duration {
+	if this.ReadTimeout > 0 {
+		return this.ReadTimeout
+	}
+	return this.Timeout
+}
+
+func (this *mongoSvc[DocType]) writeTimeout() time.Duration {
+	if this.WriteTimeout > 0 {
+		return this.WriteTimeout
+	}
+	return this.Timeout
 }
 
 func (this *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, error) {
 	ctx, span := tracer.Start(ctx, "mongoSvc.connect")
 	defer span.End()
+
+	var uri = fmt.Sprintf("mongodb://%v:%v", this.ServerHost, this.ServerPort)
+	return this.connectClient(ctx, &this.client, func() string {
+		log.Printf("Using URI: " + uri)
+		if len(this.UserName) != 0 {
+			return fmt.Sprintf("mongodb://%v:%v@%v:%v", this.UserName, this.Password, this.ServerHost, this.ServerPort)
+		}
+		return uri
+	})
+}
+
+// connectRead provides the client for the reads of the context, the client of the read deployment
+// if it is configured and the read is not the part of an update
+func (this *mongoSvc[DocType]) connectRead(ctx context.Context) (*mongo.Client, error) {
+	if this.ReadConnectionString == "" || isReadForUpdate(ctx) {
+		return this.connect(ctx)
+	}
+	ctx, span := tracer.Start(ctx, "mongoSvc.connectRead")
+	defer span.End()
+
+	return this.connectClient(ctx, &this.readClient, func() string {
+		// the connection string may contain the credentials
+		log.Printf("Using separate read connection")
+		return this.ReadConnectionString
+	})
+}
+
+// connectClient provides the client stored in the slot, the client is connected to the uri
+// and stored in the slot on the first use
+func (this *mongoSvc[DocType]) connectClient(
+	ctx context.Context,
+	slot *atomic.Pointer[mongo.Client],
+	uri func() string,
+) (*mongo.Client, error) {
 	// optimistic check
-	client := this.client.Load()
+	client := slot.Load()
 	if client != nil {
 		return client, nil
 	}
@@ -124,7 +515,7 @@ func (this *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, erro
 	this.clientLock.Lock()
 	defer this.clientLock.Unlock()
 	// pesimistic check
-	client = this.client.Load()
+	client = slot.Load()
 	if client != nil {
 		return client, nil
 	}
@@ -132,39 +523,200 @@ func (this *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, erro
 	ctx, contextCancel := context.WithTimeout(ctx, this.Timeout)
 	defer contextCancel()
 
-	var uri = fmt.Sprintf("mongodb://%v:%v", this.ServerHost, this.ServerPort)
-	log.Printf("Using URI: " + uri)
-
-	if len(this.UserName) != 0 {
-		uri = fmt.Sprintf("mongodb://%v:%v@%v:%v", this.UserName, this.Password, this.ServerHost, this.ServerPort)
+	clientOptions := options.Client().ApplyURI(uri()).SetConnectTimeout(this.ConnectTimeout)
+	if this.AppName != "" {
+		clientOptions.SetAppName(this.AppName)
 	}
-
-	if client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetConnectTimeout(10*time.Second)); err != nil {
+	if this.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(this.ServerSelectionTimeout)
+	}
+	if this.HeartbeatInterval > 0 {
+		clientOptions.SetHeartbeatInterval(this.HeartbeatInterval)
+	}
+	if writeConcern, err := parseWriteConcern(this.WriteConcern); err != nil {
 		return nil, err
+	} else if writeConcern != nil {
+		clientOptions.SetWriteConcern(writeConcern)
+	}
+	if len(this.Compressors) > 0 {
+		clientOptions.SetCompressors(this.Compressors)
+	}
+
+	if client, err := mongoConnect(ctx, clientOptions); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
 	} else {
-		this.client.Store(client)
+		slot.Store(client)
 		return client, nil
 	}
 }
 
+// parseWriteConcern maps the configured write concern value to the driver's write concern,
+// returns nil for an empty value so that the driver default is used
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	switch value {
+	case "":
+		return nil, nil
+	case "majority":
+		return writeconcern.Majority(), nil
+	}
+
+	w, err := strconv.Atoi(value)
+	if err != nil || w < 0 {
+		return nil, fmt.Errorf("unsupported write concern %q, expected \"majority\" or non-negative number", value)
+	}
+	return &writeconcern.WriteConcern{W: w}, nil
+}
+
+// wire compression algorithms supported by the driver
+var supportedCompressors = []string{"zstd", "snappy", "zlib"}
+
+// parseCompressors splits the comma separated list of the compressor names
+func parseCompressors(value string) []string {
+	var compressors []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			compressors = append(compressors, name)
+		}
+	}
+	return compressors
+}
+
+// validateCompressors verifies all the compressors are supported by the driver
+func validateCompressors(compressors []string) error {
+	for _, name := range compressors {
+		if !slices.Contains(supportedCompressors, name) {
+			return fmt.Errorf("unsupported compressor %q, expected any of %v", name, strings.Join(supportedCompressors, ", "))
+		}
+	}
+	return nil
+}
+
+// Reconnect re-reads the configuration from the environment and replaces the database client.
+// New operations are blocked until the in-flight operations finish and the new client is connected.
+func (this *mongoSvc[DocType]) Reconnect(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "mongoSvc.Reconnect")
+	defer span.End()
+
+	this.operationsLock.Lock()
+	defer this.operationsLock.Unlock()
+
+	this.MongoServiceConfig = resolveConfig(this.config, this.opts)
+	for _, slot := range []*atomic.Pointer[mongo.Client]{&this.client, &this.readClient} {
+		if previous := slot.Swap(nil); previous != nil {
+			if err := previous.Disconnect(ctx); err != nil {
+				// the old client is not used anymore, just report the failure
+				log.Printf("Failed to disconnect previous MongoDB client: %v", err)
+			}
+		}
+	}
+
+	if _, err := this.connect(ctx); err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.Reconnect failed")
+		return err
+	}
+	if _, err := this.connectRead(ctx); err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.Reconnect failed")
+		return err
+	}
+	return nil
+}
+
+// traceComment returns the trace id of the span active in the context. The id is attached as the comment
+// to the database commands, so that the operations found in the MongoDB profiler can be correlated
+// with the traces of the originating requests. Returns nil if the context is not traced.
+func traceComment(ctx context.Context) *string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return nil
+	}
+	comment := spanContext.TraceID().String()
+	return &comment
+}
+
+// traceCommentValue is the traceComment for the options accepting any comment value
+func traceCommentValue(ctx context.Context) interface{} {
+	if comment := traceComment(ctx); comment != nil {
+		return *comment
+	}
+	return nil
+}
+
+// EnsureIndexes creates the unique index of the document ids and the unique indexes configured
+// by WithUniqueIndex, existing indexes are kept
+func (this *mongoSvc[DocType]) EnsureIndexes(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "mongoSvc.EnsureIndexes")
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
+	defer contextCancel()
+	client, err := this.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexes := []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}}
+	for _, fields := range this.UniqueIndexes {
+		keys := bson.D{}
+		for _, field := range fields {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+		indexes = append(indexes, mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(true)})
+	}
+
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.EnsureIndexes failed")
+		return err
+	}
+	return nil
+}
+
+// Ping connects to the database and verifies the primary server responds within the configured timeout
+func (this *mongoSvc[DocType]) Ping(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "mongoSvc.Ping")
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.readTimeout())
+	defer contextCancel()
+	client, err := this.connect(ctx)
+	if err == nil {
+		err = client.Ping(ctx, readpref.Primary())
+	}
+	if err == nil && this.ReadConnectionString != "" {
+		if client, err = this.connectRead(ctx); err == nil {
+			// the read deployment is reachable if any of its members responds
+			err = client.Ping(ctx, readpref.Nearest())
+		}
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.Ping failed")
+	}
+	return err
+}
+
 func (this *mongoSvc[DocType]) Disconnect(ctx context.Context) error {
 	ctx, span := tracer.Start(ctx, "mongoSvc.Disconnect")
 	defer span.End()
-	client := this.client.Load()
-
-	if client != nil {
-		this.clientLock.Lock()
-		defer this.clientLock.Unlock()
+	this.clientLock.Lock()
+	defer this.clientLock.Unlock()
 
-		client = this.client.Load()
-		defer this.client.Store(nil)
-		if client != nil {
+	var errs []error
+	for _, slot := range []*atomic.Pointer[mongo.Client]{&this.readClient, &this.client} {
+		if client := slot.Swap(nil); client != nil {
 			if err := client.Disconnect(ctx); err != nil {
-				return err
+				errs = append(errs, err)
 			}
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (this *mongoSvc[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
@@ -173,16 +725,24 @@ func (this *mongoSvc[DocType]) CreateDocument(ctx context.Context, id string, do
 		trace.WithAttributes(attribute.String("id", id)),
 	)
 	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "CreateDocument", id, time.Now())
 
-	ctx, contextCancel := context.WithTimeout(ctx, this.Timeout)
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
 	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	client, err := this.connect(ctx)
 	if err != nil {
 		return err
 	}
 	db := client.Database(this.DbName)
 	collection := db.Collection(this.Collection)
-	result := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}})
+	result := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}}, &options.FindOneOptions{Comment: traceComment(ctx)})
 	switch result.Err() {
 	case nil: // no error means there is conflicting document
 		return ErrConflict
@@ -192,20 +752,36 @@ func (this *mongoSvc[DocType]) CreateDocument(ctx context.Context, id string, do
 		return result.Err()
 	}
 
-	_, err = collection.InsertOne(ctx, document)
+	_, err = collection.InsertOne(ctx, document, &options.InsertOneOptions{Comment: traceCommentValue(ctx)})
+	if mongo.IsDuplicateKeyError(err) {
+		// conflicting document was inserted concurrently or violates the unique index
+		return ErrConflict
+	}
 	return err
 }
 
+// FindDocument looks up the document by its string id field first. If there is no such document
+// and the id is a valid hex ObjectID, the document with the matching native _id is provided,
+// so that the documents inserted by tools using the native ids are found. The documents are
+// updated and deleted by the same lookup, see findById.
 func (this *mongoSvc[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
 	ctx, span := tracer.Start(
 		ctx, "mongoSvc.FindDocument",
 		trace.WithAttributes(attribute.String("id", id)),
 	)
 	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "FindDocument", id, time.Now())
 
-	ctx, contextCancel := context.WithTimeout(ctx, this.Timeout)
+	ctx, contextCancel := contextWithTimeout(ctx, this.readTimeout())
 	defer contextCancel()
-	client, err := this.connect(ctx)
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	client, err := this.connectRead(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +796,7 @@ func (this *mongoSvc[DocType]) FindDocument(ctx context.Context, id string) (*Do
 
 	db := client.Database(this.DbName)
 	collection := db.Collection(this.Collection)
-	result := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}})
+	result, key := findById(ctx, collection, id)
 	if result.Err() != nil {
 		findspan.SetStatus(codes.Error, "mongoSvc.FindDocument.find failed")
 		span.SetStatus(codes.Error, "mongoSvc.FindDocument.find failed")
@@ -233,11 +809,139 @@ func (this *mongoSvc[DocType]) FindDocument(ctx context.Context, id string) (*Do
 	default: // other errors - return them
 		return nil, result.Err()
 	}
-	var document *DocType
-	if err := result.Decode(&document); err != nil {
+	if _, byObjectId := key["_id"]; byObjectId {
+		// the id field is provided so that the document is stored back with it, see withStringId
+		return decodeWith[DocType](func(value interface{}) error {
+			raw, err := withStringId(result, id)
+			if err != nil {
+				return err
+			}
+			return bson.Unmarshal(raw, value)
+		})
+	}
+	return decodeWith[DocType](result.Decode)
+}
+
+// withStringId provides the document found by its native ObjectID with the string id field set to the hex
+// of the ObjectID. Otherwise the document decodes with the empty id and is stored back with it, so that the
+// next such document violates the unique index of the id field.
+func withStringId(result *mongo.SingleResult, id string) (bson.Raw, error) {
+	raw, err := result.Raw()
+	if err != nil {
 		return nil, err
 	}
-	return document, nil
+	document := bson.D{}
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return bson.Marshal(append(document, bson.E{Key: "id", Value: id}))
+}
+
+// findById looks up the document by its string id field first, then by the native _id if the id is a valid
+// hex ObjectID, since the documents inserted by other tools may be keyed only by the native ObjectID.
+// Provides the filter addressing the found document.
+func findById(ctx context.Context, collection *mongo.Collection, id string) (*mongo.SingleResult, bson.M) {
+	filter := bson.M{"id": id}
+	result := collection.FindOne(ctx, filter, &options.FindOneOptions{Comment: traceComment(ctx)})
+	if objectId, err := primitive.ObjectIDFromHex(id); err == nil && result.Err() == mongo.ErrNoDocuments {
+		trace.SpanFromContext(ctx).AddEvent("document not found by id, trying _id")
+		filter = bson.M{"_id": objectId}
+		result = collection.FindOne(ctx, filter, &options.FindOneOptions{Comment: traceComment(ctx)})
+	}
+	return result, filter
+}
+
+// ListDocumentsAfter returns the page of documents with the `id` greater than afterId, ordered by `id`,
+// and the id of the last returned document to be used as the cursor of the next page.
+// Empty afterId starts from the beginning, empty next cursor means there are no more documents.
+func (this *mongoSvc[DocType]) ListDocumentsAfter(ctx context.Context, afterId string, limit int64) ([]*DocType, string, error) {
+	ctx, span := tracer.Start(
+		ctx, "mongoSvc.ListDocumentsAfter",
+		trace.WithAttributes(
+			attribute.String("afterId", afterId),
+			attribute.Int64("limit", limit),
+		),
+	)
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "ListDocumentsAfter", afterId, time.Now())
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.readTimeout())
+	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+	client, err := this.connectRead(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	filter := bson.D{}
+	if afterId != "" {
+		filter = bson.D{{Key: "id", Value: bson.D{{Key: "$gt", Value: afterId}}}}
+	}
+
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	cursor, err := collection.Find(
+		ctx,
+		filter,
+		&options.FindOptions{
+			Sort:    bson.D{{Key: "id", Value: 1}},
+			Limit:   &limit,
+			Comment: traceComment(ctx),
+		},
+	)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.ListDocumentsAfter failed")
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	documents, nextId, err := this.decodeCursor(ctx, span, cursor)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.ListDocumentsAfter failed")
+		return nil, "", err
+	}
+	return documents, nextId, nil
+}
+
+// decodeCursor decodes the documents of the cursor and provides the id of the last visited document,
+// including the skipped one, so that the listing continues behind it. The undecodable documents fail
+// the decoding in the strict decode mode, in the lenient mode they are skipped and logged.
+func (this *mongoSvc[DocType]) decodeCursor(ctx context.Context, span trace.Span, cursor *mongo.Cursor) ([]*DocType, string, error) {
+	documents := []*DocType{}
+	lastId := ""
+	for cursor.Next(ctx) {
+		id, ok := cursor.Current.Lookup("id").StringValueOK()
+		if ok {
+			lastId = id
+		}
+		document, err := decodeWith[DocType](cursor.Decode)
+		if err != nil {
+			if this.DecodeMode != DecodeLenient {
+				return nil, "", err
+			}
+			log.Printf(
+				"WARNING: skipping undecodable document collection=%v id=%v error=%v",
+				this.Collection, id, err,
+			)
+			span.AddEvent("undecodable document skipped", trace.WithAttributes(attribute.String("id", id)))
+			continue
+		}
+		documents = append(documents, document)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, "", err
+	}
+	return documents, lastId, nil
 }
 
 func (this *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
@@ -247,9 +951,17 @@ func (this *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, do
 		trace.WithAttributes(attribute.String("id", id)),
 	)
 	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "UpdateDocument", id, time.Now())
 
-	ctx, contextCancel := context.WithTimeout(ctx, this.Timeout)
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
 	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	client, err := this.connect(ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, "mongoSvc.UpdateDocument failed")
@@ -265,7 +977,7 @@ func (this *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, do
 	defer findspan.End()
 	db := client.Database(this.DbName)
 	collection := db.Collection(this.Collection)
-	result := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}})
+	result, filter := findById(ctx, collection, id)
 	if result.Err() != nil {
 		findspan.SetStatus(codes.Error, "mongoSvc.UpdateDocument.find_replace failed")
 		span.SetStatus(codes.Error, "mongoSvc.UpdateDocument failed")
@@ -280,7 +992,12 @@ func (this *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, do
 		return result.Err()
 	}
 	findspan.AddEvent("document found")
-	_, err = collection.ReplaceOne(ctx, bson.D{{Key: "id", Value: id}}, document)
+	_, err = collection.ReplaceOne(
+		ctx,
+		filter,
+		document,
+		&options.ReplaceOptions{Comment: traceCommentValue(ctx)},
+	)
 	if err != nil {
 		findspan.AddEvent("document replace failed")
 		findspan.SetStatus(codes.Error, "mongoSvc.UpdateDocument.find_replace failed")
@@ -289,6 +1006,229 @@ func (this *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, do
 	return err
 }
 
+// UpdateDocumentIf replaces the document only if the stored one satisfies the condition, e.g. it still has
+// the version the change is based on. The condition is the filter of the stored document fields.
+// The document keyed only by the native ObjectID is replaced as well, see findById.
+func (this *mongoSvc[DocType]) UpdateDocumentIf(ctx context.Context, id string, document *DocType, condition bson.M) error {
+	ctx, span := tracer.Start(
+		ctx,
+		"mongoSvc.UpdateDocumentIf",
+		trace.WithAttributes(attribute.String("id", id)),
+	)
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "UpdateDocumentIf", id, time.Now())
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
+	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	client, err := this.connect(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.UpdateDocumentIf failed")
+		return err
+	}
+
+	// create nested span to trace db connection
+	ctx, replacespan := tracer.Start(
+		ctx,
+		"mongoSvc.UpdateDocumentIf.replace",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer replacespan.End()
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	replaceIf := func(key bson.M) (bool, error) {
+		filter := bson.M{}
+		for field, value := range condition {
+			filter[field] = value
+		}
+		for field, value := range key {
+			filter[field] = value
+		}
+		result, err := collection.ReplaceOne(ctx, filter, document, &options.ReplaceOptions{Comment: traceCommentValue(ctx)})
+		if mongo.IsDuplicateKeyError(err) {
+			return false, ErrConflict
+		}
+		if err != nil {
+			replacespan.SetStatus(codes.Error, "mongoSvc.UpdateDocumentIf.replace failed")
+			span.SetStatus(codes.Error, "mongoSvc.UpdateDocumentIf failed")
+			return false, err
+		}
+		return result.MatchedCount > 0, nil
+	}
+	if replaced, err := replaceIf(bson.M{"id": id}); replaced || err != nil {
+		return err
+	}
+
+	// the document is either missing, keyed only by the native ObjectID, or does not satisfy the condition
+	found, key := findById(ctx, collection, id)
+	switch found.Err() {
+	case nil:
+	case mongo.ErrNoDocuments:
+		replacespan.AddEvent("document not found")
+		return ErrNotFound
+	default:
+		return found.Err()
+	}
+	if _, byObjectId := key["_id"]; byObjectId {
+		if replaced, err := replaceIf(key); replaced || err != nil {
+			return err
+		}
+	}
+	replacespan.AddEvent("condition not satisfied")
+	return ErrPreconditionFailed
+}
+
+// UpdateFields sets only the given top-level fields of the document and increments the given
+// numeric fields by the given amounts, e.g. the version of the document, in a single atomic update.
+// The other fields are left untouched so that the concurrent changes to them are not overwritten.
+// The field names are the names of the stored document fields.
+func (this *mongoSvc[DocType]) UpdateFields(ctx context.Context, id string, fields bson.M, increments bson.M) error {
+	ctx, span := tracer.Start(
+		ctx,
+		"mongoSvc.UpdateFields",
+		trace.WithAttributes(attribute.String("id", id)),
+	)
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "UpdateFields", id, time.Now())
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
+	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	client, err := this.connect(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.UpdateFields failed")
+		return err
+	}
+
+	// create nested span to trace db connection
+	ctx, updatespan := tracer.Start(
+		ctx,
+		"mongoSvc.UpdateFields.update",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer updatespan.End()
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	update := bson.M{"$set": fields}
+	if len(increments) > 0 {
+		update["$inc"] = increments
+	}
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"id": id},
+		update,
+		&options.UpdateOptions{Comment: traceCommentValue(ctx)},
+	)
+	if err == nil && result.MatchedCount == 0 && primitive.IsValidObjectID(id) {
+		// the document may be keyed only by the native ObjectID, see findById
+		found, key := findById(ctx, collection, id)
+		switch found.Err() {
+		case nil:
+		case mongo.ErrNoDocuments:
+			updatespan.AddEvent("document not found")
+			return ErrNotFound
+		default:
+			return found.Err()
+		}
+		// the string id is set as well, see withStringId
+		withId := bson.M{"id": id}
+		for field, value := range fields {
+			withId[field] = value
+		}
+		update["$set"] = withId
+		result, err = collection.UpdateOne(ctx, key, update, &options.UpdateOptions{Comment: traceCommentValue(ctx)})
+	}
+	if err != nil {
+		updatespan.SetStatus(codes.Error, "mongoSvc.UpdateFields.update failed")
+		span.SetStatus(codes.Error, "mongoSvc.UpdateFields failed")
+		return err
+	}
+	if result.MatchedCount == 0 {
+		updatespan.AddEvent("document not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpsertDocument replaces the document with the same id, or inserts it if there is none, so that
+// repeated imports of the same document succeed in contrast to CreateDocument
+func (this *mongoSvc[DocType]) UpsertDocument(ctx context.Context, id string, document *DocType) error {
+	ctx, span := tracer.Start(
+		ctx,
+		"mongoSvc.UpsertDocument",
+		trace.WithAttributes(attribute.String("id", id)),
+	)
+	defer span.End()
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "UpsertDocument", id, time.Now())
+
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
+	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	client, err := this.connect(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, "mongoSvc.UpsertDocument failed")
+		return err
+	}
+
+	// create nested span to trace db connection
+	ctx, upsertspan := tracer.Start(
+		ctx,
+		"mongoSvc.UpsertDocument.replace",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer upsertspan.End()
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	filter := bson.M{"id": id}
+	if primitive.IsValidObjectID(id) {
+		// the document keyed only by the native ObjectID is replaced, not duplicated, see findById
+		found, key := findById(ctx, collection, id)
+		switch found.Err() {
+		case nil:
+			filter = key
+		case mongo.ErrNoDocuments:
+		default:
+			upsertspan.SetStatus(codes.Error, "mongoSvc.UpsertDocument.replace failed")
+			span.SetStatus(codes.Error, "mongoSvc.UpsertDocument failed")
+			return found.Err()
+		}
+	}
+	upsert := true
+	_, err = collection.ReplaceOne(
+		ctx,
+		filter,
+		document,
+		&options.ReplaceOptions{Upsert: &upsert, Comment: traceCommentValue(ctx)},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// other document violates the unique index
+		return ErrConflict
+	}
+	if err != nil {
+		upsertspan.SetStatus(codes.Error, "mongoSvc.UpsertDocument.replace failed")
+		span.SetStatus(codes.Error, "mongoSvc.UpsertDocument failed")
+	}
+	return err
+}
+
 func (this *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) error {
 	ctx, span := tracer.Start(
 		ctx,
@@ -296,8 +1236,16 @@ func (this *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) er
 		trace.WithAttributes(attribute.String("id", id)),
 	)
 	defer span.End()
-	ctx, contextCancel := context.WithTimeout(ctx, this.Timeout)
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+	defer this.reportSlowOperation(span, "DeleteDocument", id, time.Now())
+	ctx, contextCancel := contextWithTimeout(ctx, this.writeTimeout())
 	defer contextCancel()
+	release, err := this.acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	client, err := this.connect(ctx)
 	if err != nil {
 		return err
@@ -312,7 +1260,7 @@ func (this *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) er
 
 	db := client.Database(this.DbName)
 	collection := db.Collection(this.Collection)
-	result := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}})
+	result, filter := findById(ctx, collection, id)
 	if result.Err() != nil {
 		span.SetStatus(codes.Error, "mongoSvc.DeleteDocument.find_delete failed")
 		findspan.SetStatus(codes.Error, "mongoSvc.DeleteDocument.find_delete failed")
@@ -325,7 +1273,11 @@ func (this *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) er
 	default: // other errors - return them
 		return result.Err()
 	}
-	_, err = collection.DeleteOne(ctx, bson.D{{Key: "id", Value: id}})
+	_, err = collection.DeleteOne(
+		ctx,
+		filter,
+		&options.DeleteOptions{Comment: traceCommentValue(ctx)},
+	)
 	if err != nil {
 		findspan.AddEvent("document delete failed")
 		findspan.SetStatus(codes.Error, "mongoSvc.DeleteDocument.find_delete failed")
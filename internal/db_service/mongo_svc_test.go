@@ -0,0 +1,1234 @@
+package db_service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type MongoSvcSuite struct {
+	suite.Suite
+}
+
+func TestMongoSvcSuite(t *testing.T) {
+	suite.Run(t, new(MongoSvcSuite))
+}
+
+type testDocument struct {
+	Id   string
+	Name string
+}
+
+const testNamespace = "test-db.test-collection"
+
+// newMockedService creates the service connected to the mocked deployment of the mtest client
+func newMockedService(mt *mtest.T) *mongoSvc[testDocument] {
+	svc := &mongoSvc[testDocument]{}
+	svc.MongoServiceConfig = MongoServiceConfig{
+		DbName:     "test-db",
+		Collection: "test-collection",
+		Timeout:    5 * time.Second,
+	}
+	svc.config = svc.MongoServiceConfig
+	svc.client.Store(mt.Client)
+	return svc
+}
+
+func (suite *MongoSvcSuite) Test_ListDocumentsAfter_UndecodableDocument() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	page := func() bson.D {
+		return mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+			bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "first"}},
+			// legacy document with the name stored as a number
+			bson.D{{Key: "id", Value: "b"}, {Key: "name", Value: 42}},
+			bson.D{{Key: "id", Value: "c"}, {Key: "name", Value: "third"}},
+		)
+	}
+
+	mt.Run("strict", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		sut.DecodeMode = DecodeStrict
+		mt.AddMockResponses(page())
+
+		// ACT
+		documents, next, err := sut.ListDocumentsAfter(context.Background(), "", 10)
+
+		// ASSERT
+		suite.Error(err)
+		suite.Nil(documents)
+		suite.Empty(next)
+	})
+
+	mt.Run("lenient", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		sut.DecodeMode = DecodeLenient
+		var output bytes.Buffer
+		defer log.SetOutput(log.Writer())
+		log.SetOutput(&output)
+		mt.AddMockResponses(page())
+
+		// ACT
+		documents, next, err := sut.ListDocumentsAfter(context.Background(), "", 10)
+
+		// ASSERT
+		suite.NoError(err)
+		suite.Len(documents, 2)
+		suite.Equal("a", documents[0].Id)
+		suite.Equal("c", documents[1].Id)
+		suite.Equal("c", next)
+		suite.Contains(output.String(), "skipping undecodable document")
+		suite.Contains(output.String(), "id=b")
+	})
+}
+
+func (suite *MongoSvcSuite) Test_FindDocument_DecodeAlwaysProvidesDocumentOrError() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("no fields", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{}))
+
+		// ACT
+		document, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Require().NotNil(document)
+		suite.Equal(testDocument{}, *document)
+	})
+
+	mt.Run("undecodable", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+			bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: 42}}))
+
+		// ACT
+		document, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.ErrorIs(err, ErrUndecodable)
+		suite.Nil(document)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_NewMongoService_DecodeModeFromEnvironment() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_MONGODB_DECODE_MODE", "Lenient")
+
+	// ACT
+	lenient := NewMongoService[struct{}](MongoServiceConfig{}).(*mongoSvc[struct{}])
+	suite.T().Setenv("AMBULANCE_API_MONGODB_DECODE_MODE", "forgiving")
+	invalid := NewMongoService[struct{}](MongoServiceConfig{}).(*mongoSvc[struct{}])
+
+	// ASSERT
+	suite.Equal(DecodeLenient, lenient.DecodeMode)
+	suite.Equal(DecodeStrict, invalid.DecodeMode)
+}
+
+func (suite *MongoSvcSuite) Test_SeparateReads_FindUsesReadClientCreateUsesWriteClient() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("write", func(writeMt *mtest.T) {
+		writeMt.Run("read", func(readMt *mtest.T) {
+			// ARRANGE
+			sut := newMockedService(writeMt)
+			sut.ReadConnectionString = "mongodb://replica:27017"
+			sut.readClient.Store(readMt.Client)
+			document := bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "replica"}}
+			readMt.AddMockResponses(
+				// find
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+				// list
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+			)
+			writeMt.AddMockResponses(
+				// create
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+				mtest.CreateSuccessResponse(),
+				// find for update
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+					bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "primary"}}),
+			)
+			ctx := context.Background()
+
+			// ACT
+			found, findErr := sut.FindDocument(ctx, "a")
+			listed, _, listErr := sut.ListDocumentsAfter(ctx, "", 10)
+			createErr := sut.CreateDocument(ctx, "b", &testDocument{Id: "b"})
+			forUpdate, forUpdateErr := sut.FindDocument(ReadForUpdate(ctx), "a")
+
+			// ASSERT
+			suite.Require().NoError(findErr)
+			suite.Require().NoError(listErr)
+			suite.Require().NoError(createErr)
+			suite.Require().NoError(forUpdateErr)
+			suite.Equal("replica", found.Name)
+			suite.Equal("replica", listed[0].Name)
+			suite.Equal("primary", forUpdate.Name)
+			suite.Equal("find", readMt.GetStartedEvent().CommandName, "find")
+			suite.Equal("find", readMt.GetStartedEvent().CommandName, "list")
+			suite.Nil(readMt.GetStartedEvent(), "read client must not be used for writes")
+			suite.Equal("find", writeMt.GetStartedEvent().CommandName)
+			suite.Equal("insert", writeMt.GetStartedEvent().CommandName)
+		})
+	})
+}
+
+func (suite *MongoSvcSuite) Test_WithoutReadConnection_ReadsUseSingleClient() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("single", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}))
+
+		// ACT
+		_, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.NoError(err)
+		suite.Nil(sut.readClient.Load())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_ParseWriteConcern_MapsToDriverWriteConcern() {
+	// ARRANGE
+	cases := map[string]*writeconcern.WriteConcern{
+		"":         nil,
+		"majority": writeconcern.Majority(),
+		"1":        {W: 1},
+		"0":        {W: 0},
+		"2":        {W: 2},
+	}
+
+	for value, expected := range cases {
+		// ACT
+		actual, err := parseWriteConcern(value)
+
+		// ASSERT
+		suite.NoError(err, "value %q", value)
+		suite.Equal(expected, actual, "value %q", value)
+	}
+}
+
+func (suite *MongoSvcSuite) Test_ParseWriteConcern_InvalidValueFails() {
+	for _, value := range []string{"all", "-1", "1.5"} {
+		// ACT
+		actual, err := parseWriteConcern(value)
+
+		// ASSERT
+		suite.Error(err, "value %q", value)
+		suite.Nil(actual, "value %q", value)
+	}
+}
+
+func (suite *MongoSvcSuite) Test_NewMongoService_InvalidWriteConcernFallsBackToDefault() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_MONGODB_WRITE_CONCERN", "everyone")
+
+	// ACT
+	svc := NewMongoService[struct{}](MongoServiceConfig{}).(*mongoSvc[struct{}])
+
+	// ASSERT
+	suite.Equal("", svc.WriteConcern)
+}
+
+func (suite *MongoSvcSuite) Test_ListDocumentsAfter_WalksCollectionPageByPage() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("pages", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "first"}},
+				bson.D{{Key: "id", Value: "b"}, {Key: "name", Value: "second"}},
+			),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "id", Value: "c"}, {Key: "name", Value: "third"}},
+			),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+		)
+
+		// ACT
+		firstPage, cursor, err := sut.ListDocumentsAfter(context.Background(), "", 2)
+		suite.NoError(err)
+		firstFilter, _ := mt.GetStartedEvent().Command.Lookup("filter").Document().Elements()
+
+		secondPage, secondCursor, err := sut.ListDocumentsAfter(context.Background(), cursor, 2)
+		suite.NoError(err)
+		secondFilter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+
+		lastPage, lastCursor, err := sut.ListDocumentsAfter(context.Background(), secondCursor, 2)
+		suite.NoError(err)
+
+		// ASSERT
+		suite.Len(firstPage, 2)
+		suite.Equal("a", firstPage[0].Id)
+		suite.Equal("b", firstPage[1].Id)
+		suite.Equal("b", cursor)
+		suite.Empty(firstFilter, "first page must not be filtered")
+
+		suite.Len(secondPage, 1)
+		suite.Equal("c", secondPage[0].Id)
+		suite.Equal("third", secondPage[0].Name)
+		suite.Equal("c", secondCursor)
+		suite.Equal("b", secondFilter.Lookup("id", "$gt").StringValue())
+
+		suite.Empty(lastPage)
+		suite.Equal("", lastCursor)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_WithCollection_ServicesTargetDifferentCollections() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("collections", func(mt *mtest.T) {
+		// ARRANGE
+		ambulances := NewMongoService[testDocument](
+			MongoServiceConfig{DbName: "test-db"}, WithCollection("ambulance"),
+		).(*mongoSvc[testDocument])
+		patients := NewMongoService[testDocument](
+			MongoServiceConfig{DbName: "test-db"}, WithCollection("patients"),
+		).(*mongoSvc[testDocument])
+		ambulances.client.Store(mt.Client)
+		patients.client.Store(mt.Client)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test-db.ambulance", mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+			mtest.CreateCursorResponse(0, "test-db.patients", mtest.FirstBatch, bson.D{{Key: "id", Value: "p"}}),
+		)
+
+		// ACT
+		_, err := ambulances.FindDocument(context.Background(), "a")
+		suite.NoError(err)
+		ambulancesCollection := mt.GetStartedEvent().Command.Lookup("find").StringValue()
+
+		_, err = patients.FindDocument(context.Background(), "p")
+		suite.NoError(err)
+		patientsCollection := mt.GetStartedEvent().Command.Lookup("find").StringValue()
+
+		// ASSERT
+		suite.Equal("ambulance", ambulances.Collection)
+		suite.Equal("patients", patients.Collection)
+		suite.Equal("ambulance", ambulancesCollection)
+		suite.Equal("patients", patientsCollection)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_WithCollection_EmptyNameKeepsDefault() {
+	// ARRANGE
+	suite.T().Setenv("AMBULANCE_API_MONGODB_COLLECTION", "from-environment")
+
+	// ACT
+	svc := NewMongoService[testDocument](MongoServiceConfig{}, WithCollection("")).(*mongoSvc[testDocument])
+
+	// ASSERT
+	suite.Equal("from-environment", svc.Collection)
+}
+
+func (suite *MongoSvcSuite) Test_Reconnect_ReplacesClientWithFreshConfiguration() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("reconnect", func(mt *mtest.T) {
+		// ARRANGE
+		ctx := context.Background()
+		sut := newMockedService(mt)
+		previousClient, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://previous-host:27017"))
+		suite.Require().NoError(err)
+		sut.client.Store(previousClient)
+
+		mt.Setenv("AMBULANCE_API_MONGODB_USERNAME", "admin")
+		mt.Setenv("AMBULANCE_API_MONGODB_PASSWORD", "rotated")
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+		)
+
+		// ACT
+		err = sut.Reconnect(ctx)
+		suite.Require().NoError(err)
+		document, findErr := sut.FindDocument(ctx, "a")
+
+		// ASSERT
+		suite.Same(mt.Client, sut.client.Load())
+		suite.Equal("rotated", sut.Password)
+		suite.Equal("test-collection", sut.Collection)
+		suite.Equal("admin", connectOptions.Auth.Username)
+		suite.Equal("rotated", connectOptions.Auth.Password)
+		suite.NoError(findErr)
+		suite.Equal("a", document.Id)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Connect_AppliesAppName() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("app name", func(mt *mtest.T) {
+		// ARRANGE
+		mt.Setenv("AMBULANCE_API_MONGODB_APPNAME", "ambulance-webapi-test")
+		sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+
+		// ACT
+		_, err := sut.connect(context.Background())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Require().NotNil(connectOptions.AppName)
+		suite.Equal("ambulance-webapi-test", *connectOptions.AppName)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Connect_AppliesConnectTimeout() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("connect timeout", func(mt *mtest.T) {
+		// ARRANGE
+		mt.Setenv("AMBULANCE_API_MONGODB_CONNECT_TIMEOUT_SECONDS", "3")
+		mt.Setenv("AMBULANCE_API_MONGODB_TIMEOUT_SECONDS", "20")
+		sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+
+		// ACT
+		_, err := sut.connect(context.Background())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Require().NotNil(connectOptions.ConnectTimeout)
+		suite.Equal(3*time.Second, *connectOptions.ConnectTimeout)
+		suite.Equal(20*time.Second, sut.Timeout)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Connect_AppliesServerSelectionAndHeartbeat() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("configured", func(mt *mtest.T) {
+		// ARRANGE
+		mt.Setenv("AMBULANCE_API_MONGODB_SERVER_SELECTION_TIMEOUT_SECONDS", "2")
+		sut := NewMongoService[testDocument](MongoServiceConfig{
+			HeartbeatInterval: 3 * time.Second,
+		}).(*mongoSvc[testDocument])
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+
+		// ACT
+		_, err := sut.connect(context.Background())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Require().NotNil(connectOptions.ServerSelectionTimeout)
+		suite.Equal(2*time.Second, *connectOptions.ServerSelectionTimeout)
+		suite.Require().NotNil(connectOptions.HeartbeatInterval)
+		suite.Equal(3*time.Second, *connectOptions.HeartbeatInterval)
+	})
+
+	mt.Run("driver defaults", func(mt *mtest.T) {
+		// ARRANGE
+		sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+
+		// ACT
+		_, err := sut.connect(context.Background())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Nil(connectOptions.ServerSelectionTimeout)
+		suite.Nil(connectOptions.HeartbeatInterval)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Connect_AppliesCompressors() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("configured", func(mt *mtest.T) {
+		// ARRANGE
+		mt.Setenv("AMBULANCE_API_MONGODB_COMPRESSORS", " zstd, Snappy ")
+		sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+		var connectOptions *options.ClientOptions
+		defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+			mongoConnect = previous
+		}(mongoConnect)
+		mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+			connectOptions = opts[0]
+			return mt.Client, nil
+		}
+
+		// ACT
+		_, err := sut.connect(context.Background())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal([]string{"zstd", "snappy"}, connectOptions.Compressors)
+	})
+
+	mt.Run("unsupported", func(mt *mtest.T) {
+		// ARRANGE
+		mt.Setenv("AMBULANCE_API_MONGODB_COMPRESSORS", "zstd,lz4")
+
+		// ACT
+		sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+
+		// ASSERT
+		suite.Empty(sut.Compressors)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_ConnectFailure_IsUnavailable() {
+	// ARRANGE
+	sut := NewMongoService[testDocument](MongoServiceConfig{}).(*mongoSvc[testDocument])
+	defer func(previous func(context.Context, ...*options.ClientOptions) (*mongo.Client, error)) {
+		mongoConnect = previous
+	}(mongoConnect)
+	mongoConnect = func(ctx context.Context, opts ...*options.ClientOptions) (*mongo.Client, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	// ACT
+	_, err := sut.FindDocument(context.Background(), "a")
+
+	// ASSERT
+	suite.True(IsUnavailable(err))
+	suite.ErrorIs(err, ErrUnavailable)
+	suite.False(IsUnavailable(ErrNotFound))
+}
+
+func (suite *MongoSvcSuite) Test_Redacted_MasksPassword() {
+	// ARRANGE
+	sut := NewMongoService[testDocument](MongoServiceConfig{
+		ServerHost: "mongo",
+		UserName:   "admin",
+		Password:   "secret",
+	}).(*mongoSvc[testDocument])
+
+	// ACT
+	reported := sut.EffectiveConfig().Redacted()
+
+	// ASSERT
+	suite.Equal("***", reported.Password)
+	suite.Equal("admin", reported.UserName)
+	suite.Equal("mongo", reported.ServerHost)
+	suite.Equal("secret", sut.Password, "service keeps using the real password")
+}
+
+func (suite *MongoSvcSuite) Test_UpdateFields_SetsOnlyGivenFields() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("update fields", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		// ACT
+		err := sut.UpdateFields(context.Background(), "a", bson.M{"name": "renamed"}, bson.M{"version": 1})
+
+		// ASSERT
+		suite.NoError(err)
+		update := mt.GetStartedEvent()
+		suite.Equal("update", update.CommandName)
+		statement := update.Command.Lookup("updates", "0").Document()
+		suite.Equal("a", statement.Lookup("q", "id").StringValue())
+		set := statement.Lookup("u", "$set").Document()
+		suite.Equal("renamed", set.Lookup("name").StringValue())
+		elements, _ := set.Elements()
+		suite.Len(elements, 1)
+		suite.Equal(int32(1), statement.Lookup("u", "$inc", "version").Int32())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_UpdateFields_NotFound() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("missing document", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+			bson.E{Key: "nModified", Value: 0},
+		))
+
+		// ACT
+		err := sut.UpdateFields(context.Background(), "a", bson.M{"name": "renamed"}, nil)
+
+		// ASSERT
+		suite.ErrorIs(err, ErrNotFound)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_UpdateDocumentIf_ReplacesFilteredByCondition() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("condition satisfied", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		// ACT
+		err := sut.UpdateDocumentIf(context.Background(), "a", &testDocument{Id: "a", Name: "renamed"}, bson.M{"name": "original"})
+
+		// ASSERT
+		suite.NoError(err)
+		update := mt.GetStartedEvent()
+		suite.Equal("update", update.CommandName)
+		filter := update.Command.Lookup("updates", "0", "q").Document()
+		suite.Equal("a", filter.Lookup("id").StringValue())
+		suite.Equal("original", filter.Lookup("name").StringValue())
+	})
+
+	mt.Run("condition not satisfied", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "changed"}}),
+		)
+
+		// ACT
+		err := sut.UpdateDocumentIf(context.Background(), "a", &testDocument{Id: "a", Name: "renamed"}, bson.M{"name": "original"})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrPreconditionFailed)
+	})
+
+	mt.Run("missing document", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+		)
+
+		// ACT
+		err := sut.UpdateDocumentIf(context.Background(), "a", &testDocument{Id: "a", Name: "renamed"}, bson.M{"name": "original"})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrNotFound)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Operations_CommentedWithTraceId() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("comments", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		traceId := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceId,
+			SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		}))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		// ACT
+		err := sut.UpdateDocument(ctx, "a", &testDocument{Id: "a", Name: "updated"})
+
+		// ASSERT
+		suite.NoError(err)
+		find := mt.GetStartedEvent()
+		replace := mt.GetStartedEvent()
+		suite.Equal("find", find.CommandName)
+		suite.Equal(traceId.String(), find.Command.Lookup("comment").StringValue())
+		suite.Equal("update", replace.CommandName)
+		suite.Equal(traceId.String(), replace.Command.Lookup("comment").StringValue())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Operations_NotCommentedWithoutTrace() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("no comments", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+		)
+
+		// ACT
+		_, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.NoError(err)
+		_, lookupErr := mt.GetStartedEvent().Command.LookupErr("comment")
+		suite.Error(lookupErr)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Ping_UnreachableHostFails() {
+	// ARRANGE
+	sut := NewMongoService[testDocument](MongoServiceConfig{
+		ServerHost: "unreachable.invalid",
+		ServerPort: 27017,
+		Timeout:    200 * time.Millisecond,
+	}).(*mongoSvc[testDocument])
+	defer sut.Disconnect(context.Background())
+
+	// ACT
+	err := sut.Ping(context.Background())
+
+	// ASSERT
+	suite.Error(err)
+}
+
+func (suite *MongoSvcSuite) Test_Ping_ReachableServerSucceeds() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ping", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		// ACT
+		err := sut.Ping(context.Background())
+
+		// ASSERT
+		suite.NoError(err)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_Operations_UseReadOrWriteTimeout() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	for name, config := range map[string]struct {
+		read, write                 time.Duration
+		expectedRead, expectedWrite time.Duration
+	}{
+		"split":    {read: time.Second, write: 2 * time.Second, expectedRead: time.Second, expectedWrite: 2 * time.Second},
+		"fallback": {expectedRead: 5 * time.Second, expectedWrite: 5 * time.Second},
+	} {
+		mt.Run(name, func(mt *mtest.T) {
+			// ARRANGE
+			sut := newMockedService(mt)
+			sut.ReadTimeout = config.read
+			sut.WriteTimeout = config.write
+			timeouts := []time.Duration{}
+			defer func(previous func(context.Context, time.Duration) (context.Context, context.CancelFunc)) {
+				contextWithTimeout = previous
+			}(contextWithTimeout)
+			contextWithTimeout = func(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+				timeouts = append(timeouts, timeout)
+				return context.WithTimeout(ctx, timeout)
+			}
+			document := bson.D{{Key: "id", Value: "a"}}
+			mt.AddMockResponses(
+				// find
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+				// list
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+				// create
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+				mtest.CreateSuccessResponse(),
+				// update
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+				mtest.CreateSuccessResponse(),
+				// delete
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, document),
+				mtest.CreateSuccessResponse(),
+			)
+			ctx := context.Background()
+
+			// ACT
+			_, findErr := sut.FindDocument(ctx, "a")
+			_, _, listErr := sut.ListDocumentsAfter(ctx, "", 10)
+			createErr := sut.CreateDocument(ctx, "b", &testDocument{Id: "b"})
+			updateErr := sut.UpdateDocument(ctx, "a", &testDocument{Id: "a"})
+			deleteErr := sut.DeleteDocument(ctx, "a")
+
+			// ASSERT
+			suite.NoError(findErr)
+			suite.NoError(listErr)
+			suite.NoError(createErr)
+			suite.NoError(updateErr)
+			suite.NoError(deleteErr)
+			suite.Equal([]time.Duration{
+				config.expectedRead,
+				config.expectedRead,
+				config.expectedWrite,
+				config.expectedWrite,
+				config.expectedWrite,
+			}, timeouts)
+		})
+	}
+}
+
+func (suite *MongoSvcSuite) Test_CreateDocument_DuplicateKeyIsConflict() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("duplicate key", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			// no conflicting document found by the application level check
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+			// but the insert violates the unique index
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{
+				Index:   0,
+				Code:    11000,
+				Message: "E11000 duplicate key error collection: test-db.test-collection",
+			}),
+		)
+
+		// ACT
+		err := sut.CreateDocument(context.Background(), "a", &testDocument{Id: "a"})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrConflict)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_EnsureIndexes_CreatesIdAndConfiguredIndexes() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("indexes", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		WithUniqueIndex("ambulanceid", "patientid")(&sut.MongoServiceConfig)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		// ACT
+		err := sut.EnsureIndexes(context.Background())
+
+		// ASSERT
+		suite.NoError(err)
+		indexes, _ := mt.GetStartedEvent().Command.Lookup("indexes").Array().Values()
+		suite.Len(indexes, 2)
+		idKeys, _ := indexes[0].Document().Lookup("key").Document().Elements()
+		suite.Len(idKeys, 1)
+		suite.Equal("id", idKeys[0].Key())
+		secondKeys, _ := indexes[1].Document().Lookup("key").Document().Elements()
+		suite.Equal("ambulanceid", secondKeys[0].Key())
+		suite.Equal("patientid", secondKeys[1].Key())
+		suite.True(indexes[1].Document().Lookup("unique").Boolean())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_SlowOperation_Reported() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("slow", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		sut.SlowOperationThreshold = 10 * time.Millisecond
+		var output bytes.Buffer
+		defer log.SetOutput(log.Writer())
+		log.SetOutput(&output)
+		defer func(previous func(context.Context, time.Duration) (context.Context, context.CancelFunc)) {
+			contextWithTimeout = previous
+		}(contextWithTimeout)
+		contextWithTimeout = func(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+			// simulates the slow database
+			time.Sleep(20 * time.Millisecond)
+			return context.WithTimeout(ctx, timeout)
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+		)
+
+		// ACT
+		_, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.NoError(err)
+		suite.Contains(output.String(), "slow database operation operation=FindDocument collection=test-collection id=a")
+	})
+}
+
+func (suite *MongoSvcSuite) Test_FastOperation_NotReported() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("fast", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		sut.SlowOperationThreshold = time.Minute
+		var output bytes.Buffer
+		defer log.SetOutput(log.Writer())
+		log.SetOutput(&output)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}),
+		)
+
+		// ACT
+		_, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.NoError(err)
+		suite.NotContains(output.String(), "slow database operation")
+	})
+}
+
+func (suite *MongoSvcSuite) Test_OperationSlots_LimitConcurrency() {
+	// ARRANGE
+	sut := NewMongoService[testDocument](MongoServiceConfig{MaxConcurrentOperations: 2}).(*mongoSvc[testDocument])
+	var inFlight, maxInFlight atomic.Int32
+	var wg sync.WaitGroup
+
+	// ACT
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := sut.acquireOperationSlot(context.Background())
+			suite.NoError(err)
+			defer release()
+			current := inFlight.Add(1)
+			for {
+				previous := maxInFlight.Load()
+				if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	// ASSERT
+	suite.LessOrEqual(maxInFlight.Load(), int32(2))
+	suite.Equal(int32(0), inFlight.Load())
+}
+
+func (suite *MongoSvcSuite) Test_OperationSlots_DeadlineExceeded() {
+	// ARRANGE
+	sut := NewMongoService[testDocument](MongoServiceConfig{
+		MaxConcurrentOperations: 1,
+		Timeout:                 50 * time.Millisecond,
+	}).(*mongoSvc[testDocument])
+	release, err := sut.acquireOperationSlot(context.Background())
+	suite.Require().NoError(err)
+	defer release()
+
+	// ACT
+	_, err = sut.FindDocument(context.Background(), "a")
+
+	// ASSERT
+	suite.ErrorIs(err, ErrOverloaded)
+	suite.True(IsUnavailable(err))
+}
+
+func (suite *MongoSvcSuite) Test_FindDocument_ByStringId() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("string id", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+			bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "by id"}}))
+
+		// ACT
+		document, err := sut.FindDocument(context.Background(), "a")
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal("by id", document.Name)
+		suite.Equal("id", mt.GetStartedEvent().Command.Lookup("filter").Document().Index(0).Key())
+		suite.Nil(mt.GetStartedEvent())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_FindDocument_FallsBackToObjectId() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("object id", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		objectId := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: objectId}, {Key: "name", Value: "by object id"}}),
+		)
+
+		// ACT
+		document, err := sut.FindDocument(context.Background(), objectId.Hex())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal("by object id", document.Name)
+		mt.GetStartedEvent()
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		suite.Equal(objectId, filter.Lookup("_id").ObjectID())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_CreateDocument_ConflictsWithExisting() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("strict create", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, bson.D{{Key: "id", Value: "a"}}))
+
+		// ACT
+		err := sut.CreateDocument(context.Background(), "a", &testDocument{Id: "a"})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrConflict)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_UpsertDocument_ReplacesExisting() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("upsert", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		// ACT
+		err := sut.UpsertDocument(context.Background(), "a", &testDocument{Id: "a", Name: "reimported"})
+
+		// ASSERT
+		suite.NoError(err)
+		update := mt.GetStartedEvent()
+		suite.Equal("update", update.CommandName)
+		statement := update.Command.Lookup("updates", "0").Document()
+		suite.True(statement.Lookup("upsert").Boolean())
+		suite.Equal("reimported", statement.Lookup("u", "name").StringValue())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_UpdateAndDelete_FallBackToObjectId() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+	objectId := primitive.NewObjectID()
+	notFoundByIdThenFound := func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: objectId}, {Key: "name", Value: "by object id"}}),
+		)
+	}
+	// skips the lookups and provides the filter of the write
+	writeFilter := func(mt *mtest.T, filterPath ...string) bson.Raw {
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName != "find" {
+				return event.Command.Lookup(filterPath...).Document()
+			}
+		}
+		return nil
+	}
+
+	mt.Run("update", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		// ACT
+		err := sut.UpdateDocument(context.Background(), objectId.Hex(), &testDocument{Id: objectId.Hex(), Name: "renamed"})
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal(objectId, writeFilter(mt, "updates", "0", "q").Lookup("_id").ObjectID())
+	})
+
+	mt.Run("conditional update", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		// ACT
+		err := sut.UpdateDocumentIf(context.Background(), objectId.Hex(), &testDocument{Id: objectId.Hex(), Name: "renamed"}, bson.M{"name": "by object id"})
+
+		// ASSERT
+		suite.Require().NoError(err)
+		byId := writeFilter(mt, "updates", "0", "q")
+		suite.Equal(objectId.Hex(), byId.Lookup("id").StringValue())
+		byObjectId := writeFilter(mt, "updates", "0", "q")
+		suite.Equal(objectId, byObjectId.Lookup("_id").ObjectID())
+		suite.Equal("by object id", byObjectId.Lookup("name").StringValue())
+	})
+
+	mt.Run("conditional update not satisfied", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		// ACT
+		err := sut.UpdateDocumentIf(context.Background(), objectId.Hex(), &testDocument{Id: objectId.Hex(), Name: "renamed"}, bson.M{"name": "original"})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrPreconditionFailed)
+	})
+
+	mt.Run("update fields", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		// ACT
+		err := sut.UpdateFields(context.Background(), objectId.Hex(), bson.M{"name": "renamed"}, nil)
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal(objectId.Hex(), writeFilter(mt, "updates", "0", "q").Lookup("id").StringValue())
+		update := mt.GetStartedEvent()
+		for update.CommandName == "find" {
+			update = mt.GetStartedEvent()
+		}
+		statement := update.Command.Lookup("updates", "0").Document()
+		suite.Equal(objectId, statement.Lookup("q", "_id").ObjectID())
+		suite.Equal(objectId.Hex(), statement.Lookup("u", "$set", "id").StringValue())
+		suite.Equal("renamed", statement.Lookup("u", "$set", "name").StringValue())
+	})
+
+	mt.Run("upsert", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		// ACT
+		err := sut.UpsertDocument(context.Background(), objectId.Hex(), &testDocument{Id: objectId.Hex(), Name: "reimported"})
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal(objectId, writeFilter(mt, "updates", "0", "q").Lookup("_id").ObjectID())
+	})
+
+	mt.Run("upsert new", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}),
+		)
+
+		// ACT
+		err := sut.UpsertDocument(context.Background(), objectId.Hex(), &testDocument{Id: objectId.Hex(), Name: "imported"})
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal(objectId.Hex(), writeFilter(mt, "updates", "0", "q").Lookup("id").StringValue())
+	})
+
+	mt.Run("delete", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		notFoundByIdThenFound(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		// ACT
+		err := sut.DeleteDocument(context.Background(), objectId.Hex())
+
+		// ASSERT
+		suite.Require().NoError(err)
+		suite.Equal(objectId, writeFilter(mt, "deletes", "0", "q").Lookup("_id").ObjectID())
+	})
+}
+
+func (suite *MongoSvcSuite) Test_ObjectIdDocuments_StoredBackWithTheirIds() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("two documents", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		stored := map[string]bson.Raw{}
+		for _, objectId := range []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()} {
+			found := bson.D{{Key: "_id", Value: objectId}, {Key: "name", Value: "original"}}
+			mt.AddMockResponses(
+				// find falls back to _id
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, found),
+				// replace by id does not match, falls back to _id
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, found),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			)
+
+			// ACT
+			document, err := sut.FindDocument(context.Background(), objectId.Hex())
+			suite.Require().NoError(err)
+			document.Name = "renamed"
+			err = sut.UpdateDocumentIf(context.Background(), document.Id, document, bson.M{"name": "original"})
+
+			// ASSERT
+			suite.Require().NoError(err)
+			suite.Equal(objectId.Hex(), document.Id)
+			for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+				if event.CommandName == "update" {
+					stored[objectId.Hex()] = event.Command.Lookup("updates", "0", "u").Document()
+				}
+			}
+		}
+
+		// each is stored with its own id, none violates the unique index with the empty id
+		suite.Len(stored, 2)
+		for id, document := range stored {
+			suite.Equal(id, document.Lookup("id").StringValue())
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package db_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DocumentChangeHandler is called for every change of the watched collection,
+// the document is nil if it was deleted
+type DocumentChangeHandler[DocType interface{}] func(id string, document *DocType)
+
+// DocumentWatcher is implemented by the services able to observe changes made by any client of the database
+type DocumentWatcher[DocType interface{}] interface {
+	WatchDocuments(ctx context.Context, handler DocumentChangeHandler[DocType]) error
+}
+
+var ErrChangeStreamNotSupported = fmt.Errorf("change streams are not supported by the database server")
+
+// error code returned by the standalone servers - change streams are available on replica sets only
+const changeStreamNotSupportedCode = 40573
+
+// error codes returned when the resume token is no longer in the oplog, e.g. after a long outage
+const (
+	changeStreamFatalErrorCode  = 280
+	changeStreamHistoryLostCode = 286
+)
+
+// delay before the interrupted change stream is reopened
+var watchRetryDelay = 5 * time.Second
+
+// subset of mongo.ChangeStream used by the watcher
+type changeStream interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	ResumeToken() bson.Raw
+	Err() error
+	Close(ctx context.Context) error
+}
+
+type changeEvent struct {
+	OperationType            string   `bson:"operationType"`
+	FullDocument             bson.Raw `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+}
+
+// WatchDocuments tails the collection change stream and calls the handler for each changed document
+// until the context is cancelled. The stream is resumed after the last processed event when interrupted,
+// so short disconnects do not lose any changes. If the oplog no longer holds the last processed event,
+// the stream is reopened from the current changes. Returns ErrChangeStreamNotSupported on standalone servers.
+func (this *mongoSvc[DocType]) WatchDocuments(ctx context.Context, handler DocumentChangeHandler[DocType]) error {
+	var resumeToken bson.Raw
+	for {
+		err := this.watchOnce(ctx, &resumeToken, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var serverError mongo.ServerError
+		if errors.As(err, &serverError) && serverError.HasErrorCode(changeStreamNotSupportedCode) {
+			return ErrChangeStreamNotSupported
+		}
+		if resumeToken != nil && errors.As(err, &serverError) &&
+			(serverError.HasErrorCode(changeStreamHistoryLostCode) || serverError.HasErrorCode(changeStreamFatalErrorCode)) {
+			// the same token would fail forever
+			log.Printf("Change stream cannot be resumed, the changes made meanwhile may have been missed: %v", err)
+			resumeToken = nil
+			continue
+		}
+
+		log.Printf("Change stream interrupted, will resume in %v: %v", watchRetryDelay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+func (this *mongoSvc[DocType]) watchOnce(
+	ctx context.Context,
+	resumeToken *bson.Raw,
+	handler DocumentChangeHandler[DocType],
+) error {
+	stream, err := this.openChangeStream(ctx, *resumeToken)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	return processChangeStream(ctx, stream, resumeToken, handler)
+}
+
+// openChangeStream opens the stream on the current client, the stream is interrupted by Reconnect
+// and then resumed on the new client
+func (this *mongoSvc[DocType]) openChangeStream(ctx context.Context, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	this.operationsLock.RLock()
+	defer this.operationsLock.RUnlock()
+
+	connectCtx, contextCancel := context.WithTimeout(ctx, this.Timeout)
+	defer contextCancel()
+	client, err := this.connect(connectCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	streamOptions := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if resumeToken != nil {
+		streamOptions.SetResumeAfter(resumeToken)
+	}
+
+	db := client.Database(this.DbName)
+	collection := db.Collection(this.Collection)
+	return collection.Watch(ctx, mongo.Pipeline{}, streamOptions)
+}
+
+// processChangeStream dispatches the stream events to the handler and keeps the resume token
+// of the last processed event. Deletions are reported only if the server provides the pre-image
+// of the document, otherwise the id of the deleted document is not known.
+func processChangeStream[DocType interface{}](
+	ctx context.Context,
+	stream changeStream,
+	resumeToken *bson.Raw,
+	handler DocumentChangeHandler[DocType],
+) error {
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+
+		switch event.OperationType {
+		case "insert", "update", "replace":
+			// full document is missing if it was deleted before the lookup
+			if event.FullDocument != nil {
+				document, err := decodeDocument[DocType](event.FullDocument)
+				if err != nil {
+					log.Printf("Cannot decode changed document: %v", err)
+				} else if id, ok := event.FullDocument.Lookup("id").StringValueOK(); ok {
+					handler(id, document)
+				}
+			}
+		case "delete":
+			if event.FullDocumentBeforeChange != nil {
+				if id, ok := event.FullDocumentBeforeChange.Lookup("id").StringValueOK(); ok {
+					handler(id, nil)
+				}
+			}
+		}
+		*resumeToken = stream.ResumeToken()
+	}
+	return stream.Err()
+}
@@ -0,0 +1,193 @@
+package db_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fakeChangeStream replays the prepared change events
+type fakeChangeStream struct {
+	events  []bson.Raw
+	current int
+	err     error
+}
+
+func newFakeChangeStream(events ...bson.D) *fakeChangeStream {
+	stream := &fakeChangeStream{current: -1}
+	for _, event := range events {
+		raw, _ := bson.Marshal(event)
+		stream.events = append(stream.events, raw)
+	}
+	return stream
+}
+
+func (this *fakeChangeStream) Next(ctx context.Context) bool {
+	this.current++
+	return this.current < len(this.events)
+}
+
+func (this *fakeChangeStream) Decode(val interface{}) error {
+	return bson.Unmarshal(this.events[this.current], val)
+}
+
+func (this *fakeChangeStream) ResumeToken() bson.Raw {
+	return this.events[this.current].Lookup("_id").Document()
+}
+
+func (this *fakeChangeStream) Err() error {
+	return this.err
+}
+
+func (this *fakeChangeStream) Close(ctx context.Context) error {
+	return nil
+}
+
+type publishedChange struct {
+	id       string
+	document *testDocument
+}
+
+func (suite *MongoSvcSuite) Test_ProcessChangeStream_FeedsChangesToHandler() {
+	// ARRANGE
+	stream := newFakeChangeStream(
+		bson.D{
+			{Key: "_id", Value: bson.D{{Key: "_data", Value: "token-1"}}},
+			{Key: "operationType", Value: "insert"},
+			{Key: "fullDocument", Value: bson.D{{Key: "id", Value: "a"}, {Key: "name", Value: "first"}}},
+		},
+		bson.D{
+			{Key: "_id", Value: bson.D{{Key: "_data", Value: "token-2"}}},
+			{Key: "operationType", Value: "replace"},
+			{Key: "fullDocument", Value: bson.D{{Key: "id", Value: "b"}, {Key: "name", Value: "second"}}},
+		},
+		bson.D{
+			{Key: "_id", Value: bson.D{{Key: "_data", Value: "token-3"}}},
+			{Key: "operationType", Value: "delete"},
+			{Key: "fullDocumentBeforeChange", Value: bson.D{{Key: "id", Value: "a"}}},
+		},
+	)
+	published := []publishedChange{}
+	var resumeToken bson.Raw
+
+	// ACT
+	err := processChangeStream(
+		context.Background(),
+		stream,
+		&resumeToken,
+		func(id string, document *testDocument) {
+			published = append(published, publishedChange{id, document})
+		},
+	)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.Len(published, 3)
+	suite.Equal("a", published[0].id)
+	suite.Equal("first", published[0].document.Name)
+	suite.Equal("b", published[1].id)
+	suite.Equal("second", published[1].document.Name)
+	suite.Equal("a", published[2].id)
+	suite.Nil(published[2].document)
+	suite.Equal("token-3", resumeToken.Lookup("_data").StringValue())
+}
+
+func (suite *MongoSvcSuite) Test_ProcessChangeStream_KeepsResumeTokenOfLastEventOnError() {
+	// ARRANGE
+	stream := newFakeChangeStream(
+		bson.D{
+			{Key: "_id", Value: bson.D{{Key: "_data", Value: "token-1"}}},
+			{Key: "operationType", Value: "insert"},
+			{Key: "fullDocument", Value: bson.D{{Key: "id", Value: "a"}}},
+		},
+	)
+	stream.err = errors.New("connection reset")
+	var resumeToken bson.Raw
+
+	// ACT
+	err := processChangeStream(
+		context.Background(),
+		stream,
+		&resumeToken,
+		func(id string, document *testDocument) {},
+	)
+
+	// ASSERT
+	suite.Error(err)
+	suite.Equal("token-1", resumeToken.Lookup("_data").StringValue())
+}
+
+func (suite *MongoSvcSuite) Test_WatchDocuments_StandaloneServerNotSupported() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("standalone", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    changeStreamNotSupportedCode,
+			Name:    "Location40573",
+			Message: "The $changeStream stage is only supported on replica sets",
+		}))
+
+		// ACT
+		err := sut.WatchDocuments(context.Background(), func(id string, document *testDocument) {})
+
+		// ASSERT
+		suite.ErrorIs(err, ErrChangeStreamNotSupported)
+	})
+}
+
+func (suite *MongoSvcSuite) Test_WatchDocuments_HistoryLost_ReopensWithoutResumeToken() {
+	mt := mtest.New(suite.T(), mtest.NewOptions().ClientType(mtest.Mock))
+	defer func(previous time.Duration) { watchRetryDelay = previous }(watchRetryDelay)
+	watchRetryDelay = time.Millisecond
+
+	mt.Run("history lost", func(mt *mtest.T) {
+		// ARRANGE
+		sut := newMockedService(mt)
+		event := func(token string, id string) bson.D {
+			return bson.D{
+				{Key: "_id", Value: bson.D{{Key: "_data", Value: token}}},
+				{Key: "operationType", Value: "insert"},
+				{Key: "fullDocument", Value: bson.D{{Key: "id", Value: id}}},
+			}
+		}
+		mt.AddMockResponses(
+			// the stream ends after the first event
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, event("token-1", "a")),
+			// the oplog no longer holds the event
+			mtest.CreateCommandErrorResponse(mtest.CommandError{
+				Code:    changeStreamHistoryLostCode,
+				Name:    "ChangeStreamHistoryLost",
+				Message: "Resume of change stream was not possible, as the resume point may no longer be in the oplog",
+			}),
+			mtest.CreateCursorResponse(0, testNamespace, mtest.FirstBatch, event("token-2", "b")),
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changed := []string{}
+
+		// ACT
+		err := sut.WatchDocuments(ctx, func(id string, document *testDocument) {
+			changed = append(changed, id)
+			if id == "b" {
+				cancel()
+			}
+		})
+
+		// ASSERT
+		suite.ErrorIs(err, context.Canceled)
+		suite.Equal([]string{"a", "b"}, changed)
+		resumed := []bool{}
+		for started := mt.GetStartedEvent(); started != nil; started = mt.GetStartedEvent() {
+			if started.CommandName == "aggregate" {
+				_, err := started.Command.LookupErr("pipeline", "0", "$changeStream", "resumeAfter")
+				resumed = append(resumed, err == nil)
+			}
+		}
+		suite.Equal([]bool{false, true, false}, resumed)
+	})
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+// OtlpHandler emits the log records by the OpenTelemetry logger, e.g. exported by the OTLP logs exporter.
+// The logger correlates the records with the active span of their context.
+type OtlpHandler struct {
+	logger otellog.Logger
+	attrs  []otellog.KeyValue
+	// prefix of the attribute keys of the open group, empty if no group is open
+	group string
+}
+
+func NewOtlpHandler(logger otellog.Logger) *OtlpHandler {
+	return &OtlpHandler{logger: logger}
+}
+
+func (this *OtlpHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return this.logger.Enabled(ctx, otellog.EnabledParameters{Severity: severity(level)})
+}
+
+func (this *OtlpHandler) Handle(ctx context.Context, record slog.Record) error {
+	var emitted otellog.Record
+	emitted.SetTimestamp(record.Time)
+	emitted.SetSeverity(severity(record.Level))
+	emitted.SetSeverityText(record.Level.String())
+	emitted.SetBody(otellog.StringValue(record.Message))
+	emitted.AddAttributes(this.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		if keyValue, ok := this.keyValue(attr); ok {
+			emitted.AddAttributes(keyValue)
+		}
+		return true
+	})
+	this.logger.Emit(ctx, emitted)
+	return nil
+}
+
+func (this *OtlpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *this
+	handler.attrs = append([]otellog.KeyValue{}, this.attrs...)
+	for _, attr := range attrs {
+		if keyValue, ok := this.keyValue(attr); ok {
+			handler.attrs = append(handler.attrs, keyValue)
+		}
+	}
+	return &handler
+}
+
+func (this *OtlpHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return this
+	}
+	handler := *this
+	handler.group = this.group + name + "."
+	return &handler
+}
+
+// keyValue converts the slog attribute to the log attribute, the key is prefixed by the open group;
+// empty attributes are dropped as slog handlers do
+func (this *OtlpHandler) keyValue(attr slog.Attr) (otellog.KeyValue, bool) {
+	if attr.Equal(slog.Attr{}) {
+		return otellog.KeyValue{}, false
+	}
+	return otellog.KeyValue{Key: this.group + attr.Key, Value: value(attr.Value)}, true
+}
+
+// value converts the slog value to the log value, the values of unknown kinds are provided as strings
+func value(slogValue slog.Value) otellog.Value {
+	slogValue = slogValue.Resolve()
+	switch slogValue.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(slogValue.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(slogValue.Int64())
+	case slog.KindUint64:
+		return otellog.Int64Value(int64(slogValue.Uint64()))
+	case slog.KindFloat64:
+		return otellog.Float64Value(slogValue.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(slogValue.Bool())
+	case slog.KindDuration:
+		return otellog.Int64Value(slogValue.Duration().Nanoseconds())
+	case slog.KindGroup:
+		group := make([]otellog.KeyValue, 0, len(slogValue.Group()))
+		for _, attr := range slogValue.Group() {
+			group = append(group, otellog.KeyValue{Key: attr.Key, Value: value(attr.Value)})
+		}
+		return otellog.MapValue(group...)
+	default:
+		return otellog.StringValue(slogValue.String())
+	}
+}
+
+// severity maps the slog level to the log severity, the levels between the slog levels are kept
+// between the severities, e.g. slog.LevelInfo+1 is otellog.SeverityInfo2
+func severity(level slog.Level) otellog.Severity {
+	severity := int(otellog.SeverityInfo) + int(level)
+	return otellog.Severity(min(max(severity, int(otellog.SeverityTrace1)), int(otellog.SeverityFatal4)))
+}
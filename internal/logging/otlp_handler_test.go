@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// recordingExporter keeps the exported log records in memory
+type recordingExporter struct {
+	mutex   sync.Mutex
+	records []sdklog.Record
+}
+
+func (this *recordingExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, record := range records {
+		this.records = append(this.records, record.Clone())
+	}
+	return nil
+}
+
+func (this *recordingExporter) Shutdown(ctx context.Context) error   { return nil }
+func (this *recordingExporter) ForceFlush(ctx context.Context) error { return nil }
+
+type OtlpHandlerSuite struct {
+	suite.Suite
+	exporter *recordingExporter
+	logger   *slog.Logger
+}
+
+func TestOtlpHandlerSuite(t *testing.T) {
+	suite.Run(t, new(OtlpHandlerSuite))
+}
+
+func (suite *OtlpHandlerSuite) SetupTest() {
+	suite.exporter = &recordingExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(suite.exporter)))
+	suite.logger = slog.New(NewOtlpHandler(provider.Logger("test")))
+}
+
+func (suite *OtlpHandlerSuite) Test_Record_ExportedWithSeverityAndAttributes() {
+	// ARRANGE
+	logger := suite.logger.With("component", "test").WithGroup("request")
+
+	// ACT
+	logger.WarnContext(context.Background(), "exported message", "status", 409)
+
+	// ASSERT
+	suite.Require().Len(suite.exporter.records, 1)
+	record := suite.exporter.records[0]
+	suite.Equal("exported message", record.Body().AsString())
+	suite.Equal(otellog.SeverityWarn, record.Severity())
+	suite.Equal("WARN", record.SeverityText())
+
+	attrs := map[string]otellog.Value{}
+	record.WalkAttributes(func(keyValue otellog.KeyValue) bool {
+		attrs[keyValue.Key] = keyValue.Value
+		return true
+	})
+	suite.Equal("test", attrs["component"].AsString())
+	suite.Equal(int64(409), attrs["request.status"].AsInt64())
+}
+
+func (suite *OtlpHandlerSuite) Test_Record_CarriesActiveSpan() {
+	// ARRANGE
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	// ACT
+	suite.logger.InfoContext(ctx, "traced message")
+
+	// ASSERT
+	suite.Require().Len(suite.exporter.records, 1)
+	suite.Equal(span.SpanContext().TraceID(), suite.exporter.records[0].TraceID())
+	suite.Equal(span.SpanContext().SpanID(), suite.exporter.records[0].SpanID())
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHandler adds the trace and span id of the active span to the log records, so that the logs
+// can be correlated with the traces in the telemetry backend
+type TraceHandler struct {
+	slog.Handler
+}
+
+func NewTraceHandler(next slog.Handler) *TraceHandler {
+	return &TraceHandler{Handler: next}
+}
+
+func (this *TraceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return this.Handler.Handle(ctx, record)
+}
+
+func (this *TraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewTraceHandler(this.Handler.WithAttrs(attrs))
+}
+
+func (this *TraceHandler) WithGroup(name string) slog.Handler {
+	return NewTraceHandler(this.Handler.WithGroup(name))
+}
+
+// Setup installs the default structured logger for the exporters selected by the `OTEL_LOGS_EXPORTER`
+// value, the comma separated list of `console` and `otlp`. The console records are written to the output
+// as JSON, the otlp records are exported by the OTLP logs exporter configured by the `OTEL_EXPORTER_OTLP_*`
+// variables. The returned function flushes and stops the exporter. The default logger is kept if no
+// exporter is selected.
+func Setup(ctx context.Context, exporters string, output io.Writer, res *resource.Resource) (func(context.Context) error, error) {
+	shutdown := func(context.Context) error { return nil }
+	handlers := []slog.Handler{}
+	for _, exporter := range strings.Split(exporters, ",") {
+		switch exporter = strings.TrimSpace(exporter); exporter {
+		case "", "none":
+		case "console":
+			handlers = append(handlers, NewTraceHandler(slog.NewJSONHandler(output, nil)))
+		case "otlp":
+			logExporter, err := otlploggrpc.New(ctx)
+			if err != nil {
+				return shutdown, err
+			}
+			provider := sdklog.NewLoggerProvider(
+				sdklog.WithResource(res),
+				sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)))
+			shutdown = provider.Shutdown
+			handlers = append(handlers, NewOtlpHandler(provider.Logger("ambulance-webapi")))
+		default:
+			log.Printf("Invalid OTEL_LOGS_EXPORTER value: %v", exporter)
+		}
+	}
+
+	switch len(handlers) {
+	case 0:
+		return shutdown, nil
+	case 1:
+		slog.SetDefault(slog.New(handlers[0]))
+	default:
+		slog.SetDefault(slog.New(fanoutHandler(handlers)))
+	}
+	return shutdown, nil
+}
+
+// fanoutHandler passes the records to all handlers, e.g. to export them and to write them to the console
+type fanoutHandler []slog.Handler
+
+func (this fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range this {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var result error
+	for _, handler := range this {
+		if handler.Enabled(ctx, record.Level) {
+			if err := handler.Handle(ctx, record.Clone()); err != nil {
+				result = err
+			}
+		}
+	}
+	return result
+}
+
+func (this fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(this))
+	for i, handler := range this {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (this fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(this))
+	for i, handler := range this {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type TraceHandlerSuite struct {
+	suite.Suite
+}
+
+func TestTraceHandlerSuite(t *testing.T) {
+	suite.Run(t, new(TraceHandlerSuite))
+}
+
+func (suite *TraceHandlerSuite) Test_Record_CarriesActiveTraceId() {
+	// ARRANGE
+	defer func(previous *slog.Logger) { slog.SetDefault(previous) }(slog.Default())
+	var output bytes.Buffer
+	_, err := Setup(context.Background(), "console", &output, nil)
+	suite.Require().NoError(err)
+
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+
+	// ACT
+	slog.InfoContext(ctx, "traced message")
+
+	// ASSERT
+	var record map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &record))
+	suite.Equal("traced message", record["msg"])
+	suite.Equal(span.SpanContext().TraceID().String(), record["trace_id"])
+	suite.Equal(span.SpanContext().SpanID().String(), record["span_id"])
+}
+
+func (suite *TraceHandlerSuite) Test_Record_WithoutSpanHasNoTraceId() {
+	// ARRANGE
+	var output bytes.Buffer
+	logger := slog.New(NewTraceHandler(slog.NewJSONHandler(&output, nil))).With("component", "test")
+
+	// ACT
+	logger.InfoContext(context.Background(), "plain message")
+
+	// ASSERT
+	var record map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &record))
+	suite.Equal("test", record["component"])
+	suite.NotContains(record, "trace_id")
+}
+
+func (suite *TraceHandlerSuite) Test_Setup_NoExporterKeepsDefaultLogger() {
+	// ARRANGE
+	previous := slog.Default()
+	var output bytes.Buffer
+
+	// ACT
+	shutdown, err := Setup(context.Background(), "", &output, nil)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.NoError(shutdown(context.Background()))
+	suite.Same(previous, slog.Default())
+}
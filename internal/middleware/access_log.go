@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fields of the access log record
+const (
+	AccessLogMethod        = "method"
+	AccessLogPath          = "path"
+	AccessLogStatus        = "status"
+	AccessLogLatency       = "latency_ms"
+	AccessLogClientIp      = "client_ip"
+	AccessLogCorrelationId = "correlation_id"
+	AccessLogResponseSize  = "response_size"
+)
+
+// all fields are logged by default
+var DefaultAccessLogFields = []string{
+	AccessLogMethod, AccessLogPath, AccessLogStatus, AccessLogLatency,
+	AccessLogClientIp, AccessLogCorrelationId, AccessLogResponseSize,
+}
+
+// the probes and the metrics scraping are polled periodically and would flood the log
+var DefaultAccessLogSkipPaths = []string{"/metrics", "/health", "/healthz", "/readyz", "/livez"}
+
+// request headers carrying the correlation id assigned by the clients or the gateway
+var correlationIdHeaders = []string{"X-Correlation-Id", "X-Request-Id"}
+
+type AccessLogConfig struct {
+	// logger the records are written to, the default logger is used if nil
+	Logger *slog.Logger
+	// level of the access log records
+	Level slog.Level
+	// names of the logged fields, all fields are logged if empty
+	Fields []string
+	// request paths not logged, the paths below the listed ones are skipped as well
+	SkipPaths []string
+}
+
+// AccessLog writes one structured record per handled request with the selected fields.
+// The correlation id is taken from the request headers, or the trace id of the request is used.
+func AccessLog(config AccessLogConfig) gin.HandlerFunc {
+	fields := config.Fields
+	if len(fields) == 0 {
+		fields = DefaultAccessLogFields
+	}
+
+	return func(ctx *gin.Context) {
+		path := ctx.Request.URL.Path
+		if skipAccessLog(path, config.SkipPaths) {
+			ctx.Next()
+			return
+		}
+
+		logger := config.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		if !logger.Enabled(ctx.Request.Context(), config.Level) {
+			ctx.Next()
+			return
+		}
+
+		start := time.Now()
+		ctx.Next()
+		latency := time.Since(start)
+
+		attrs := make([]slog.Attr, 0, len(fields))
+		for _, field := range fields {
+			switch field {
+			case AccessLogMethod:
+				attrs = append(attrs, slog.String(field, ctx.Request.Method))
+			case AccessLogPath:
+				attrs = append(attrs, slog.String(field, path))
+			case AccessLogStatus:
+				attrs = append(attrs, slog.Int(field, ctx.Writer.Status()))
+			case AccessLogLatency:
+				attrs = append(attrs, slog.Float64(field, float64(latency.Microseconds())/1000))
+			case AccessLogClientIp:
+				attrs = append(attrs, slog.String(field, ctx.ClientIP()))
+			case AccessLogCorrelationId:
+				attrs = append(attrs, slog.String(field, correlationId(ctx)))
+			case AccessLogResponseSize:
+				// size is -1 if nothing was written
+				attrs = append(attrs, slog.Int(field, max(ctx.Writer.Size(), 0)))
+			}
+		}
+		logger.LogAttrs(ctx.Request.Context(), config.Level, "request handled", attrs...)
+	}
+}
+
+func skipAccessLog(path string, skipPaths []string) bool {
+	for _, skipPath := range skipPaths {
+		if path == skipPath || strings.HasPrefix(path, strings.TrimSuffix(skipPath, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func correlationId(ctx *gin.Context) string {
+	for _, header := range correlationIdHeaders {
+		if value := ctx.GetHeader(header); value != "" {
+			return value
+		}
+	}
+	if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.IsValid() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+// ParseAccessLogFields parses the comma separated list of the field names, unknown names are reported
+func ParseAccessLogFields(value string) (fields []string, unknown []string) {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case slices.Contains(DefaultAccessLogFields, item):
+			fields = append(fields, item)
+		default:
+			unknown = append(unknown, item)
+		}
+	}
+	return fields, unknown
+}
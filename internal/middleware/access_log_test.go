@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccessLogSuite struct {
+	suite.Suite
+	output bytes.Buffer
+}
+
+func TestAccessLogSuite(t *testing.T) {
+	suite.Run(t, new(AccessLogSuite))
+}
+
+func (suite *AccessLogSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.output.Reset()
+}
+
+func (suite *AccessLogSuite) engine(config AccessLogConfig) *gin.Engine {
+	config.Logger = slog.New(slog.NewJSONHandler(&suite.output, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	engine := gin.New()
+	engine.Use(AccessLog(config))
+	engine.GET("/ambulance/:ambulanceId", func(ctx *gin.Context) {
+		ctx.String(http.StatusCreated, "created")
+	})
+	engine.GET("/metrics", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "# metrics")
+	})
+	return engine
+}
+
+func (suite *AccessLogSuite) serve(engine *gin.Engine, url string) {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("X-Request-Id", "req-42")
+	request.RemoteAddr = "10.0.0.7:5123"
+	engine.ServeHTTP(httptest.NewRecorder(), request)
+}
+
+func (suite *AccessLogSuite) Test_LogsRequestFields() {
+	// ARRANGE
+	engine := suite.engine(AccessLogConfig{Level: slog.LevelInfo})
+
+	// ACT
+	suite.serve(engine, "/ambulance/bobulova?limit=5")
+
+	// ASSERT
+	record := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(suite.output.Bytes(), &record))
+	suite.Equal("INFO", record["level"])
+	suite.Equal("GET", record["method"])
+	suite.Equal("/ambulance/bobulova", record["path"])
+	suite.Equal(float64(http.StatusCreated), record["status"])
+	suite.Equal("10.0.0.7", record["client_ip"])
+	suite.Equal("req-42", record["correlation_id"])
+	suite.Equal(float64(len("created")), record["response_size"])
+	suite.Contains(record, "latency_ms")
+}
+
+func (suite *AccessLogSuite) Test_LogsSelectedFieldsOnly() {
+	// ARRANGE
+	engine := suite.engine(AccessLogConfig{
+		Level:  slog.LevelWarn,
+		Fields: []string{AccessLogPath, AccessLogStatus},
+	})
+
+	// ACT
+	suite.serve(engine, "/ambulance/bobulova")
+
+	// ASSERT
+	record := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(suite.output.Bytes(), &record))
+	suite.Equal("WARN", record["level"])
+	suite.Equal("/ambulance/bobulova", record["path"])
+	suite.NotContains(record, "method")
+	suite.NotContains(record, "correlation_id")
+}
+
+func (suite *AccessLogSuite) Test_SkipsConfiguredPaths() {
+	// ARRANGE
+	engine := suite.engine(AccessLogConfig{SkipPaths: DefaultAccessLogSkipPaths})
+
+	// ACT
+	suite.serve(engine, "/metrics")
+
+	// ASSERT
+	suite.Empty(suite.output.String())
+}
+
+func (suite *AccessLogSuite) Test_LevelBelowLoggerThreshold_NothingLogged() {
+	// ARRANGE
+	engine := suite.engine(AccessLogConfig{Level: slog.LevelDebug})
+
+	// ACT
+	suite.serve(engine, "/ambulance/bobulova")
+
+	// ASSERT
+	suite.Empty(suite.output.String())
+}
+
+func (suite *AccessLogSuite) Test_ParseAccessLogFields_ReportsUnknown() {
+	fields, unknown := ParseAccessLogFields(" method, status,,size ")
+	suite.Equal([]string{AccessLogMethod, AccessLogStatus}, fields)
+	suite.Equal([]string{"size"}, unknown)
+}
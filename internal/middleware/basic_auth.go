@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BasicAuth rejects requests without the basic authentication credentials matching the user and password.
+// Used for the endpoints scraped by tools supporting the basic authentication only.
+func BasicAuth(user string, password string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		providedUser, providedPassword, found := ctx.Request.BasicAuth()
+		// both values are compared to not reveal which one is wrong
+		userMatches := subtle.ConstantTimeCompare([]byte(providedUser), []byte(user))
+		passwordMatches := subtle.ConstantTimeCompare([]byte(providedPassword), []byte(password))
+		if user == "" || !found || userMatches&passwordMatches != 1 {
+			ctx.Header("WWW-Authenticate", `Basic realm="ambulance-webapi"`)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "Unauthorized",
+				"message": "Valid credentials are required",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type BasicAuthSuite struct {
+	suite.Suite
+}
+
+func TestBasicAuthSuite(t *testing.T) {
+	suite.Run(t, new(BasicAuthSuite))
+}
+
+func (suite *BasicAuthSuite) request(user string, password string, credentials []string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/metrics", BasicAuth(user, password), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	if credentials != nil {
+		request.SetBasicAuth(credentials[0], credentials[1])
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *BasicAuthSuite) Test_MatchingCredentials_AreAllowed() {
+	suite.Equal(http.StatusOK, suite.request("prometheus", "secret", []string{"prometheus", "secret"}).Code)
+}
+
+func (suite *BasicAuthSuite) Test_MissingOrWrongCredentials_AreUnauthorized() {
+	missing := suite.request("prometheus", "secret", nil)
+	suite.Equal(http.StatusUnauthorized, missing.Code)
+	suite.Contains(missing.Header().Get("WWW-Authenticate"), "Basic")
+	suite.Equal(http.StatusUnauthorized, suite.request("prometheus", "secret", []string{"prometheus", "other"}).Code)
+	suite.Equal(http.StatusUnauthorized, suite.request("prometheus", "secret", []string{"other", "secret"}).Code)
+	// empty user must never match
+	suite.Equal(http.StatusUnauthorized, suite.request("", "", []string{"", ""}).Code)
+}
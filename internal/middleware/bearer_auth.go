@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BearerAuth rejects requests without the `Authorization: Bearer <token>` header matching the token.
+// Used to guard the operational endpoints not intended for the regular clients.
+func BearerAuth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		provided, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "Unauthorized",
+				"message": "Valid bearer token is required",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type BearerAuthSuite struct {
+	suite.Suite
+}
+
+func TestBearerAuthSuite(t *testing.T) {
+	suite.Run(t, new(BearerAuthSuite))
+}
+
+func (suite *BearerAuthSuite) request(token string, authorization string) int {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/admin/action", BearerAuth(token), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	request := httptest.NewRequest("POST", "/admin/action", nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func (suite *BearerAuthSuite) Test_MatchingToken_IsAllowed() {
+	suite.Equal(http.StatusNoContent, suite.request("secret", "Bearer secret"))
+}
+
+func (suite *BearerAuthSuite) Test_MissingOrWrongToken_IsUnauthorized() {
+	suite.Equal(http.StatusUnauthorized, suite.request("secret", ""))
+	suite.Equal(http.StatusUnauthorized, suite.request("secret", "Bearer other"))
+	suite.Equal(http.StatusUnauthorized, suite.request("secret", "Basic secret"))
+	// empty token must never match
+	suite.Equal(http.StatusUnauthorized, suite.request("", "Bearer "))
+}
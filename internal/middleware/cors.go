@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headers the cross-origin clients can read by default - the caching and pagination headers
+// and the headers describing how the request was processed
+var DefaultCorsExposedHeaders = []string{"ETag", "X-Total-Count", "Link", "Retry-After", "X-Dry-Run", ResponseTimeHeader}
+
+// preflight responses are cached by the browsers for this duration by default
+const DefaultCorsMaxAge = 10 * time.Minute
+
+type CorsConfig struct {
+	// origins allowed to call the api, "*" allows any origin
+	AllowedOrigins []string
+	// response headers readable by the cross-origin clients
+	ExposedHeaders []string
+	// duration the browsers may cache the preflight response, zero leaves it on the browser default
+	MaxAge time.Duration
+}
+
+// Cors allows the cross-origin requests of the configured origins. Preflight requests are answered
+// directly, other requests get the allowed origin and the exposed headers in the response.
+func Cors(config CorsConfig) gin.HandlerFunc {
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+		ctx.Header("Vary", "Origin")
+		if !slices.Contains(config.AllowedOrigins, "*") && !slices.Contains(config.AllowedOrigins, origin) {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Access-Control-Allow-Origin", origin)
+		if ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != "" {
+			ctx.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			if requested := ctx.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				ctx.Header("Access-Control-Allow-Headers", requested)
+			}
+			if config.MaxAge > 0 {
+				ctx.Header("Access-Control-Max-Age", maxAge)
+			}
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			ctx.Header("Access-Control-Expose-Headers", exposedHeaders)
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type CorsSuite struct {
+	suite.Suite
+	engine *gin.Engine
+}
+
+func TestCorsSuite(t *testing.T) {
+	suite.Run(t, new(CorsSuite))
+}
+
+func (suite *CorsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.engine = gin.New()
+	suite.engine.Use(Cors(CorsConfig{
+		AllowedOrigins: []string{"https://wac.example.com"},
+		ExposedHeaders: DefaultCorsExposedHeaders,
+		MaxAge:         time.Hour,
+	}))
+	suite.engine.GET("/entries", func(ctx *gin.Context) {
+		ctx.Header("ETag", `W/"1"`)
+		ctx.JSON(http.StatusOK, []string{})
+	})
+}
+
+func (suite *CorsSuite) request(method string, origin string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "/entries", nil)
+	request.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		request.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		request.Header.Set("Access-Control-Request-Headers", "If-None-Match")
+	}
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *CorsSuite) Test_Response_ExposesHeaders() {
+	// ACT
+	recorder := suite.request(http.MethodGet, "https://wac.example.com")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("https://wac.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	suite.Equal("ETag, X-Total-Count, Link, Retry-After, X-Dry-Run, X-Response-Time", recorder.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func (suite *CorsSuite) Test_Preflight_CachedForMaxAge() {
+	// ACT
+	recorder := suite.request(http.MethodOptions, "https://wac.example.com")
+
+	// ASSERT
+	suite.Equal(http.StatusNoContent, recorder.Code)
+	suite.Equal("3600", recorder.Header().Get("Access-Control-Max-Age"))
+	suite.Equal("If-None-Match", recorder.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func (suite *CorsSuite) Test_OtherOrigin_NotAllowed() {
+	// ACT
+	recorder := suite.request(http.MethodGet, "https://evil.example.com")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Empty(recorder.Header().Get("Access-Control-Allow-Origin"))
+	suite.Empty(recorder.Header().Get("Access-Control-Expose-Headers"))
+}
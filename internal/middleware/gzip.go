@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responses smaller than this size are not worth compressing
+const DefaultGzipMinSize = 1024
+
+// gzipWriter buffers the response until it reaches the minimal size, then switches to the compressed output
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize    int
+	buffer     bytes.Buffer
+	compressor *gzip.Writer
+}
+
+func (this *gzipWriter) Write(data []byte) (int, error) {
+	if this.compressor != nil {
+		return this.compressor.Write(data)
+	}
+
+	this.buffer.Write(data)
+	if this.buffer.Len() >= this.minSize {
+		header := this.ResponseWriter.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		this.compressor = gzip.NewWriter(this.ResponseWriter)
+		if _, err := this.compressor.Write(this.buffer.Bytes()); err != nil {
+			return 0, err
+		}
+		this.buffer.Reset()
+	}
+	return len(data), nil
+}
+
+func (this *gzipWriter) WriteString(data string) (int, error) {
+	return this.Write([]byte(data))
+}
+
+// close writes out the small responses uncompressed, or completes the compressed stream
+func (this *gzipWriter) close() error {
+	if this.compressor != nil {
+		return this.compressor.Close()
+	}
+	if this.buffer.Len() > 0 {
+		_, err := this.ResponseWriter.Write(this.buffer.Bytes())
+		return err
+	}
+	return nil
+}
+
+// Gzip compresses responses of at least minSize bytes for the clients accepting gzip encoding.
+// Server-sent events and websocket upgrades are passed through, compression would break their flushing.
+func Gzip(minSize int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(ctx.GetHeader("Accept-Encoding")) || isStreaming(ctx.Request) {
+			ctx.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: ctx.Writer, minSize: minSize}
+		ctx.Writer = writer
+		defer func() {
+			ctx.Writer = writer.ResponseWriter
+			_ = writer.close()
+		}()
+		ctx.Next()
+	}
+}
+
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.TrimSpace(name)
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if quality, err := strconv.ParseFloat(value, 64); key == "q" && err == nil && quality == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isStreaming detects the requests for the long lived streamed responses
+func isStreaming(request *http.Request) bool {
+	return strings.Contains(request.Header.Get("Accept"), "text/event-stream") ||
+		strings.EqualFold(request.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type GzipSuite struct {
+	suite.Suite
+	engine *gin.Engine
+}
+
+func TestGzipSuite(t *testing.T) {
+	suite.Run(t, new(GzipSuite))
+}
+
+var largeList = strings.Repeat("entry", 1000)
+
+func (suite *GzipSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.engine = gin.New()
+	suite.engine.Use(Gzip(DefaultGzipMinSize))
+	suite.engine.GET("/large", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"entries": largeList})
+	})
+	suite.engine.GET("/small", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"entries": "entry"})
+	})
+}
+
+func (suite *GzipSuite) get(url string, headers map[string]string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", url, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	suite.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *GzipSuite) Test_LargeResponse_CompressedWhenAccepted() {
+	// ACT
+	recorder := suite.get("/large", map[string]string{"Accept-Encoding": "br, gzip;q=0.8"})
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("gzip", recorder.Header().Get("Content-Encoding"))
+	suite.Equal("Accept-Encoding", recorder.Header().Get("Vary"))
+
+	reader, err := gzip.NewReader(recorder.Body)
+	suite.Require().NoError(err)
+	body, err := io.ReadAll(reader)
+	suite.NoError(err)
+	suite.JSONEq(`{"entries": "`+largeList+`"}`, string(body))
+}
+
+func (suite *GzipSuite) Test_SmallResponse_NotCompressed() {
+	// ACT
+	recorder := suite.get("/small", map[string]string{"Accept-Encoding": "gzip"})
+
+	// ASSERT
+	suite.Empty(recorder.Header().Get("Content-Encoding"))
+	suite.JSONEq(`{"entries": "entry"}`, recorder.Body.String())
+}
+
+func (suite *GzipSuite) Test_LargeResponse_NotCompressedWithoutSupport() {
+	for _, headers := range []map[string]string{
+		{},
+		{"Accept-Encoding": "gzip;q=0"},
+		{"Accept-Encoding": "gzip", "Accept": "text/event-stream"},
+	} {
+		// ACT
+		recorder := suite.get("/large", headers)
+
+		// ASSERT
+		suite.Empty(recorder.Header().Get("Content-Encoding"), headers)
+		suite.JSONEq(`{"entries": "`+largeList+`"}`, recorder.Body.String())
+	}
+}
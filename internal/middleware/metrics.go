@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// route label of the requests not matching any registered route, raw paths would make
+// the label cardinality unbounded
+const unmatchedRoute = "unmatched"
+
+// HandlerErrorsMetrics counts responses with the 4xx and 5xx status codes labeled by the matched route
+// and the status class. The counter is exported as `http_handler_errors_total`.
+func HandlerErrorsMetrics(meter metric.Meter) (gin.HandlerFunc, error) {
+	errorsCounter, err := meter.Int64Counter(
+		"http_handler_errors",
+		metric.WithDescription("The number of requests handled with the client or server error status"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		if status < 400 {
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		errorsCounter.Add(ctx.Request.Context(), 1, metric.WithAttributes(
+			attribute.String("route", route),
+			attribute.String("status_class", fmt.Sprintf("%dxx", status/100)),
+		))
+	}, nil
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+	reader *sdkmetric.ManualReader
+	engine *gin.Engine
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
+
+func (suite *MetricsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.reader = sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(suite.reader))
+
+	handler, err := HandlerErrorsMetrics(provider.Meter("test"))
+	suite.Require().NoError(err)
+
+	suite.engine = gin.New()
+	suite.engine.Use(handler)
+	suite.engine.GET("/ambulance/:ambulanceId", func(ctx *gin.Context) {
+		if ctx.Param("ambulanceId") == "missing" {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+}
+
+func (suite *MetricsSuite) get(path string) {
+	suite.engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+}
+
+// collectErrors returns the counter values keyed by the route and status class labels
+func (suite *MetricsSuite) collectErrors() map[[2]string]int64 {
+	var data metricdata.ResourceMetrics
+	suite.Require().NoError(suite.reader.Collect(context.Background(), &data))
+
+	result := map[[2]string]int64{}
+	for _, scope := range data.ScopeMetrics {
+		for _, instrument := range scope.Metrics {
+			if instrument.Name != "http_handler_errors" {
+				continue
+			}
+			for _, point := range instrument.Data.(metricdata.Sum[int64]).DataPoints {
+				route, _ := point.Attributes.Value(attribute.Key("route"))
+				class, _ := point.Attributes.Value(attribute.Key("status_class"))
+				result[[2]string{route.AsString(), class.AsString()}] = point.Value
+			}
+		}
+	}
+	return result
+}
+
+func (suite *MetricsSuite) Test_NotFound_IncrementsCounterForMatchedRoute() {
+	// ACT
+	suite.get("/ambulance/missing")
+	suite.get("/ambulance/missing")
+	suite.get("/ambulance/found")
+
+	// ASSERT
+	suite.Equal(map[[2]string]int64{{"/ambulance/:ambulanceId", "4xx"}: 2}, suite.collectErrors())
+}
+
+func (suite *MetricsSuite) Test_UnknownPath_UsesBoundedRouteLabel() {
+	// ACT
+	suite.get("/some/random/path")
+	suite.get("/another/random/path")
+
+	// ASSERT
+	suite.Equal(map[[2]string]int64{{unmatchedRoute, "4xx"}: 2}, suite.collectErrors())
+}
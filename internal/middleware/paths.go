@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NormalizePaths makes the routes of the engine resolve regardless of the trailing slash and the letter case
+// of their static segments. The trailing slash is removed before routing, so both forms are served directly
+// without a redirect. Paths differing in the letter case only are redirected to the registered route,
+// the path parameters keep their case. The response writer is passed through, streaming is not affected.
+func NormalizePaths(engine *gin.Engine) http.Handler {
+	engine.RedirectTrailingSlash = false
+	engine.RedirectFixedPath = true
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if path := request.URL.Path; len(path) > 1 && strings.HasSuffix(path, "/") {
+			request.URL.Path = strings.TrimRight(path, "/")
+			if request.URL.Path == "" {
+				request.URL.Path = "/"
+			}
+			if request.URL.RawPath != "" {
+				request.URL.RawPath = strings.TrimRight(request.URL.RawPath, "/")
+			}
+		}
+		engine.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathsSuite struct {
+	suite.Suite
+	handler http.Handler
+}
+
+func TestPathsSuite(t *testing.T) {
+	suite.Run(t, new(PathsSuite))
+}
+
+func (suite *PathsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/waiting-list/:ambulanceId/entries", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.Param("ambulanceId"))
+	})
+	engine.POST("/api/waiting-list/:ambulanceId/entries", func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+	suite.handler = NormalizePaths(engine)
+}
+
+func (suite *PathsSuite) serve(method string, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.handler.ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+	return recorder
+}
+
+func (suite *PathsSuite) Test_SlashedAndUnslashed_BothResolve() {
+	for _, url := range []string{
+		"/api/waiting-list/Bobulova/entries",
+		"/api/waiting-list/Bobulova/entries/",
+		"/api/waiting-list/Bobulova/entries//?limit=5",
+	} {
+		// ACT
+		recorder := suite.serve(http.MethodGet, url)
+
+		// ASSERT
+		suite.Equal(http.StatusOK, recorder.Code, url)
+		suite.Equal("Bobulova", recorder.Body.String(), url)
+	}
+
+	suite.Equal(http.StatusCreated, suite.serve(http.MethodPost, "/api/waiting-list/Bobulova/entries/").Code)
+}
+
+func (suite *PathsSuite) Test_MixedCase_RedirectedKeepingParameters() {
+	// ACT
+	get := suite.serve(http.MethodGet, "/API/Waiting-List/Bobulova/Entries/")
+	post := suite.serve(http.MethodPost, "/api/waiting-list/Bobulova/ENTRIES")
+
+	// ASSERT
+	suite.Equal(http.StatusMovedPermanently, get.Code)
+	suite.Equal("/api/waiting-list/Bobulova/entries", get.Header().Get("Location"))
+	suite.Equal(http.StatusTemporaryRedirect, post.Code)
+	suite.Equal("/api/waiting-list/Bobulova/entries", post.Header().Get("Location"))
+}
+
+func (suite *PathsSuite) Test_UnknownPath_NotFound() {
+	suite.Equal(http.StatusNotFound, suite.serve(http.MethodGet, "/api/waiting-list/Bobulova/unknown/").Code)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// header with the duration of the request processing in milliseconds
+const ResponseTimeHeader = "X-Response-Time"
+
+// responseTimeWriter stamps the response time just before the headers are sent, later the headers cannot change
+type responseTimeWriter struct {
+	gin.ResponseWriter
+	start   time.Time
+	stamped bool
+}
+
+func (this *responseTimeWriter) stamp() {
+	if this.stamped || this.ResponseWriter.Written() {
+		return
+	}
+	this.stamped = true
+	elapsed := float64(time.Since(this.start)) / float64(time.Millisecond)
+	this.Header().Set(ResponseTimeHeader, strconv.FormatFloat(elapsed, 'f', 3, 64))
+}
+
+func (this *responseTimeWriter) WriteHeaderNow() {
+	this.stamp()
+	this.ResponseWriter.WriteHeaderNow()
+}
+
+func (this *responseTimeWriter) Write(data []byte) (int, error) {
+	this.stamp()
+	return this.ResponseWriter.Write(data)
+}
+
+func (this *responseTimeWriter) WriteString(data string) (int, error) {
+	this.stamp()
+	return this.ResponseWriter.WriteString(data)
+}
+
+// ResponseTime reports the duration of the request processing in the X-Response-Time header, in milliseconds,
+// for the field debugging of the latency where the traces are not available. The duration ends when the
+// response starts to be sent. Server-sent events and websocket upgrades are passed through, their duration
+// is not known when their headers are sent.
+func ResponseTime() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if isStreaming(ctx.Request) {
+			ctx.Next()
+			return
+		}
+
+		writer := &responseTimeWriter{ResponseWriter: ctx.Writer, start: time.Now()}
+		ctx.Writer = writer
+		defer func() {
+			// responses without the body are sent by gin only after all handlers return
+			writer.stamp()
+			ctx.Writer = writer.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ResponseTimeSuite struct {
+	suite.Suite
+	engine *gin.Engine
+}
+
+func TestResponseTimeSuite(t *testing.T) {
+	suite.Run(t, new(ResponseTimeSuite))
+}
+
+func (suite *ResponseTimeSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.engine = gin.New()
+	suite.engine.Use(ResponseTime())
+	suite.engine.GET("/json", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"entries": "entry"})
+	})
+	suite.engine.DELETE("/empty", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	suite.engine.GET("/events", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "data: entry\n\n")
+	})
+}
+
+func (suite *ResponseTimeSuite) serve(method string, url string, accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, url, nil)
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *ResponseTimeSuite) Test_Response_HasNumericResponseTime() {
+	for _, recorder := range []*httptest.ResponseRecorder{
+		suite.serve(http.MethodGet, "/json", "application/json"),
+		suite.serve(http.MethodDelete, "/empty", ""),
+		suite.serve(http.MethodGet, "/missing", ""),
+	} {
+		// ASSERT
+		value := recorder.Header().Get(ResponseTimeHeader)
+		elapsed, err := strconv.ParseFloat(value, 64)
+		suite.NoError(err, value)
+		suite.GreaterOrEqual(elapsed, 0.0)
+	}
+}
+
+func (suite *ResponseTimeSuite) Test_Stream_Skipped() {
+	// ACT
+	recorder := suite.serve(http.MethodGet, "/events", "text/event-stream")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Empty(recorder.Header().Get(ResponseTimeHeader))
+}
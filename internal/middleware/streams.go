@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LimitStreams bounds the number of the simultaneously served server-sent events and websocket
+// upgrades. Each stream holds its connection for a long time, so without the bound the subscribers
+// could take up the whole connection budget of the server and starve the regular requests.
+// Streams over the limit are rejected with 503 Service Unavailable, non-positive limit disables the check.
+func LimitStreams(limit int) gin.HandlerFunc {
+	slots := make(chan struct{}, max(limit, 0))
+	return func(ctx *gin.Context) {
+		if limit <= 0 || !isStreaming(ctx.Request) {
+			ctx.Next()
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			ctx.Next()
+		default:
+			ctx.Header("Retry-After", "5")
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "Service Unavailable",
+				"message": "Too many open streams, retry later",
+			})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamsSuite struct {
+	suite.Suite
+	engine  *gin.Engine
+	release chan struct{}
+	opened  chan struct{}
+}
+
+func TestStreamsSuite(t *testing.T) {
+	suite.Run(t, new(StreamsSuite))
+}
+
+func (suite *StreamsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.release = make(chan struct{})
+	suite.opened = make(chan struct{}, 10)
+	suite.engine = gin.New()
+	suite.engine.Use(LimitStreams(1))
+	// simulates the subscriber holding the stream open
+	suite.engine.GET("/events", func(ctx *gin.Context) {
+		if isStreaming(ctx.Request) {
+			suite.opened <- struct{}{}
+			<-suite.release
+		}
+		ctx.Status(http.StatusOK)
+	})
+}
+
+func (suite *StreamsSuite) serve(accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/events", nil)
+	request.Header.Set("Accept", accept)
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *StreamsSuite) Test_StreamOverLimit_Rejected() {
+	// ARRANGE
+	done := make(chan int)
+	go func() { done <- suite.serve("text/event-stream").Code }()
+	<-suite.opened
+
+	// ACT
+	rejected := suite.serve("text/event-stream")
+	regular := suite.serve("application/json")
+
+	// ASSERT
+	suite.Equal(http.StatusServiceUnavailable, rejected.Code)
+	suite.Equal("5", rejected.Header().Get("Retry-After"))
+	suite.Equal(http.StatusOK, regular.Code)
+
+	close(suite.release)
+	suite.Equal(http.StatusOK, <-done)
+}
+
+func (suite *StreamsSuite) Test_ClosedStream_ReleasesSlot() {
+	// ARRANGE
+	close(suite.release)
+	suite.Equal(http.StatusOK, suite.serve("text/event-stream").Code)
+
+	// ACT
+	recorder := suite.serve("text/event-stream")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutWriter holds the response back until the handler completes within the deadline,
+// so that it can be replaced by the timeout error. The handler and the middleware access it
+// concurrently once the handler runs over the deadline.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	lock    sync.Mutex
+	header  http.Header
+	status  int
+	written bool
+	buffer  bytes.Buffer
+	// the timeout error was sent instead of the response, the later writes are discarded
+	timedOut bool
+}
+
+func (this *timeoutWriter) Header() http.Header {
+	return this.header
+}
+
+func (this *timeoutWriter) WriteHeader(code int) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if code > 0 && !this.written {
+		this.status = code
+	}
+}
+
+func (this *timeoutWriter) WriteHeaderNow() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.written = true
+}
+
+func (this *timeoutWriter) Write(data []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	this.written = true
+	return this.buffer.Write(data)
+}
+
+func (this *timeoutWriter) WriteString(data string) (int, error) {
+	return this.Write([]byte(data))
+}
+
+func (this *timeoutWriter) Status() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.status
+}
+
+func (this *timeoutWriter) Size() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if !this.written {
+		return -1
+	}
+	return this.buffer.Len()
+}
+
+func (this *timeoutWriter) Written() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.written
+}
+
+// Flush is deferred until the handler completes
+func (this *timeoutWriter) Flush() {}
+
+// flush writes the held response to the underlying writer
+func (this *timeoutWriter) flush() error {
+	header := this.ResponseWriter.Header()
+	for key, values := range this.header {
+		header[key] = values
+	}
+	this.ResponseWriter.WriteHeader(this.status)
+	if this.buffer.Len() > 0 {
+		_, err := this.ResponseWriter.Write(this.buffer.Bytes())
+		return err
+	}
+	return nil
+}
+
+// timeout sends the timeout error to the client instead of the response. The response already written
+// by the handler is kept, the handler has done its work, e.g. stored the change, and is just completing.
+// Reports whether the timeout error was sent.
+func (this *timeoutWriter) timeout(timeout time.Duration) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.written {
+		return false
+	}
+	this.timedOut = true
+
+	body, _ := json.Marshal(gin.H{
+		"status":  "Service Unavailable",
+		"message": "Request was not processed within the deadline",
+		"error":   "request timeout " + timeout.String() + " exceeded",
+	})
+	header := this.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	// the length lets the client complete the response while the handler still runs
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	this.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = this.ResponseWriter.Write(body)
+	this.ResponseWriter.Flush()
+	return true
+}
+
+// Timeout limits the processing of the request by the deadline. The request context is cancelled
+// at the deadline, so that the database operations and other context aware calls of the handler
+// are interrupted, and 503 Service Unavailable is sent at once, unless the handler has already
+// written its response. Handlers ignoring the request context keep running until they complete,
+// their response is discarded. Server-sent events and websocket upgrades are not limited.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if timeout <= 0 || isStreaming(ctx.Request) {
+			ctx.Next()
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+
+		writer := &timeoutWriter{ResponseWriter: ctx.Writer, header: http.Header{}, status: http.StatusOK}
+		ctx.Writer = writer
+		// restore the writer also on panic, so that the recovery response is not lost
+		defer func() { ctx.Writer = writer.ResponseWriter }()
+
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer close(done)
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					panicked <- recovered
+				}
+			}()
+			ctx.Next()
+		}()
+
+		timedOut := false
+		select {
+		case <-done:
+		case <-timeoutCtx.Done():
+			timedOut = errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) && writer.timeout(timeout)
+			// the context is reused by gin once the middleware returns, it must outlive the handler
+			<-done
+		}
+		ctx.Writer = writer.ResponseWriter
+
+		select {
+		case recovered := <-panicked:
+			// passed to the recovery middleware
+			panic(recovered)
+		default:
+		}
+		if timedOut {
+			ctx.Abort()
+			return
+		}
+		_ = writer.flush()
+	}
+}
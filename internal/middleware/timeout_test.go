@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeoutSuite struct {
+	suite.Suite
+	engine *gin.Engine
+}
+
+func TestTimeoutSuite(t *testing.T) {
+	suite.Run(t, new(TimeoutSuite))
+}
+
+func (suite *TimeoutSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.engine = gin.New()
+	suite.engine.Use(Timeout(50 * time.Millisecond))
+	// simulates the handler waiting for the database
+	suite.engine.GET("/slow", func(ctx *gin.Context) {
+		select {
+		case <-ctx.Request.Context().Done():
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": ctx.Request.Context().Err().Error()})
+		case <-time.After(5 * time.Second):
+			ctx.JSON(http.StatusOK, gin.H{"status": "done"})
+		}
+	})
+	// simulates the handler ignoring the request context
+	suite.engine.GET("/blocking", func(ctx *gin.Context) {
+		time.Sleep(time.Second)
+		ctx.JSON(http.StatusOK, gin.H{"status": "done"})
+	})
+	// simulates the handler which stored the change just before the deadline
+	suite.engine.POST("/stored", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusCreated, gin.H{"status": "stored"})
+		time.Sleep(200 * time.Millisecond)
+	})
+	suite.engine.GET("/fast", func(ctx *gin.Context) {
+		ctx.Header("X-Test", "fast")
+		ctx.JSON(http.StatusCreated, gin.H{"status": "done"})
+	})
+}
+
+func (suite *TimeoutSuite) get(url string, accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", url, nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (suite *TimeoutSuite) Test_SlowHandler_ServiceUnavailable() {
+	// ACT
+	start := time.Now()
+	recorder := suite.get("/slow", "")
+
+	// ASSERT
+	suite.Less(time.Since(start), time.Second)
+	suite.Equal(http.StatusServiceUnavailable, recorder.Code)
+	var body map[string]interface{}
+	suite.NoError(json.Unmarshal(recorder.Body.Bytes(), &body))
+	suite.Equal("Service Unavailable", body["status"])
+}
+
+func (suite *TimeoutSuite) Test_BlockingHandler_CutOffAtDeadline() {
+	// ARRANGE
+	server := httptest.NewServer(suite.engine)
+	defer server.Close()
+
+	// ACT
+	start := time.Now()
+	response, err := http.Get(server.URL + "/blocking")
+	suite.Require().NoError(err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	elapsed := time.Since(start)
+
+	// ASSERT
+	suite.NoError(err)
+	suite.Less(elapsed, 500*time.Millisecond)
+	suite.Equal(http.StatusServiceUnavailable, response.StatusCode)
+	suite.Contains(string(body), "Service Unavailable")
+}
+
+func (suite *TimeoutSuite) Test_ResponseWrittenBeforeDeadline_Kept() {
+	// ACT
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/stored", nil))
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.JSONEq(`{"status": "stored"}`, recorder.Body.String())
+}
+
+func (suite *TimeoutSuite) Test_FastHandler_ResponsePassedThrough() {
+	// ACT
+	recorder := suite.get("/fast", "")
+
+	// ASSERT
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Equal("fast", recorder.Header().Get("X-Test"))
+	suite.JSONEq(`{"status": "done"}`, recorder.Body.String())
+}
+
+func (suite *TimeoutSuite) Test_StreamingRequest_NotLimited() {
+	// ARRANGE
+	suite.engine.GET("/stream", func(ctx *gin.Context) {
+		_, hasDeadline := ctx.Request.Context().Deadline()
+		if hasDeadline {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	// ACT
+	recorder := suite.get("/stream", "text/event-stream")
+
+	// ASSERT
+	suite.Equal(http.StatusOK, recorder.Code)
+}
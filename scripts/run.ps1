@@ -43,7 +43,9 @@ switch ($command) {
         }
     }
     "build" {
-        go build -o ${ProjectRoot}/bin/ambulance-api-service ${ProjectRoot}/cmd/ambulance-api-service
+        $commit = git rev-parse --short HEAD
+        $buildTime = (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+        go build -ldflags "-X main.gitCommit=$commit -X main.buildTime=$buildTime" -o ${ProjectRoot}/bin/ambulance-api-service ${ProjectRoot}/cmd/ambulance-api-service
     }
     "test" {
         go test -v ./...